@@ -0,0 +1,259 @@
+// Package census builds and stores the Merkle trees of the censuses of the
+// manager, using the same tree format as the Vocdoni census service.
+package census
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"path/filepath"
+	"sync"
+
+	"github.com/vocdoni/arbo"
+	"go.vocdoni.io/dvote/censustree"
+	"go.vocdoni.io/dvote/db"
+	"go.vocdoni.io/dvote/db/metadb"
+	"go.vocdoni.io/dvote/db/prefixeddb"
+	"go.vocdoni.io/dvote/log"
+	"go.vocdoni.io/manager/types"
+	"go.vocdoni.io/proto/build/go/models"
+)
+
+// TreeType is the census tree type used by default by the census service
+const TreeType = models.Census_ARBO_BLAKE2B
+
+// ErrNotInCensus is returned when asking for the proof of a claim that is
+// not in the census tree
+var ErrNotInCensus = errors.New("claim not in census")
+
+// ErrTreeNotBuilt is returned when asking for the proof of a claim in a census
+// whose stored tree is missing or does not match its Merkle root
+var ErrTreeNotBuilt = errors.New("census tree not built")
+
+// Store is the part of the database the census trees are built from
+type Store interface {
+	Census(entityID, censusID []byte) (*types.Census, error)
+	DumpCensusWeightedClaims(entityID, censusID []byte) ([][]byte, []uint64, error)
+	UpdateCensus(entityID, censusID []byte, info *types.CensusInfo) (int, error)
+}
+
+// Trees stores a Merkle tree for each census under a single database
+type Trees struct {
+	db db.Database
+	// lock serializes the (re)builds of the trees
+	lock sync.Mutex
+}
+
+// NewTrees opens (or creates) the census trees database inside dataDir
+func NewTrees(dataDir string) (*Trees, error) {
+	database, err := metadb.New(db.TypePebble, filepath.Join(dataDir, "censustree"))
+	if err != nil {
+		return nil, fmt.Errorf("cannot open census trees database: %w", err)
+	}
+	return &Trees{db: database}, nil
+}
+
+// Close closes the census trees database
+func (t *Trees) Close() error {
+	return t.db.Close()
+}
+
+func treeName(censusID []byte) string {
+	return fmt.Sprintf("census/%x/", censusID)
+}
+
+func (t *Trees) open(censusID []byte) (*censustree.Tree, error) {
+	return censustree.New(censustree.Options{
+		ParentDB:   t.db,
+		Name:       treeName(censusID),
+		CensusType: TreeType,
+	})
+}
+
+// Build replaces the tree of the census with one containing the given claims
+// (the public keys returned by dumpCensus), which are hashed and added with
+// their weight as value, as the census service does for non digested claims.
+// Claims weigh 1 if weights is nil. It returns the resulting root and the
+// indexes of the claims that could not be added.
+func (t *Trees) Build(censusID []byte, claims [][]byte, weights []uint64) ([]byte, []int, error) {
+	if weights != nil && len(weights) != len(claims) {
+		return nil, nil, fmt.Errorf("expected %d weights but got %d", len(claims), len(weights))
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if err := t.clear(censusID); err != nil {
+		return nil, nil, fmt.Errorf("cannot clear census tree: %w", err)
+	}
+	tree, err := t.open(censusID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot create census tree: %w", err)
+	}
+	keys := make([][]byte, len(claims))
+	values := make([][]byte, len(claims))
+	for i, claim := range claims {
+		if keys[i], err = tree.Hash(claim); err != nil {
+			return nil, nil, fmt.Errorf("cannot hash claim: %w", err)
+		}
+		weight := big.NewInt(1)
+		if weights != nil {
+			weight.SetUint64(weights[i])
+		}
+		values[i] = tree.BigIntToBytes(weight)
+	}
+	invalid, err := tree.AddBatch(keys, values)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot add claims to census tree: %w", err)
+	}
+	root, err := tree.Root()
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot get census tree root: %w", err)
+	}
+	return root, invalid, nil
+}
+
+// Root returns the root of the stored tree of the census, which is the one
+// of an empty tree if it was never built
+func (t *Trees) Root(censusID []byte) ([]byte, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	tree, err := t.open(censusID)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open census tree: %w", err)
+	}
+	return tree.Root()
+}
+
+// Proof returns the leaf value of a claim in the tree of the census, its
+// Merkle inclusion proof and the root of the tree
+func (t *Trees) Proof(censusID, claim []byte) ([]byte, []byte, []byte, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	tree, err := t.open(censusID)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("cannot open census tree: %w", err)
+	}
+	return proof(tree, claim)
+}
+
+// CensusProof is like Proof, but without building nor writing anything. It
+// returns ErrTreeNotBuilt unless the stored tree of the census matches the
+// census Merkle root in the database.
+func (t *Trees) CensusProof(d Store, entityID, censusID, claim []byte) ([]byte, []byte, []byte, error) {
+	census, err := d.Census(entityID, censusID)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("cannot retrieve census: %w", err)
+	}
+	if len(census.MerkleRoot) == 0 {
+		return nil, nil, nil, ErrTreeNotBuilt
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	tree, err := t.open(censusID)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("cannot open census tree: %w", err)
+	}
+	root, err := tree.Root()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("cannot get census tree root: %w", err)
+	}
+	if !bytes.Equal(root, census.MerkleRoot) {
+		return nil, nil, nil, ErrTreeNotBuilt
+	}
+	return proof(tree, claim)
+}
+
+func proof(tree *censustree.Tree, claim []byte) ([]byte, []byte, []byte, error) {
+	key, err := tree.Hash(claim)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("cannot hash claim: %w", err)
+	}
+	if _, err := tree.Get(key); err != nil {
+		if errors.Is(err, arbo.ErrKeyNotFound) {
+			return nil, nil, nil, ErrNotInCensus
+		}
+		return nil, nil, nil, err
+	}
+	value, proof, err := tree.GenProof(key)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("cannot generate proof: %w", err)
+	}
+	root, err := tree.Root()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("cannot get census tree root: %w", err)
+	}
+	return value, proof, root, nil
+}
+
+// Delete removes the stored tree of the census
+func (t *Trees) Delete(censusID []byte) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.clear(censusID)
+}
+
+// BuildCensus builds the tree of a census from the weighted claims of its
+// members in the database, and stores its root as the census Merkle root
+func (t *Trees) BuildCensus(d Store, entityID, censusID []byte) ([]byte, error) {
+	claims, weights, err := d.DumpCensusWeightedClaims(entityID, censusID)
+	if err != nil {
+		return nil, fmt.Errorf("cannot dump census claims: %w", err)
+	}
+	root, invalid, err := t.Build(censusID, claims, weights)
+	if err != nil {
+		return nil, err
+	}
+	if len(invalid) > 0 {
+		log.Warnf("census %x: %d claims could not be added to the tree", censusID, len(invalid))
+	}
+	if _, err := d.UpdateCensus(entityID, censusID, &types.CensusInfo{MerkleRoot: root}); err != nil {
+		return nil, fmt.Errorf("cannot store census root: %w", err)
+	}
+	return root, nil
+}
+
+// CensusRoot returns the root of the tree of a census, building the tree again
+// if it does not match the census Merkle root in the database
+func (t *Trees) CensusRoot(d Store, entityID, censusID []byte) ([]byte, error) {
+	census, err := d.Census(entityID, censusID)
+	if err != nil {
+		return nil, fmt.Errorf("cannot retrieve census: %w", err)
+	}
+	root, err := t.Root(censusID)
+	if err != nil {
+		return nil, err
+	}
+	if len(census.MerkleRoot) > 0 && bytes.Equal(root, census.MerkleRoot) {
+		return root, nil
+	}
+	return t.BuildCensus(d, entityID, censusID)
+}
+
+// clear removes all the nodes of the tree of the census
+func (t *Trees) clear(censusID []byte) error {
+	census := prefixeddb.NewPrefixedDatabase(t.db, []byte(treeName(censusID)))
+	var keys [][]byte
+	if err := census.Iterate(nil, func(key, _ []byte) bool {
+		keys = append(keys, append([]byte{}, key...))
+		return true
+	}); err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	tx := census.WriteTx()
+	defer tx.Discard()
+	for _, key := range keys {
+		if err := tx.Delete(key); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
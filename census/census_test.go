@@ -0,0 +1,99 @@
+package census
+
+import (
+	"math/big"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"go.vocdoni.io/dvote/censustree"
+	"go.vocdoni.io/dvote/db/metadb"
+	"go.vocdoni.io/dvote/util"
+)
+
+func TestBuild(t *testing.T) {
+	c := qt.New(t)
+	trees, err := NewTrees(t.TempDir())
+	c.Assert(err, qt.IsNil)
+	defer trees.Close()
+
+	censusID := util.RandomBytes(32)
+	claims := make([][]byte, 10)
+	for i := range claims {
+		claims[i] = util.RandomBytes(33)
+	}
+
+	// the root must be the one the census service computes for the same
+	// non digested claims
+	expected, err := censustree.New(censustree.Options{
+		ParentDB:   metadb.NewTest(t),
+		Name:       "expected",
+		CensusType: TreeType,
+	})
+	c.Assert(err, qt.IsNil)
+	for _, claim := range claims {
+		key, err := expected.Hash(claim)
+		c.Assert(err, qt.IsNil)
+		c.Assert(expected.Add(key, expected.BigIntToBytes(big.NewInt(1))), qt.IsNil)
+	}
+	expectedRoot, err := expected.Root()
+	c.Assert(err, qt.IsNil)
+
+	root, invalid, err := trees.Build(censusID, claims, nil)
+	c.Assert(err, qt.IsNil)
+	c.Assert(invalid, qt.HasLen, 0)
+	c.Assert(root, qt.DeepEquals, expectedRoot)
+
+	stored, err := trees.Root(censusID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(stored, qt.DeepEquals, root)
+
+	// building again replaces the previous tree
+	root2, invalid, err := trees.Build(censusID, claims[:5], nil)
+	c.Assert(err, qt.IsNil)
+	c.Assert(invalid, qt.HasLen, 0)
+	c.Assert(root2, qt.Not(qt.DeepEquals), root)
+	root3, _, err := trees.Build(censusID, claims[:5], nil)
+	c.Assert(err, qt.IsNil)
+	c.Assert(root3, qt.DeepEquals, root2)
+
+	// duplicated claims are reported as invalid
+	_, invalid, err = trees.Build(censusID, append(claims, claims[0]), nil)
+	c.Assert(err, qt.IsNil)
+	c.Assert(invalid, qt.DeepEquals, []int{10})
+
+	// weights are the values of the leaves
+	weights := make([]uint64, 5)
+	for i := range weights {
+		weights[i] = uint64(i + 1)
+	}
+	weighted, err := censustree.New(censustree.Options{
+		ParentDB:   metadb.NewTest(t),
+		Name:       "weighted",
+		CensusType: TreeType,
+	})
+	c.Assert(err, qt.IsNil)
+	for i, claim := range claims[:5] {
+		key, err := weighted.Hash(claim)
+		c.Assert(err, qt.IsNil)
+		c.Assert(weighted.Add(key, weighted.BigIntToBytes(new(big.Int).SetUint64(weights[i]))), qt.IsNil)
+	}
+	weightedRoot, err := weighted.Root()
+	c.Assert(err, qt.IsNil)
+	root4, _, err := trees.Build(censusID, claims[:5], weights)
+	c.Assert(err, qt.IsNil)
+	c.Assert(root4, qt.DeepEquals, weightedRoot)
+	c.Assert(root4, qt.Not(qt.DeepEquals), root2)
+	_, _, err = trees.Build(censusID, claims, weights)
+	c.Assert(err, qt.Not(qt.IsNil))
+
+	// other censuses are not affected
+	other, err := trees.Root(util.RandomBytes(32))
+	c.Assert(err, qt.IsNil)
+	c.Assert(other, qt.Not(qt.DeepEquals), root)
+
+	// deleting the tree leaves an empty one
+	c.Assert(trees.Delete(censusID), qt.IsNil)
+	deleted, err := trees.Root(censusID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(deleted, qt.DeepEquals, other)
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"os/signal"
@@ -9,6 +11,8 @@ import (
 	"syscall"
 	"time"
 
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
 	flag "github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"go.vocdoni.io/dvote/httprouter"
@@ -18,10 +22,13 @@ import (
 	"go.vocdoni.io/dvote/crypto/ethereum"
 	chain "go.vocdoni.io/dvote/ethereum"
 	log "go.vocdoni.io/dvote/log"
+	dvoteutil "go.vocdoni.io/dvote/util"
+	"go.vocdoni.io/manager/census"
 	"go.vocdoni.io/manager/config"
 	"go.vocdoni.io/manager/database"
 	"go.vocdoni.io/manager/database/pgsql"
 	"go.vocdoni.io/manager/manager"
+	"go.vocdoni.io/manager/registry"
 	"go.vocdoni.io/manager/smtpclient"
 )
 
@@ -50,6 +57,7 @@ func newConfig() (*config.Manager, config.Error) {
 	cfg.LogErrorFile = *flag.String("logErrorFile", "", "Log errors and warnings to a file")
 	cfg.SaveConfig = *flag.Bool("saveConfig", false, "overwrites an existing config file with the CLI provided flags")
 	cfg.SigningKeys = *flag.StringArray("signingKeys", []string{}, "signing private Keys (if not specified, a new one will be created), the first one is the oracle public key")
+	cfg.Admins = *flag.StringArray("admins", []string{}, "hex encoded addresses of the platform superadmins, allowed to call the admin API")
 	cfg.API.Route = *flag.String("apiRoute", "/api", "dvote API route")
 	cfg.API.ListenHost = *flag.String("listenHost", "0.0.0.0", "API endpoint listen address")
 	cfg.API.ListenPort = *flag.Int("listenPort", 8000, "API endpoint http port")
@@ -77,6 +85,16 @@ func newConfig() (*config.Manager, config.Error) {
 	cfg.EthNetwork.GasLimit = *flag.Uint64("ethNetworkGasLimit", 0, "Gas limit for sending an EVM transaction in units")
 	cfg.EthNetwork.FaucetAmount = *flag.Int("ethNetworkFaucetAmount", 0*types.Finney, "Amount of eth or similar to be provided upon an entity sign up (in milliEther)")
 	cfg.EthNetwork.Timeout = *flag.Duration("ethNetworkTimeout", 60*time.Second, "Timeout for ethereum transactions (default: 60s) ")
+	// members export
+	flag.StringVar(&cfg.Export.EntityID, "entityId", "", "exportMembers: hex encoded ID of the entity whose members are exported")
+	flag.StringVar(&cfg.Export.Format, "exportFormat", manager.ExportFormatCSV, fmt.Sprintf("exportMembers: export format (%s, %s)", manager.ExportFormatCSV, manager.ExportFormatJSONL))
+	flag.StringVar(&cfg.Export.TargetID, "exportTarget", "", "exportMembers: only export the members of the target with this ID")
+	flag.IntVar(&cfg.Export.TagID, "exportTag", 0, "exportMembers: only export the members with the tag with this ID")
+	flag.StringVar(&cfg.Export.Output, "exportOutput", "", "exportMembers: file to write the export to (default stdout)")
+	// members
+	cfg.Members.PurgePeriod = *flag.Duration("membersPurgePeriod", 30*24*time.Hour, "time deleted members are kept before being permanently deleted (0 keeps them forever)")
+	// entities
+	cfg.Entities.DeletionWindow = *flag.Duration("entitiesDeletionWindow", 30*24*time.Hour, "time after the end of a process during which the entity with a census used by it cannot be deleted")
 	// metrics
 	cfg.Metrics.Enabled = *flag.Bool("metricsEnabled", true, "enable prometheus metrics")
 	cfg.Metrics.RefreshInterval = *flag.Int("metricsRefreshInterval", 10, "metrics refresh interval in seconds")
@@ -101,6 +119,7 @@ func newConfig() (*config.Manager, config.Error) {
 	viper.BindPFlag("logErrorFile", flag.Lookup("logErrorFile"))
 	viper.BindPFlag("logOutput", flag.Lookup("logOutput"))
 	viper.BindPFlag("signingKeys", flag.Lookup("signingKeys"))
+	viper.BindPFlag("admins", flag.Lookup("admins"))
 	viper.BindPFlag("api.route", flag.Lookup("apiRoute"))
 	viper.BindPFlag("api.listenHost", flag.Lookup("listenHost"))
 	viper.BindPFlag("api.listenPort", flag.Lookup("listenPort"))
@@ -129,6 +148,10 @@ func newConfig() (*config.Manager, config.Error) {
 	viper.BindPFlag("ethnetwork.gasLimit", flag.Lookup("ethNetworkGasLimit"))
 	viper.BindPFlag("ethnetwork.faucetAmount", flag.Lookup("ethNetworkFaucetAmount"))
 	viper.BindPFlag("ethnetwork.timeout", flag.Lookup("ethNetworkTimeout"))
+	// members
+	viper.BindPFlag("members.purgePeriod", flag.Lookup("membersPurgePeriod"))
+	// entities
+	viper.BindPFlag("entities.deletionWindow", flag.Lookup("entitiesDeletionWindow"))
 	// metrics
 	viper.BindPFlag("metrics.enabled", flag.Lookup("metricsEnabled"))
 	viper.BindPFlag("metrics.refreshInterval", flag.Lookup("metricsRefreshInterval"))
@@ -207,6 +230,11 @@ func main() {
 	if cfg == nil {
 		panic("cannot read configuration")
 	}
+	exporting := flag.Arg(0) == "exportMembers"
+	if exporting && cfg.Export.Output == "" && cfg.LogOutput == "stdout" {
+		// keep the logs out of the exported members
+		cfg.LogOutput = "stderr"
+	}
 	log.Init(cfg.LogLevel, cfg.LogOutput)
 	if path := cfg.LogErrorFile; path != "" {
 		if err := log.SetFileErrorLog(path); err != nil {
@@ -218,6 +246,14 @@ func main() {
 		log.Fatalf("invalid mode %s", cfg.Mode)
 	}
 
+	// Standalone members export
+	if exporting {
+		if err := exportMembers(cfg); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	// Signer
 	signer := ethereum.NewSignKeys()
 	for idx, key := range cfg.SigningKeys {
@@ -230,6 +266,19 @@ func main() {
 	log.Infof("my public key: %s", pub)
 	log.Infof("my address: %s", signer.AddressString())
 
+	// Platform superadmins
+	admins := make([]ethcommon.Address, len(cfg.Admins))
+	for idx, admin := range cfg.Admins {
+		admin = strings.Trim(admin, `"[]`)
+		if !ethcommon.IsHexAddress(admin) {
+			log.Fatalf("invalid admin address %q", admin)
+		}
+		admins[idx] = ethcommon.HexToAddress(admin)
+	}
+	if len(admins) == 0 {
+		log.Warn("no platform superadmins set with --admins: the admin API and adminEntityList cannot be called")
+	}
+
 	var httpRouter httprouter.HTTProuter
 	httpRouter.TLSdomain = cfg.API.Ssl.Domain
 	httpRouter.TLSdirCert = cfg.API.Ssl.DirCert
@@ -261,6 +310,17 @@ func main() {
 		log.Fatal(err)
 	}
 
+	// Permanently delete the members deleted before the purge period and
+	// enforce the retention settings of the entities
+	go sweepRetention(db, cfg.Members.PurgePeriod)
+
+	// Census Merkle trees computed by the manager
+	censusTrees, err := census.NewTrees(cfg.DataDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer censusTrees.Close()
+
 	// Generate SMTP config object
 	smtp := smtpclient.New(cfg.SMTP)
 	if err := smtp.StartPool(); err != nil {
@@ -305,7 +365,7 @@ func main() {
 	// var managerapi *rpcapi.RPCAPI
 	if cfg.Mode == "manager" || cfg.Mode == "all" {
 		log.Infof("enabling Manager API methods")
-		mg, err := manager.NewManager(signer, &httpRouter, cfg.API.Route, db, smtp, ethClient)
+		mg, err := manager.NewManager(signer, &httpRouter, cfg.API.Route, db, smtp, ethClient, admins, cfg.Entities.DeletionWindow, censusTrees)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -314,6 +374,19 @@ func main() {
 		}
 	}
 
+	// Census proofs for the voters, served along with the manager API since
+	// they are read from the census trees it builds
+	if cfg.Mode == "manager" || cfg.Mode == "all" {
+		log.Infof("enabling census proof API methods")
+		proofs, err := registry.NewCensusProofs(signer, &httpRouter, cfg.API.Route, db, nil, censusTrees)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := proofs.EnableCensusProofAPI(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	// // User registry
 	// if cfg.Mode == "registry" || cfg.Mode == "all" {
 	// 	log.Infof("enabling Registry API methods")
@@ -340,3 +413,80 @@ func main() {
 	log.Warnf("received SIGTERM, exiting at %s", time.Now().Format(time.RFC850))
 	os.Exit(0)
 }
+
+// sweepRetentionInterval is how often the retention sweeper runs
+const sweepRetentionInterval = time.Hour
+
+// sweepRetention periodically deletes the members that were deleted longer
+// than purgePeriod ago, unless it is zero, and enforces the retention settings
+// of every entity, logging what was purged
+func sweepRetention(db database.Database, purgePeriod time.Duration) {
+	for {
+		if purgePeriod > 0 {
+			count, err := db.PurgeDeletedMembers(time.Now().Add(-purgePeriod))
+			if err != nil {
+				log.Errorf("cannot purge deleted members: (%v)", err)
+			} else if count > 0 {
+				log.Infof("purged %d deleted members", count)
+			}
+		}
+		purged, err := db.PurgeUnverifiedMembers()
+		if err != nil {
+			log.Errorf("cannot purge unverified members: (%v)", err)
+		}
+		for entityID, count := range purged {
+			log.Infof("purged %d unverified members of entity %s", count, entityID)
+		}
+		dropped, err := db.DropEphemeralKeys()
+		if err != nil {
+			log.Errorf("cannot drop ephemeral keys: (%v)", err)
+		}
+		for entityID, count := range dropped {
+			log.Infof("dropped %d ephemeral keys of entity %s", count, entityID)
+		}
+		time.Sleep(sweepRetentionInterval)
+	}
+}
+
+// exportMembers writes the members of the entity given by the export options
+// to the export output or stdout
+func exportMembers(cfg *config.Manager) error {
+	entityID, err := hex.DecodeString(dvoteutil.TrimHex(cfg.Export.EntityID))
+	if err != nil || len(entityID) == 0 {
+		return fmt.Errorf("invalid entity ID %q", cfg.Export.EntityID)
+	}
+	opts := &manager.ExportOptions{Format: cfg.Export.Format, TagID: int32(cfg.Export.TagID)}
+	if len(cfg.Export.TargetID) > 0 {
+		targetID, err := uuid.Parse(cfg.Export.TargetID)
+		if err != nil {
+			return fmt.Errorf("invalid target ID %q: %w", cfg.Export.TargetID, err)
+		}
+		opts.TargetID = &targetID
+	}
+
+	db, err := pgsql.New(cfg.DB)
+	if err != nil {
+		return err
+	}
+	if err := pgsql.Migrator("upSync", db); err != nil {
+		return err
+	}
+
+	out := os.Stdout
+	if len(cfg.Export.Output) > 0 {
+		if out, err = os.Create(cfg.Export.Output); err != nil {
+			return fmt.Errorf("cannot create export file: %w", err)
+		}
+		defer out.Close()
+	}
+	w := bufio.NewWriter(out)
+	count, err := manager.ExportMembers(db, w, entityID, opts)
+	if err != nil {
+		return fmt.Errorf("cannot export members: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("cannot write export: %w", err)
+	}
+	log.Infof("exported %d members of entity %x", count, entityID)
+	return nil
+}
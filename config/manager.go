@@ -38,13 +38,22 @@ type Manager struct {
 	SigningKeys []string
 	// Migration options
 	Migrate *Migrate
+	// Members export options
+	Export *Export
+	// Members options
+	Members *Members
+	// Entities options
+	Entities *Entities
 	// Web3 connection options
 	EthNetwork *EthNetwork
+	// Admins are the hex encoded addresses of the platform superadmins,
+	// allowed to call the admin API
+	Admins []string
 }
 
 func (m *Manager) String() string {
-	return fmt.Sprintf("API: %+v,  DB: %+v, SMTP: %+v, LogLevel: %s, LogOutput: %s, LogErrorFile: %s,  Metrics: %+v, Mode: %s, DataDir: %s, SaveConfig: %v, SigningKey: %s,  SMTP: %v, Migrate: %+v, Eth: %v",
-		*m.API, *m.DB, *m.SMTP, m.LogLevel, m.LogOutput, m.LogErrorFile, *m.Metrics, m.Mode, m.DataDir, m.SaveConfig, m.SigningKeys, *m.SMTP, *m.Migrate, *m.EthNetwork)
+	return fmt.Sprintf("API: %+v,  DB: %+v, SMTP: %+v, LogLevel: %s, LogOutput: %s, LogErrorFile: %s,  Metrics: %+v, Mode: %s, DataDir: %s, SaveConfig: %v, SigningKey: %s,  SMTP: %v, Migrate: %+v, Eth: %v, Admins: %v",
+		*m.API, *m.DB, *m.SMTP, m.LogLevel, m.LogOutput, m.LogErrorFile, *m.Metrics, m.Mode, m.DataDir, m.SaveConfig, m.SigningKeys, *m.SMTP, *m.Migrate, *m.EthNetwork, m.Admins)
 }
 
 func (m *Manager) ValidMode() bool {
@@ -57,6 +66,9 @@ func NewManagerConfig() *Manager {
 		API:        new(API),
 		DB:         new(DB),
 		Migrate:    new(Migrate),
+		Export:     new(Export),
+		Members:    new(Members),
+		Entities:   new(Entities),
 		SMTP:       new(SMTP),
 		Metrics:    new(MetricsCfg),
 		EthNetwork: new(EthNetwork),
@@ -82,6 +94,31 @@ type Migrate struct {
 	Action string
 }
 
+type Export struct {
+	// EntityID is the hex encoded ID of the entity whose members are exported
+	EntityID string
+	// Format is the export format (csv, jsonl)
+	Format string
+	// TargetID restricts the export to the members of a target
+	TargetID string
+	// TagID restricts the export to the members with a tag
+	TagID int
+	// Output is the file to write the export to, stdout if empty
+	Output string
+}
+
+type Members struct {
+	// PurgePeriod is how long deleted members are kept before being
+	// permanently deleted, zero keeps them forever
+	PurgePeriod time.Duration
+}
+
+type Entities struct {
+	// DeletionWindow is how long after the end of a process an entity with
+	// a census used by it cannot be deleted
+	DeletionWindow time.Duration
+}
+
 type EthNetwork struct {
 	// NetworkName is the Ethereum Network Name
 	// currently supported: "mainnet", "sokol", goerli", "xdai",
@@ -1,6 +1,8 @@
 package database
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	migrate "github.com/rubenv/sql-migrate"
 	"go.vocdoni.io/manager/types"
@@ -12,14 +14,26 @@ type Database interface {
 	AddEntity(entityID []byte, info *types.EntityInfo) error
 	Entity(entityID []byte) (*types.Entity, error)
 	DeleteEntity(entityID []byte) error
+	CountActiveProcessCensuses(entityID []byte, since time.Time) (int, error)
+	SetEntityQuotas(entityID []byte, quotas *types.Quotas) (int, error)
+	EntityUsage(entityID []byte, day, month time.Time) (*types.Usage, error)
+	AddFaucetRequest(entityID []byte) error
+	ReserveEmails(entityID []byte, day time.Time, count, limit int) (bool, error)
+	ReleaseEmails(entityID []byte, day time.Time, count int) error
 	EntitiesID() ([]string, error)
 	AuthorizeEntity(entityID []byte) error
 	UpdateEntity(entityID []byte, info *types.EntityInfo) (int, error)
 	EntityOrigins(entityID []byte) ([]types.Origin, error)
+	EntityCustomFieldsSchema(entityID []byte) (types.CustomFieldsSchema, error)
+	EntityOperators(entityID []byte) ([]types.Operator, error)
+	EntityOperatorRole(entityID, address []byte) (types.OperatorRole, error)
+	SetEntityOperator(entityID, address []byte, role types.OperatorRole) error
+	RemoveEntityOperator(entityID, address []byte) (int, error)
 	EntityHas(entityID []byte, memberID *uuid.UUID) bool
 	AddMember(entityID []byte, pubKey []byte, info *types.MemberInfo) (uuid.UUID, error)
 	ImportMembersWithPubKey(entityID []byte, info []types.MemberInfo) error
 	ImportMembers(entityID []byte, info []types.MemberInfo) error
+	ExistingMemberEmails(entityID []byte, emails []string) ([]string, error)
 	AddMemberBulk(entityID []byte, members []types.Member) error
 	Member(entityID []byte, memberID *uuid.UUID) (*types.Member, error)
 	Members(entityID []byte, memberIDs []uuid.UUID) ([]types.Member, []uuid.UUID, error)
@@ -27,41 +41,67 @@ type Database interface {
 	DeleteMember(entityID []byte, memberID *uuid.UUID) error
 	DeleteMembers(entityID []byte, members []uuid.UUID) (int, []uuid.UUID, error)
 	DeleteMembersByKeys(entityID []byte, memberKeys [][]byte) (int, [][]byte, error)
+	RestoreMembers(entityID []byte, members []uuid.UUID) (int, []uuid.UUID, error)
+	PurgeDeletedMembers(before time.Time) (int, error)
+	PurgeUnverifiedMembers() (map[string]int, error)
+	DropEphemeralKeys() (map[string]int, error)
 	MemberPubKey(entityID, pubKey []byte) (*types.Member, error)
-	CountMembers(entityID []byte) (int, error)
-	ListMembers(entityID []byte, filter *types.ListOptions) ([]types.Member, error)
+	CountMembers(entityID []byte, search string) (int, error)
+	ListMembers(entityID []byte, filter *types.ListOptions) ([]types.Member, string, error)
+	ListDeletedMembers(entityID []byte, filter *types.ListOptions) ([]types.Member, string, error)
 	UpdateMember(entityID []byte, memberID *uuid.UUID, info *types.MemberInfo) (int, error)
+	UpdateMembers(entityID []byte, members []uuid.UUID, patch *types.MemberPatch) (int, []uuid.UUID, error)
+	UpdateMembersByFilters(entityID []byte, filters *types.TargetFilters, patch *types.MemberPatch) (int, error)
+	MemberEvents(entityID []byte, memberID *uuid.UUID) ([]types.MemberEvent, error)
+	AsOperator(address []byte) Database
+	AddMemberEmails(entityID []byte, memberIDs []uuid.UUID, emailType string, censusID, processID []byte) error
+	ExportMemberData(entityID []byte, memberID *uuid.UUID) (*types.MemberData, error)
+	EraseMember(entityID []byte, memberID *uuid.UUID) error
 	AddTag(entityID []byte, tagName string) (int32, error)
+	UpdateTag(entityID []byte, tagID int32, tag *types.Tag) (int, error)
 	DeleteTag(entityID []byte, tagID int32) error
 	Tag(entityID []byte, tagID int32) (*types.Tag, error)
 	TagByName(entityID []byte, tagName string) (*types.Tag, error)
 	ListTags(entityID []byte) ([]types.Tag, error)
 	AddTagToMembers(entityID []byte, members []uuid.UUID, tagID int32) (int, []uuid.UUID, error)
 	RemoveTagFromMembers(entityID []byte, members []uuid.UUID, tagID int32) (int, []uuid.UUID, error)
+	AddTagToMembersByFilters(entityID []byte, filters *types.TargetFilters, tagID int32) (int, error)
+	RemoveTagFromMembersByFilters(entityID []byte, filters *types.TargetFilters, tagID int32) (int, error)
 	CreateMembersWithTokens(entityID []byte, tokens []uuid.UUID) error
 	CreateNMembers(entityID []byte, n int) ([]uuid.UUID, error)
 	RegisterMember(entityID, pubKey []byte, token *uuid.UUID) error
 	MembersTokensEmails(entityID []byte) ([]types.Member, error)
 	AddTarget(entityID []byte, target *types.Target) (uuid.UUID, error)
 	Target(entityID []byte, targetID *uuid.UUID) (*types.Target, error)
+	UpdateTarget(entityID []byte, targetID *uuid.UUID, target *types.Target) (int, error)
+	DeleteTarget(entityID []byte, targetID *uuid.UUID) error
 	CountTargets(entityID []byte) (int, error)
 	ListTargets(entityID []byte) ([]types.Target, error)
 	TargetMembers(entityID []byte, targetID *uuid.UUID) ([]types.Member, error)
+	StreamMembers(entityID []byte, filters *types.TargetFilters, fn func(member *types.Member) error) error
 	AddUser(user *types.User) error
 	User(pubKey []byte) (*types.User, error)
 	DumpClaims(entityID []byte) ([][]byte, error)
+	DumpTargetClaims(entityID []byte, targetID *uuid.UUID) ([][]byte, int, error)
 	DumpCensusClaims(entityID []byte, censusID []byte) ([][]byte, error)
+	DumpCensusWeightedClaims(entityID, censusID []byte) ([][]byte, []uint64, error)
 	ExpandCensusMembers(entityID, censusID []byte) ([]types.CensusMember, error)
 	ListEphemeralMemberInfo(entityID, censusID []byte) ([]types.EphemeralMemberInfo, error)
 	EphemeralMemberInfoByEmail(entityID, censusID []byte, email string) (*types.EphemeralMemberInfo, error)
+	CensusMember(entityID, censusID []byte, memberID *uuid.UUID) (*types.CensusMember, error)
 	Census(entityID, censusID []byte) (*types.Census, error)
 	UpdateCensus(entityID, censusID []byte, info *types.CensusInfo) (int, error)
+	SetCensusState(entityID, censusID []byte, from, to types.CensusState) error
+	FlagInvalidClaims(entityID, censusID []byte, claims [][]byte, reasons []string) ([]types.InvalidClaim, error)
+	CensusInvalidClaims(entityID, censusID []byte) ([]types.InvalidClaim, error)
 	AddCensus(entityID, censusID []byte, targetID *uuid.UUID, info *types.CensusInfo) error
 	AddCensusWithMembers(entityID, censusID []byte, targetID *uuid.UUID, info *types.CensusInfo) (int64, error)
 	CountCensus(entityID []byte) (int, error)
 	DeleteCensus(entityID []byte, censusID []byte) error
-	ListCensus(entityID []byte, filter *types.ListOptions) ([]types.Census, error)
+	ListCensus(entityID []byte, filter *types.ListOptions) ([]types.Census, string, error)
 	AdminEntityList() ([]types.Entity, error)
+	CountEntities(search string) (int, error)
+	ListEntities(filter *types.ListOptions) ([]types.Entity, string, error)
 	Migrate(dir migrate.MigrationDirection) (int, error)
 	MigrateStatus() (int, int, string, error)
 	MigrationUpSync() (int, error)
@@ -0,0 +1,190 @@
+package pgsql
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"go.vocdoni.io/manager/types"
+)
+
+// sortColumnTypes holds the SQL type of every sortable column, which is needed
+// to cast back the values kept in a cursor. Only the fields allowed by
+// ToOrderBySQLi can be found here.
+var sortColumnTypes = map[string]string{
+	"date_of_birth":   "timestamptz",
+	"email":           "text",
+	"first_name":      "text",
+	"last_name":       "text",
+	"phone":           "text",
+	"street_address":  "text",
+	"consented":       "boolean",
+	"verified":        "timestamptz",
+	"origin":          "origins",
+	"custom_fields":   "jsonb",
+	"name":            "text",
+	"merkle_root":     "bytea",
+	"merkle_tree_uri": "text",
+	"size":            "integer",
+	"created_at":      "timestamptz",
+	"updated_at":      "timestamptz",
+}
+
+// listCursor points to the last element of a page. It is handed to the clients
+// as an opaque string and the next page starts right after it.
+// Value and ID are the text representation of the sort column and the id
+// of the last element.
+type listCursor struct {
+	SortBy string `json:"s,omitempty"`
+	Order  string `json:"o,omitempty"`
+	Value  string `json:"v"`
+	ID     string `json:"i"`
+}
+
+func (c *listCursor) encode() (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(cursor string) (*listCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+	var c listCursor
+	if err := json.Unmarshal(data, &c); err != nil || len(c.ID) == 0 {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+	return &c, nil
+}
+
+// ValidateCursor checks that the cursor of the list options is well formed and
+// that it was generated with the same sort options
+func ValidateCursor(filter *types.ListOptions) error {
+	if filter == nil || len(filter.Cursor) == 0 {
+		return nil
+	}
+	c, err := decodeCursor(filter.Cursor)
+	if err != nil {
+		return err
+	}
+	if c.SortBy != filter.SortBy || c.Order != filter.Order {
+		return fmt.Errorf("cursor does not match the sort options")
+	}
+	return nil
+}
+
+// listPage holds the ordering and paging of a list query.
+// Pages are always sorted by (sort column, id) so that the elements sharing
+// the same value keep a stable order. If a cursor is given the page starts
+// after it (keyset pagination), otherwise the offset is used.
+type listPage struct {
+	sortBy   string
+	order    string
+	column   string
+	desc     bool
+	limit    int
+	offset   int
+	idColumn string
+	idType   string
+	cursor   *listCursor
+}
+
+// newListPage parses the list options. t is the type holding the db tags of the
+// sortable fields and defaultSortBy the field used if none is requested.
+func newListPage(filter *types.ListOptions, t reflect.Type, defaultSortBy, idColumn, idType string) (*listPage, error) {
+	field, found := t.FieldByName(strings.Title(defaultSortBy))
+	if !found {
+		return nil, fmt.Errorf("%s field not found in DB. Something is very wrong", defaultSortBy)
+	}
+	p := &listPage{column: field.Tag.Get("db"), idColumn: idColumn, idType: idType}
+	if filter == nil {
+		return p, nil
+	}
+	p.sortBy, p.order = filter.SortBy, filter.Order
+	if len(filter.SortBy) > 0 {
+		if field, found := t.FieldByName(strings.Title(filter.SortBy)); found {
+			p.desc = filter.Order == "descend"
+			p.column = field.Tag.Get("db")
+		}
+	}
+	if _, ok := sortColumnTypes[p.column]; !ok {
+		return nil, fmt.Errorf("cannot sort by %s", p.column)
+	}
+	if filter.Count > 0 {
+		p.limit = filter.Count
+	}
+	if len(filter.Cursor) > 0 {
+		if err := ValidateCursor(filter); err != nil {
+			return nil, err
+		}
+		p.cursor, _ = decodeCursor(filter.Cursor)
+	} else if filter.Skip > 0 {
+		p.offset = filter.Skip
+	}
+	return p, nil
+}
+
+// sortExpression returns the SQL expression used for sorting, which is also
+// the one stored in the cursors. Nullable columns are coalesced, since NULL
+// values cannot be compared.
+func (p *listPage) sortExpression() string {
+	if p.column == "email" {
+		return "COALESCE(email, '')"
+	}
+	return p.column
+}
+
+// cursorColumns returns the columns to be selected in order to build the cursors
+func (p *listPage) cursorColumns() string {
+	return fmt.Sprintf(`CAST(%s AS text) AS "cursor_value", CAST(%s AS text) AS "cursor_id"`, p.sortExpression(), p.idColumn)
+}
+
+// where returns the condition that skips the elements up to the cursor.
+// The placeholders are numbered starting at argOffset+1.
+func (p *listPage) where(argOffset int) (string, []interface{}) {
+	if p.cursor == nil {
+		return "TRUE", nil
+	}
+	operator := ">"
+	if p.desc {
+		operator = "<"
+	}
+	return fmt.Sprintf("(%s, %s) %s (CAST($%d AS %s), CAST($%d AS %s))",
+			p.sortExpression(), p.idColumn, operator,
+			argOffset+1, sortColumnTypes[p.column], argOffset+2, p.idType),
+		[]interface{}{p.cursor.Value, p.cursor.ID}
+}
+
+// orderLimit returns the ORDER BY, LIMIT and OFFSET clauses. One extra element
+// is requested in order to know if there is a next page.
+func (p *listPage) orderLimit(argOffset int) (string, []interface{}) {
+	order := "ASC"
+	if p.desc {
+		order = "DESC"
+	}
+	var limit sql.NullInt32
+	if p.limit > 0 {
+		limit = sql.NullInt32{Int32: int32(p.limit + 1), Valid: true}
+	}
+	return fmt.Sprintf("ORDER BY %s %s, %s %s LIMIT $%d OFFSET $%d",
+			p.sortExpression(), order, p.idColumn, order, argOffset+1, argOffset+2),
+		[]interface{}{limit, p.offset}
+}
+
+// hasNext returns true if the page got more elements than requested
+func (p *listPage) hasNext(n int) bool {
+	return p.limit > 0 && n > p.limit
+}
+
+// nextCursor returns the cursor pointing to the given element
+func (p *listPage) nextCursor(value, id string) (string, error) {
+	c := &listCursor{SortBy: p.sortBy, Order: p.order, Value: value, ID: id}
+	return c.encode()
+}
@@ -0,0 +1,44 @@
+package pgsql
+
+import (
+	"reflect"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"go.vocdoni.io/manager/types"
+)
+
+func TestListPage(t *testing.T) {
+	c := qt.New(t)
+	memberType := reflect.TypeOf(types.MemberInfo{})
+
+	// without cursor the offset is used
+	page, err := newListPage(&types.ListOptions{Count: 10, Skip: 20, SortBy: "email", Order: "descend"}, memberType, "lastName", "id", "uuid")
+	c.Assert(err, qt.IsNil)
+	where, args := page.where(1)
+	c.Assert(where, qt.Equals, "TRUE")
+	c.Assert(args, qt.HasLen, 0)
+	orderLimit, args := page.orderLimit(1)
+	c.Assert(orderLimit, qt.Equals, "ORDER BY COALESCE(email, '') DESC, id DESC LIMIT $2 OFFSET $3")
+	c.Assert(args[1], qt.Equals, 20)
+	c.Assert(page.hasNext(10), qt.IsFalse)
+	c.Assert(page.hasNext(11), qt.IsTrue)
+
+	// cursors keep the sort options and replace the offset
+	cursor, err := page.nextCursor("member@vocdoni.io", "6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	c.Assert(err, qt.IsNil)
+	page, err = newListPage(&types.ListOptions{Count: 10, Skip: 20, SortBy: "email", Order: "descend", Cursor: cursor}, memberType, "lastName", "id", "uuid")
+	c.Assert(err, qt.IsNil)
+	where, args = page.where(1)
+	c.Assert(where, qt.Equals, "(COALESCE(email, ''), id) < (CAST($2 AS text), CAST($3 AS uuid))")
+	c.Assert(args, qt.DeepEquals, []interface{}{"member@vocdoni.io", "6ba7b810-9dad-11d1-80b4-00c04fd430c8"})
+	orderLimit, args = page.orderLimit(3)
+	c.Assert(orderLimit, qt.Equals, "ORDER BY COALESCE(email, '') DESC, id DESC LIMIT $4 OFFSET $5")
+	c.Assert(args[1], qt.Equals, 0)
+
+	// cursors cannot be used with other sort options
+	_, err = newListPage(&types.ListOptions{Count: 10, SortBy: "email", Cursor: cursor}, memberType, "lastName", "id", "uuid")
+	c.Assert(err, qt.Not(qt.IsNil))
+	c.Assert(ValidateCursor(&types.ListOptions{Cursor: "invalid"}), qt.Not(qt.IsNil))
+	c.Assert(ValidateCursor(&types.ListOptions{SortBy: "email", Order: "descend", Cursor: cursor}), qt.IsNil)
+}
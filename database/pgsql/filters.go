@@ -0,0 +1,170 @@
+package pgsql
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgtype"
+	"go.vocdoni.io/manager/types"
+)
+
+// filterQuery accumulates the conditions and the positional arguments
+// of a compiled set of target filters
+type filterQuery struct {
+	conditions []string
+	args       []interface{}
+	offset     int
+}
+
+// arg registers a new query argument and returns its placeholder
+func (q *filterQuery) arg(v interface{}) string {
+	q.args = append(q.args, v)
+	return fmt.Sprintf("$%d", q.offset+len(q.args))
+}
+
+func (q *filterQuery) add(format string, a ...interface{}) {
+	q.conditions = append(q.conditions, fmt.Sprintf(format, a...))
+}
+
+// targetFiltersSQL compiles the target filters into a parameterized SQL
+// condition over the members table, which must be aliased as "m".
+// The placeholders are numbered starting at argOffset+1, so that the condition
+// can be appended to queries that already use argOffset arguments.
+// No user provided value is ever written into the returned SQL string.
+func targetFiltersSQL(filters *types.TargetFilters, argOffset int) (string, []interface{}, error) {
+	q := &filterQuery{offset: argOffset}
+	if filters == nil {
+		return "TRUE", nil, nil
+	}
+	if filters.Tags != nil {
+		for _, t := range []struct {
+			tags   []int32
+			format string
+		}{
+			{filters.Tags.Any, "m.tags && CAST(%s AS int[])"},
+			{filters.Tags.All, "m.tags @> CAST(%s AS int[])"},
+			{filters.Tags.None, "NOT (m.tags && CAST(%s AS int[]))"},
+		} {
+			if len(t.tags) == 0 {
+				continue
+			}
+			var tags pgtype.Int4Array
+			if err := tags.Set(t.tags); err != nil {
+				return "", nil, fmt.Errorf("cannot convert tags filter: %w", err)
+			}
+			q.add(t.format, q.arg(tags))
+		}
+	}
+	if len(filters.Origins) > 0 {
+		var origins pgtype.EnumArray
+		if err := origins.Set(filters.Origins); err != nil {
+			return "", nil, fmt.Errorf("cannot convert origins filter: %w", err)
+		}
+		q.add("m.origin = ANY(CAST(%s AS origins[]))", q.arg(origins))
+	}
+	// Unverified members and unknown dates of birth are stored as zero times
+	if filters.Verified != nil {
+		if *filters.Verified {
+			q.add("m.verified > %s", q.arg(time.Time{}))
+		} else {
+			q.add("m.verified <= %s", q.arg(time.Time{}))
+		}
+	}
+	if filters.DateOfBirth != nil {
+		q.add("m.date_of_birth > %s", q.arg(time.Time{}))
+		if filters.DateOfBirth.From != nil {
+			q.add("m.date_of_birth >= %s", q.arg(*filters.DateOfBirth.From))
+		}
+		if filters.DateOfBirth.To != nil {
+			q.add("m.date_of_birth < %s", q.arg(*filters.DateOfBirth.To))
+		}
+	}
+	if filters.HasPublicKey != nil {
+		if *filters.HasPublicKey {
+			q.add("m.public_key IS NOT NULL")
+		} else {
+			q.add("m.public_key IS NULL")
+		}
+	}
+	for _, cf := range filters.CustomFields {
+		var path pgtype.TextArray
+		if err := path.Set(cf.PathElements()); err != nil {
+			return "", nil, fmt.Errorf("cannot convert custom field path: %w", err)
+		}
+		field := fmt.Sprintf("(m.custom_fields #> CAST(%s AS text[]))", q.arg(path))
+		switch cf.Op {
+		case types.FilterOpExists:
+			q.add("%s IS NOT NULL", field)
+		case types.FilterOpEq:
+			q.add("%s = CAST(%s AS jsonb)", field, q.arg(string(cf.Value)))
+		case types.FilterOpNeq:
+			q.add("%s IS DISTINCT FROM CAST(%s AS jsonb)", field, q.arg(string(cf.Value)))
+		case types.FilterOpIn:
+			q.add("CAST(%s AS jsonb) @> jsonb_build_array(%s)", q.arg(string(cf.Value)), field)
+		case types.FilterOpGt, types.FilterOpGte, types.FilterOpLt, types.FilterOpLte:
+			operator := map[string]string{
+				types.FilterOpGt:  ">",
+				types.FilterOpGte: ">=",
+				types.FilterOpLt:  "<",
+				types.FilterOpLte: "<=",
+			}[cf.Op]
+			// CASE guarantees that only numbers reach the numeric cast
+			q.add("CASE WHEN jsonb_typeof(%[1]s) = 'number' THEN CAST(%[1]s #>> '{}' AS numeric) %[2]s CAST(%[3]s AS numeric) ELSE false END",
+				field, operator, q.arg(string(cf.Value)))
+		default:
+			return "", nil, fmt.Errorf("unknown custom field operator %q", cf.Op)
+		}
+	}
+	if len(q.conditions) == 0 {
+		return "TRUE", nil, nil
+	}
+	return "(" + strings.Join(q.conditions, " AND ") + ")", q.args, nil
+}
+
+// minTrigramLength is the shortest search term that can make use of the
+// trigram index. Shorter terms only match word prefixes.
+const minTrigramLength = 3
+
+// memberSearchSQL compiles a free text search into a parameterized SQL condition
+// over the members table, which must be aliased as "m". Every word of the search
+// must be found (case-insensitive) in the names, email, phone or string custom
+// fields of the member: as a word prefix if it is shorter than minTrigramLength
+// and anywhere otherwise. The placeholders are numbered starting at argOffset+1.
+func memberSearchSQL(search string, argOffset int) (string, []interface{}) {
+	q := &filterQuery{offset: argOffset}
+	for _, term := range strings.Fields(search) {
+		pattern := "%" + escapeLike(term) + "%"
+		if len([]rune(term)) < minTrigramLength {
+			pattern = "% " + escapeLike(term) + "%"
+		}
+		q.add("member_search_text(m.first_name, m.last_name, m.email, m.phone, m.custom_fields) LIKE lower(%s)", q.arg(pattern))
+	}
+	if len(q.conditions) == 0 {
+		return "TRUE", nil
+	}
+	return "(" + strings.Join(q.conditions, " AND ") + ")", q.args
+}
+
+// entitySearchSQL compiles a free text search into a parameterized SQL condition
+// over the entities table, which must be aliased as "e". Every word of the
+// search must be found (case-insensitive) in the name or email of the entity,
+// or be a prefix of its hex encoded ID. The placeholders are numbered starting
+// at argOffset+1.
+func entitySearchSQL(search string, argOffset int) (string, []interface{}) {
+	q := &filterQuery{offset: argOffset}
+	for _, term := range strings.Fields(search) {
+		pattern := q.arg("%" + escapeLike(term) + "%")
+		q.add("(e.name ILIKE %[1]s OR e.email ILIKE %[1]s OR encode(e.id, 'hex') LIKE %[2]s)",
+			pattern, q.arg(escapeLike(strings.ToLower(strings.TrimPrefix(term, "0x")))+"%"))
+	}
+	if len(q.conditions) == 0 {
+		return "TRUE", nil
+	}
+	return "(" + strings.Join(q.conditions, " AND ") + ")", q.args
+}
+
+// escapeLike escapes the LIKE wildcards so that they are matched literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
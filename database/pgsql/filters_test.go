@@ -0,0 +1,105 @@
+package pgsql
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"go.vocdoni.io/manager/types"
+)
+
+func TestTargetFiltersSQL(t *testing.T) {
+	c := qt.New(t)
+
+	// empty filters match all the members
+	filters, err := types.ParseTargetFilters(json.RawMessage("{}"))
+	c.Assert(err, qt.IsNil)
+	where, args, err := targetFiltersSQL(filters, 1)
+	c.Assert(err, qt.IsNil)
+	c.Assert(where, qt.Equals, "TRUE")
+	c.Assert(args, qt.HasLen, 0)
+
+	filters, err = types.ParseTargetFilters(json.RawMessage(`{
+		"tags": {"any": [1, 2], "none": [3]},
+		"origins": ["Token", "Form"],
+		"verified": true,
+		"dateOfBirth": {"to": "1990-01-01T00:00:00Z"},
+		"hasPublicKey": false,
+		"customFields": [
+			{"path": "branch.city", "op": "eq", "value": "Barcelona'; DROP TABLE members; --"},
+			{"path": "shares", "op": "gte", "value": 10}
+		]
+	}`))
+	c.Assert(err, qt.IsNil)
+	where, args, err = targetFiltersSQL(filters, 1)
+	c.Assert(err, qt.IsNil)
+	c.Assert(args, qt.HasLen, 10)
+	// placeholders start after the offset
+	c.Assert(regexp.MustCompile(`\$1\b`).MatchString(where), qt.IsFalse)
+	c.Assert(regexp.MustCompile(`\$2\b`).MatchString(where), qt.IsTrue)
+	c.Assert(regexp.MustCompile(`\$11\b`).MatchString(where), qt.IsTrue)
+	c.Assert(regexp.MustCompile(`\$12\b`).MatchString(where), qt.IsFalse)
+	c.Assert(strings.Contains(where, "m.public_key IS NULL"), qt.IsTrue)
+	// values are never part of the query
+	c.Assert(strings.Contains(where, "Barcelona"), qt.IsFalse)
+	c.Assert(strings.Contains(where, "DROP"), qt.IsFalse)
+}
+
+func TestParseTargetFilters(t *testing.T) {
+	c := qt.New(t)
+	for _, invalid := range []string{
+		`{"tag": {"any": [1]}}`,
+		`{"tags": {"any": [0]}}`,
+		`{"origins": ["Unknown"]}`,
+		`{"dateOfBirth": {}}`,
+		`{"dateOfBirth": {"from": "2000-01-01T00:00:00Z", "to": "1990-01-01T00:00:00Z"}}`,
+		`{"customFields": [{"path": "a..b", "op": "exists"}]}`,
+		`{"customFields": [{"path": "a", "op": "eq"}]}`,
+		`{"customFields": [{"path": "a", "op": "in", "value": 1}]}`,
+		`{"customFields": [{"path": "a", "op": "gt", "value": "1"}]}`,
+		`{"customFields": [{"path": "a", "op": "like", "value": "1"}]}`,
+	} {
+		_, err := types.ParseTargetFilters(json.RawMessage(invalid))
+		c.Assert(err, qt.Not(qt.IsNil), qt.Commentf("filters should be invalid: %s", invalid))
+	}
+	for _, valid := range []string{
+		``,
+		`null`,
+		`{"verified": false}`,
+		`{"customFields": [{"path": "a.b", "op": "exists"}]}`,
+		`{"customFields": [{"path": "a", "op": "in", "value": ["x", 1]}]}`,
+	} {
+		_, err := types.ParseTargetFilters(json.RawMessage(valid))
+		c.Assert(err, qt.IsNil, qt.Commentf("filters should be valid: %s", valid))
+	}
+}
+
+func TestMemberSearchSQL(t *testing.T) {
+	c := qt.New(t)
+
+	where, args := memberSearchSQL("  ", 1)
+	c.Assert(where, qt.Equals, "TRUE")
+	c.Assert(args, qt.HasLen, 0)
+
+	// every word is a condition, short words only match word prefixes
+	where, args = memberSearchSQL("Jo 50%_off", 1)
+	c.Assert(regexp.MustCompile(`\$2\b`).MatchString(where), qt.IsTrue)
+	c.Assert(regexp.MustCompile(`\$3\b`).MatchString(where), qt.IsTrue)
+	c.Assert(strings.Contains(where, "Jo"), qt.IsFalse)
+	c.Assert(args, qt.DeepEquals, []interface{}{"% Jo%", `%50\%\_off%`})
+}
+
+func TestEntitySearchSQL(t *testing.T) {
+	c := qt.New(t)
+	where, args := entitySearchSQL("", 0)
+	c.Assert(where, qt.Equals, "TRUE")
+	c.Assert(args, qt.HasLen, 0)
+
+	// every word matches the name, the email or an ID prefix
+	where, args = entitySearchSQL("Vocdoni 0x5FA5", 0)
+	c.Assert(regexp.MustCompile(`\$4\b`).MatchString(where), qt.IsTrue)
+	c.Assert(strings.Contains(where, "Vocdoni"), qt.IsFalse)
+	c.Assert(args, qt.DeepEquals, []interface{}{"%Vocdoni%", "vocdoni%", "%0x5FA5%", "5fa5%"})
+}
@@ -46,6 +46,76 @@ var Migrations = migrate.MemoryMigrationSource{
 			Up:   []string{migration7up},
 			Down: []string{migration7down},
 		},
+		{
+			Id:   "8",
+			Up:   []string{migration8up},
+			Down: []string{migration8down},
+		},
+		{
+			Id:   "9",
+			Up:   []string{migration9up},
+			Down: []string{migration9down},
+		},
+		{
+			Id:   "10",
+			Up:   []string{migration10up},
+			Down: []string{migration10down},
+		},
+		{
+			Id:   "11",
+			Up:   []string{migration11up},
+			Down: []string{migration11down},
+		},
+		{
+			Id:   "12",
+			Up:   []string{migration12up},
+			Down: []string{migration12down},
+		},
+		{
+			Id:   "13",
+			Up:   []string{migration13up},
+			Down: []string{migration13down},
+		},
+		{
+			Id:   "14",
+			Up:   []string{migration14up},
+			Down: []string{migration14down},
+		},
+		{
+			Id:   "15",
+			Up:   []string{migration15up},
+			Down: []string{migration15down},
+		},
+		{
+			Id:   "16",
+			Up:   []string{migration16up},
+			Down: []string{migration16down},
+		},
+		{
+			Id:   "17",
+			Up:   []string{migration17up},
+			Down: []string{migration17down},
+		},
+		{
+			Id:   "18",
+			Up:   []string{migration18up},
+			Down: []string{migration18down},
+		},
+		{
+			Id:   "19",
+			Up:   []string{migration19up},
+			Down: []string{migration19down},
+		},
+		{
+			Id: "20",
+			Up: []string{migration20up},
+			// no down migration since the entities it authorizes cannot be told apart
+		},
+		{
+			Id:   "21",
+			Up:   []string{migration21up},
+			Down: []string{migration21down},
+		},
 	},
 }
 
@@ -357,6 +427,399 @@ ALTER TABLE ONLY entities
     DROP COLUMN consented;
 `
 
+// member_search_text concatenates, lower cased, the member fields that can be
+// searched including the string values found at any depth of the custom fields.
+// A leading space allows matching word prefixes with LIKE '% term%'.
+const migration8up = `
+CREATE EXTENSION IF NOT EXISTS pg_trgm SCHEMA public;
+
+CREATE OR REPLACE FUNCTION member_search_text(first_name text, last_name text, email text, phone text, custom_fields jsonb)
+    RETURNS text
+    LANGUAGE sql IMMUTABLE PARALLEL SAFE
+AS $$
+    SELECT lower(concat_ws(' ', '', first_name, last_name, email, phone,
+        (SELECT string_agg(value, ' ')
+            FROM jsonb_array_elements_text(jsonb_path_query_array(custom_fields, 'strict $.** ? (@.type() == "string")')))))
+$$;
+
+CREATE INDEX members_search_trgm_idx ON members
+    USING gin (member_search_text(first_name, last_name, email, phone, custom_fields) gin_trgm_ops);
+`
+
+const migration8down = `
+DROP INDEX IF EXISTS members_search_trgm_idx;
+DROP FUNCTION IF EXISTS member_search_text(text, text, text, text, jsonb);
+DROP EXTENSION IF EXISTS pg_trgm;
+`
+
+const migration9up = `
+ALTER TABLE ONLY entities
+    ADD COLUMN custom_fields_schema jsonb DEFAULT '[]'::jsonb NOT NULL;
+`
+
+const migration9down = `
+ALTER TABLE ONLY entities
+    DROP COLUMN custom_fields_schema;
+`
+
+// member_events keeps the history of the changes of the members, written by
+// a trigger within the transaction of every change. The operation and the actor
+// are taken from the transaction settings (see beginMemberEvents) and the
+// changes hold the before and after values of every modified column.
+// Members deleted along with their entity are not recorded since the entity
+// history is deleted too.
+const migration10up = `
+CREATE TABLE member_events (
+    id bigserial NOT NULL,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    entity_id bytea NOT NULL,
+    member_id uuid NOT NULL,
+    operation text NOT NULL,
+    actor_type text DEFAULT '' NOT NULL,
+    actor bytea,
+    changes jsonb DEFAULT '{}'::jsonb NOT NULL
+);
+
+ALTER TABLE ONLY member_events
+    ADD CONSTRAINT member_events_pkey PRIMARY KEY (id);
+
+ALTER TABLE ONLY member_events
+    ADD CONSTRAINT member_events_entity_id_fkey FOREIGN KEY (entity_id) REFERENCES entities(id) ON DELETE CASCADE;
+
+CREATE INDEX member_events_entity_id_member_id_idx ON member_events (entity_id, member_id);
+
+CREATE OR REPLACE FUNCTION record_member_event()
+    RETURNS trigger
+    LANGUAGE plpgsql
+AS $$
+DECLARE
+    member_before jsonb := '{}'::jsonb;
+    member_after jsonb := '{}'::jsonb;
+    member_changes jsonb;
+    event_entity_id bytea;
+    event_member_id uuid;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        IF NOT EXISTS (SELECT 1 FROM entities WHERE id = OLD.entity_id) THEN
+            RETURN NULL;
+        END IF;
+        event_entity_id := OLD.entity_id;
+        event_member_id := OLD.id;
+    ELSE
+        event_entity_id := NEW.entity_id;
+        event_member_id := NEW.id;
+    END IF;
+    IF TG_OP <> 'INSERT' THEN
+        member_before := to_jsonb(OLD) - '{id,entity_id,created_at,updated_at}'::text[];
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        member_after := to_jsonb(NEW) - '{id,entity_id,created_at,updated_at}'::text[];
+    END IF;
+    SELECT jsonb_object_agg(k.key, jsonb_build_object('before', member_before -> k.key, 'after', member_after -> k.key))
+        INTO member_changes
+        FROM jsonb_object_keys(member_before || member_after) AS k(key)
+        WHERE (member_before -> k.key) IS DISTINCT FROM (member_after -> k.key);
+    IF member_changes IS NULL THEN
+        RETURN NULL;
+    END IF;
+    INSERT INTO member_events (entity_id, member_id, operation, actor_type, actor, changes)
+        VALUES (event_entity_id, event_member_id,
+            COALESCE(NULLIF(current_setting('manager.member_event_operation', true), ''),
+                CASE TG_OP WHEN 'INSERT' THEN 'create' WHEN 'UPDATE' THEN 'update' ELSE 'delete' END),
+            COALESCE(current_setting('manager.member_event_actor_type', true), ''),
+            decode(NULLIF(current_setting('manager.member_event_actor', true), ''), 'hex'),
+            member_changes);
+    RETURN NULL;
+END;
+$$;
+
+CREATE TRIGGER members_record_event
+    AFTER INSERT OR UPDATE OR DELETE ON members
+    FOR EACH ROW EXECUTE FUNCTION record_member_event();
+`
+
+const migration10down = `
+DROP TRIGGER IF EXISTS members_record_event ON members;
+DROP FUNCTION IF EXISTS record_member_event();
+DROP TABLE member_events;
+`
+
+// Deleted members are kept with deleted_at set until they are purged, so the
+// unique email and public key only apply to the non deleted members
+const migration11up = `
+ALTER TABLE ONLY members
+    ADD COLUMN deleted_at timestamp with time zone,
+    DROP CONSTRAINT members_entity_id_email_unique,
+    DROP CONSTRAINT members_entity_id_public_key_unique;
+
+CREATE UNIQUE INDEX members_entity_id_email_unique ON members (entity_id, email) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX members_entity_id_public_key_unique ON members (entity_id, public_key) WHERE deleted_at IS NULL;
+CREATE INDEX members_deleted_at_idx ON members (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- purging a member records the event but not the member data
+CREATE OR REPLACE FUNCTION record_member_event()
+    RETURNS trigger
+    LANGUAGE plpgsql
+AS $$
+DECLARE
+    member_before jsonb := '{}'::jsonb;
+    member_after jsonb := '{}'::jsonb;
+    member_changes jsonb;
+    event_entity_id bytea;
+    event_member_id uuid;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        IF NOT EXISTS (SELECT 1 FROM entities WHERE id = OLD.entity_id) THEN
+            RETURN NULL;
+        END IF;
+        event_entity_id := OLD.entity_id;
+        event_member_id := OLD.id;
+    ELSE
+        event_entity_id := NEW.entity_id;
+        event_member_id := NEW.id;
+    END IF;
+    IF TG_OP <> 'INSERT' THEN
+        member_before := to_jsonb(OLD) - '{id,entity_id,created_at,updated_at}'::text[];
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        member_after := to_jsonb(NEW) - '{id,entity_id,created_at,updated_at}'::text[];
+    END IF;
+    SELECT jsonb_object_agg(k.key, jsonb_build_object('before', member_before -> k.key, 'after', member_after -> k.key))
+        INTO member_changes
+        FROM jsonb_object_keys(member_before || member_after) AS k(key)
+        WHERE (member_before -> k.key) IS DISTINCT FROM (member_after -> k.key);
+    IF TG_OP = 'DELETE' AND current_setting('manager.member_event_operation', true) = 'purge' THEN
+        member_changes := '{}'::jsonb;
+    ELSIF member_changes IS NULL THEN
+        RETURN NULL;
+    END IF;
+    INSERT INTO member_events (entity_id, member_id, operation, actor_type, actor, changes)
+        VALUES (event_entity_id, event_member_id,
+            COALESCE(NULLIF(current_setting('manager.member_event_operation', true), ''),
+                CASE TG_OP WHEN 'INSERT' THEN 'create' WHEN 'UPDATE' THEN 'update' ELSE 'delete' END),
+            COALESCE(current_setting('manager.member_event_actor_type', true), ''),
+            decode(NULLIF(current_setting('manager.member_event_actor', true), ''), 'hex'),
+            member_changes);
+    RETURN NULL;
+END;
+$$;
+`
+
+const migration11down = `
+DELETE FROM members WHERE deleted_at IS NOT NULL;
+DROP INDEX members_deleted_at_idx;
+DROP INDEX members_entity_id_email_unique;
+DROP INDEX members_entity_id_public_key_unique;
+ALTER TABLE ONLY members
+    DROP COLUMN deleted_at,
+    ADD CONSTRAINT members_entity_id_email_unique UNIQUE (entity_id, email),
+    ADD CONSTRAINT members_entity_id_public_key_unique UNIQUE (entity_id, public_key);
+`
+
+// Tags get a description and a colour, and members.tags is indexed with the
+// intarray operator class so that tag lookups and counts do not scan members
+const migration12up = `
+ALTER TABLE ONLY tags
+    ADD COLUMN description text NOT NULL DEFAULT '',
+    ADD COLUMN color text NOT NULL DEFAULT '';
+CREATE INDEX members_tags_idx ON members USING gin (tags gin__int_ops);
+`
+
+const migration12down = `
+DROP INDEX members_tags_idx;
+ALTER TABLE ONLY tags
+    DROP COLUMN description,
+    DROP COLUMN color;
+`
+
+// Erased members are anonymized and deleted but never purged, so that the
+// census rows referencing them are kept. member_emails records the emails
+// sent to the members in order to report them on subject access requests.
+const migration13up = `
+ALTER TABLE ONLY members
+    ADD COLUMN erased_at timestamp with time zone;
+
+CREATE TABLE member_emails (
+    id bigserial NOT NULL,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    entity_id bytea NOT NULL,
+    member_id uuid NOT NULL,
+    type text NOT NULL,
+    census_id bytea,
+    process_id bytea
+);
+
+ALTER TABLE ONLY member_emails
+    ADD CONSTRAINT member_emails_pkey PRIMARY KEY (id);
+
+ALTER TABLE ONLY member_emails
+    ADD CONSTRAINT member_emails_member_id_fkey FOREIGN KEY (member_id) REFERENCES members(id) ON DELETE CASCADE;
+
+CREATE INDEX member_emails_entity_id_member_id_idx ON member_emails (entity_id, member_id);
+`
+
+const migration13down = `
+DROP TABLE member_emails;
+ALTER TABLE ONLY members
+    DROP COLUMN erased_at;
+`
+
+// Entities define their data retention settings, and censuses keep the
+// process that uses them and when it ends in order to enforce them
+const migration14up = `
+ALTER TABLE ONLY entities
+    ADD COLUMN retention jsonb DEFAULT '{}'::jsonb NOT NULL;
+ALTER TABLE ONLY censuses
+    ADD COLUMN process_id bytea,
+    ADD COLUMN process_end_date timestamp with time zone;
+`
+
+const migration14down = `
+ALTER TABLE ONLY entities
+    DROP COLUMN retention;
+ALTER TABLE ONLY censuses
+    DROP COLUMN process_id,
+    DROP COLUMN process_end_date;
+`
+
+// The census managers addresses of an entity are its operators, each one
+// with the role at the same position. The existing ones become admins.
+const migration15up = `
+CREATE TYPE operator_role AS ENUM (
+    'admin',
+    'editor',
+    'viewer'
+);
+ALTER TABLE ONLY entities
+    ADD COLUMN census_managers_roles operator_role[] DEFAULT '{}' NOT NULL;
+UPDATE entities SET census_managers_roles = array_fill('admin'::operator_role, ARRAY[cardinality(census_managers_addresses)]);
+ALTER TABLE ONLY entities
+    ADD CONSTRAINT entities_census_managers_roles_check CHECK (cardinality(census_managers_roles) = cardinality(census_managers_addresses));
+CREATE INDEX entities_census_managers_addresses_idx ON entities USING gin (census_managers_addresses);
+`
+
+const migration15down = `
+DROP INDEX entities_census_managers_addresses_idx;
+ALTER TABLE ONLY entities
+    DROP COLUMN census_managers_roles;
+DROP TYPE operator_role;
+`
+
+// Entities have quotas overriding the default ones, and the faucet requests
+// are recorded in order to meter them along with the emails sent
+const migration16up = `
+ALTER TABLE ONLY entities
+    ADD COLUMN quotas jsonb DEFAULT '{}'::jsonb NOT NULL;
+
+CREATE TABLE faucet_requests (
+    id bigserial NOT NULL,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    entity_id bytea NOT NULL
+);
+
+ALTER TABLE ONLY faucet_requests
+    ADD CONSTRAINT faucet_requests_pkey PRIMARY KEY (id);
+
+ALTER TABLE ONLY faucet_requests
+    ADD CONSTRAINT faucet_requests_entity_id_fkey FOREIGN KEY (entity_id) REFERENCES entities(id) ON DELETE CASCADE;
+
+CREATE INDEX faucet_requests_entity_id_created_at_idx ON faucet_requests (entity_id, created_at);
+CREATE INDEX member_emails_entity_id_created_at_idx ON member_emails (entity_id, created_at);
+`
+
+const migration16down = `
+DROP INDEX member_emails_entity_id_created_at_idx;
+DROP TABLE faucet_requests;
+ALTER TABLE ONLY entities
+    DROP COLUMN quotas;
+`
+
+// Censuses have a weight source, NULL meaning that every member weighs 1,
+// and the census members have the weight computed from it
+const migration17up = `
+ALTER TABLE ONLY censuses
+    ADD COLUMN weight jsonb;
+ALTER TABLE ONLY census_members
+    ADD COLUMN weight bigint DEFAULT 1 NOT NULL;
+ALTER TABLE ONLY census_members
+    ADD CONSTRAINT census_members_weight_check CHECK (weight > 0);
+`
+
+const migration17down = `
+ALTER TABLE ONLY census_members
+    DROP COLUMN weight;
+ALTER TABLE ONLY censuses
+    DROP COLUMN weight;
+`
+
+// Censuses have a lifecycle state. The existing censuses with members are
+// considered expanded.
+const migration18up = `
+CREATE TYPE census_state AS ENUM (
+    'draft',
+    'expanded',
+    'published',
+    'closed',
+    'archived'
+);
+ALTER TABLE ONLY censuses
+    ADD COLUMN state census_state DEFAULT 'draft' NOT NULL;
+UPDATE censuses c SET state = 'expanded'
+    WHERE EXISTS (SELECT 1 FROM census_members cm WHERE cm.census_id = c.id);
+CREATE INDEX censuses_entity_id_state_idx ON censuses (entity_id, state);
+`
+
+const migration18down = `
+DROP INDEX censuses_entity_id_state_idx;
+ALTER TABLE ONLY censuses
+    DROP COLUMN state;
+DROP TYPE census_state;
+`
+
+// Census members whose claim was reported as invalid are flagged with the reason
+const migration19up = `
+ALTER TABLE ONLY census_members
+    ADD COLUMN invalid_reason text DEFAULT '' NOT NULL;
+`
+
+const migration19down = `
+ALTER TABLE ONLY census_members
+    DROP COLUMN invalid_reason;
+`
+
+// Entities pending approval are limited to a sandbox, so the entities that
+// existed before are authorized and only the new ones start in the sandbox
+const migration20up = `
+UPDATE entities SET is_authorized = true WHERE is_authorized = false;
+`
+
+// The emails sent by each entity are metered per UTC day in a table that is
+// not affected by the deletion of the members, seeded from member_emails
+const migration21up = `
+CREATE TABLE entity_email_usage (
+    entity_id bytea NOT NULL,
+    day date NOT NULL,
+    sent integer DEFAULT 0 NOT NULL
+);
+
+ALTER TABLE ONLY entity_email_usage
+    ADD CONSTRAINT entity_email_usage_pkey PRIMARY KEY (entity_id, day);
+
+ALTER TABLE ONLY entity_email_usage
+    ADD CONSTRAINT entity_email_usage_entity_id_fkey FOREIGN KEY (entity_id) REFERENCES entities(id) ON DELETE CASCADE;
+
+INSERT INTO entity_email_usage (entity_id, day, sent)
+    SELECT entity_id, (created_at AT TIME ZONE 'UTC')::date, COUNT(*)
+    FROM member_emails GROUP BY 1, 2;
+
+DROP INDEX member_emails_entity_id_created_at_idx;
+`
+
+const migration21down = `
+CREATE INDEX member_emails_entity_id_created_at_idx ON member_emails (entity_id, created_at);
+DROP TABLE entity_email_usage;
+`
+
 func Migrator(action string, db database.Database) error {
 	switch action {
 	case "upSync":
@@ -0,0 +1,45 @@
+package pgsql
+
+import (
+	"fmt"
+	"strings"
+
+	"go.vocdoni.io/manager/types"
+)
+
+// memberPatchSQL compiles a member patch into the parameterized SET clause of
+// an update over the members table, which must be aliased as "m". Custom
+// fields are merged into the current ones, removing those set to null.
+// The placeholders are numbered starting at argOffset+1.
+func memberPatchSQL(patch *types.MemberPatch, argOffset int) (string, []interface{}, error) {
+	if patch == nil || patch.IsEmpty() {
+		return "", nil, fmt.Errorf("empty patch")
+	}
+	q := &filterQuery{offset: argOffset}
+	for _, field := range []struct {
+		column string
+		value  *string
+	}{
+		{"first_name", patch.FirstName},
+		{"last_name", patch.LastName},
+		{"phone", patch.Phone},
+		{"street_address", patch.StreetAddress},
+	} {
+		if field.value != nil {
+			q.add("%s = %s", field.column, q.arg(*field.value))
+		}
+	}
+	if patch.DateOfBirth != nil {
+		q.add("date_of_birth = %s", q.arg(*patch.DateOfBirth))
+	}
+	if patch.Consented != nil {
+		q.add("consented = %s", q.arg(*patch.Consented))
+	}
+	if len(patch.CustomFields) > 0 {
+		fields := q.arg(string(patch.CustomFields))
+		q.add("custom_fields = (m.custom_fields || CAST(%[1]s AS jsonb)) - "+
+			"ARRAY(SELECT f.key FROM jsonb_each(CAST(%[1]s AS jsonb)) AS f WHERE f.value = 'null'::jsonb)", fields)
+	}
+	q.add("updated_at = now()")
+	return strings.Join(q.conditions, ", "), q.args, nil
+}
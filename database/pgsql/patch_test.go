@@ -0,0 +1,36 @@
+package pgsql
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"go.vocdoni.io/manager/types"
+)
+
+func TestMemberPatchSQL(t *testing.T) {
+	c := qt.New(t)
+
+	// empty patches are rejected
+	_, _, err := memberPatchSQL(nil, 1)
+	c.Assert(err, qt.Not(qt.IsNil))
+	_, _, err = memberPatchSQL(&types.MemberPatch{}, 1)
+	c.Assert(err, qt.Not(qt.IsNil))
+
+	name := "John'; DROP TABLE members; --"
+	consented := false
+	set, args, err := memberPatchSQL(&types.MemberPatch{
+		FirstName:    &name,
+		Consented:    &consented,
+		CustomFields: json.RawMessage(`{"shares": 10, "branch": null}`),
+	}, 2)
+	c.Assert(err, qt.IsNil)
+	c.Assert(args, qt.DeepEquals, []interface{}{name, false, `{"shares": 10, "branch": null}`})
+	c.Assert(set, qt.Equals, "first_name = $3, consented = $4, "+
+		"custom_fields = (m.custom_fields || CAST($5 AS jsonb)) - "+
+		"ARRAY(SELECT f.key FROM jsonb_each(CAST($5 AS jsonb)) AS f WHERE f.value = 'null'::jsonb), "+
+		"updated_at = now()")
+	// values are never part of the query
+	c.Assert(strings.Contains(set, "DROP"), qt.IsFalse)
+}
@@ -10,7 +10,6 @@ import (
 	"fmt"
 	"reflect"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -24,6 +23,7 @@ import (
 	"go.vocdoni.io/dvote/log"
 
 	"go.vocdoni.io/manager/config"
+	"go.vocdoni.io/manager/database"
 	"go.vocdoni.io/manager/types"
 	"go.vocdoni.io/manager/util"
 )
@@ -32,6 +32,9 @@ const connectionRetries = 5
 
 type Database struct {
 	db *sqlx.DB
+	// operator is recorded as the actor of the member events instead of the
+	// entity, see AsOperator
+	operator []byte
 	// For using pgx connector
 	// pgx    *pgxpool.Pool
 	// pgxCtx context.Context
@@ -106,8 +109,9 @@ func (d *Database) AddEntity(entityID []byte, info *types.EntityInfo) error {
 	}
 	// TODO: Calculate EntityID (consult go-dvote)
 	insert := `INSERT INTO entities
-			(id, is_authorized, email, name, type, size, consented, callback_url, callback_secret, census_managers_addresses, created_at, updated_at)
-			VALUES (:id, :is_authorized, :email, :name, :type, :size, :consented, :callback_url, :callback_secret, :pg_census_managers_addresses, :created_at, :updated_at)`
+			(id, is_authorized, email, name, type, size, consented, callback_url, callback_secret, census_managers_addresses, census_managers_roles, created_at, updated_at)
+			VALUES (:id, :is_authorized, :email, :name, :type, :size, :consented, :callback_url, :callback_secret, :pg_census_managers_addresses,
+			array_fill('admin'::::operator_role, ARRAY[cardinality(CAST(:pg_census_managers_addresses AS bytea[]))]), :created_at, :updated_at)`
 	_, err = tx.NamedExec(insert, pgEntity)
 	if err != nil {
 		return fmt.Errorf("cannot add insert query in the transaction: %w", err)
@@ -132,7 +136,8 @@ func (d *Database) AddEntity(entityID []byte, info *types.EntityInfo) error {
 
 func (d *Database) Entity(entityID []byte) (*types.Entity, error) {
 	var pgEntity PGEntity
-	selectEntity := `SELECT id, is_authorized, email, name, type, size, consented, callback_url, callback_secret, census_managers_addresses as "pg_census_managers_addresses"  
+	selectEntity := `SELECT id, is_authorized, email, name, type, size, consented, callback_url, callback_secret, census_managers_addresses as "pg_census_managers_addresses",
+						custom_fields_schema as "pg_custom_fields_schema", retention as "pg_retention", quotas as "pg_quotas"
 						FROM entities WHERE id=$1`
 	row := d.db.QueryRowx(selectEntity, entityID)
 	err := row.StructScan(&pgEntity)
@@ -176,6 +181,23 @@ func (d *Database) DeleteEntity(entityID []byte) error {
 	return nil
 }
 
+// CountActiveProcessCensuses counts the censuses of the entity used by a
+// process that ended after since. Processes with an unknown end date are
+// still active.
+func (d *Database) CountActiveProcessCensuses(entityID []byte, since time.Time) (int, error) {
+	if len(entityID) == 0 {
+		return 0, fmt.Errorf("invalid arguments")
+	}
+	var count int
+	countQuery := `SELECT COUNT(*) FROM censuses
+					WHERE entity_id = $1 AND process_id IS NOT NULL AND length(process_id) > 0
+						AND (process_end_date IS NULL OR process_end_date > $2)`
+	if err := d.db.Get(&count, countQuery, entityID, since); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
 // EntitiesID returns all the entities ID's
 func (d *Database) EntitiesID() ([]string, error) {
 	var entitiesIDs [][]byte
@@ -229,12 +251,16 @@ func (d *Database) UpdateEntity(entityID []byte, info *types.EntityInfo) (int, e
 				callback_url = :callback_url,
 				callback_secret = :callback_secret,
 				email = COALESCE(NULLIF(:email, ''), email),
+				custom_fields_schema = COALESCE(CAST(:pg_custom_fields_schema AS jsonb), custom_fields_schema),
+				retention = COALESCE(CAST(:pg_retention AS jsonb), retention),
 				updated_at = now()
 				WHERE (id = :id )
 				AND  (:name IS DISTINCT FROM name OR
 				:callback_url IS DISTINCT FROM callback_url OR
 				:callback_secret IS DISTINCT FROM callback_secret OR
-				:email IS DISTINCT FROM email)`
+				:email IS DISTINCT FROM email OR
+				COALESCE(CAST(:pg_custom_fields_schema AS jsonb), custom_fields_schema) IS DISTINCT FROM custom_fields_schema OR
+				COALESCE(CAST(:pg_retention AS jsonb), retention) IS DISTINCT FROM retention)`
 	result, err := d.db.NamedExec(update, pgentity)
 	if err != nil {
 		return 0, fmt.Errorf("error updating entity: %w", err)
@@ -248,6 +274,188 @@ func (d *Database) UpdateEntity(entityID []byte, info *types.EntityInfo) (int, e
 	return int(rows), nil
 }
 
+// SetEntityQuotas replaces the quotas of the entity
+func (d *Database) SetEntityQuotas(entityID []byte, quotas *types.Quotas) (int, error) {
+	if len(entityID) == 0 || quotas == nil {
+		return 0, fmt.Errorf("invalid arguments")
+	}
+	var pgQuotas pgtype.JSONB
+	if err := pgQuotas.Set(quotas); err != nil {
+		return 0, fmt.Errorf("cannot convert quotas to postgres types: %w", err)
+	}
+	update := `UPDATE entities SET quotas = $2, updated_at = now() WHERE id = $1`
+	result, err := d.db.Exec(update, entityID, pgQuotas)
+	if err != nil {
+		return 0, fmt.Errorf("error updating entity quotas: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("cannot get affected rows: %w", err)
+	}
+	return int(rows), nil
+}
+
+// EntityUsage returns the current consumption of the entity, counting the
+// emails reserved on the UTC day of day and the faucet requests since month
+func (d *Database) EntityUsage(entityID []byte, day, month time.Time) (*types.Usage, error) {
+	if len(entityID) == 0 {
+		return nil, fmt.Errorf("invalid arguments")
+	}
+	var usage types.Usage
+	selectQuery := `SELECT
+		(SELECT COUNT(*) FROM members WHERE entity_id = $1 AND deleted_at IS NULL) AS members,
+		(SELECT COUNT(*) FROM censuses WHERE entity_id = $1) AS censuses,
+		(SELECT COALESCE(SUM(sent), 0) FROM entity_email_usage
+			WHERE entity_id = $1 AND day = (CAST($2 AS timestamptz) AT TIME ZONE 'UTC')::date) AS emails_today,
+		(SELECT COUNT(*) FROM faucet_requests WHERE entity_id = $1 AND created_at >= $3) AS faucet_requests_this_month`
+	if err := d.db.Get(&usage, selectQuery, entityID, day, month); err != nil {
+		return nil, err
+	}
+	return &usage, nil
+}
+
+// ReserveEmails atomically adds count emails to the ones sent by the entity
+// on the UTC day of day, unless they would exceed limit. A zero limit is
+// unlimited. It returns whether the emails were reserved.
+func (d *Database) ReserveEmails(entityID []byte, day time.Time, count, limit int) (bool, error) {
+	if len(entityID) == 0 || count < 0 || limit < 0 {
+		return false, fmt.Errorf("invalid arguments")
+	}
+	upsert := `INSERT INTO entity_email_usage (entity_id, day, sent)
+				SELECT $1, (CAST($2 AS timestamptz) AT TIME ZONE 'UTC')::date, CAST($3 AS integer)
+				WHERE CAST($4 AS integer) = 0 OR $3 <= $4
+				ON CONFLICT (entity_id, day) DO UPDATE SET sent = entity_email_usage.sent + EXCLUDED.sent
+				WHERE $4 = 0 OR entity_email_usage.sent + EXCLUDED.sent <= $4
+				RETURNING sent`
+	var sent int
+	if err := d.db.QueryRowx(upsert, entityID, day, count, limit).Scan(&sent); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, fmt.Errorf("error reserving emails: %w", err)
+	}
+	return true, nil
+}
+
+// ReleaseEmails gives back count emails reserved by the entity on the UTC
+// day of day that were not sent
+func (d *Database) ReleaseEmails(entityID []byte, day time.Time, count int) error {
+	if len(entityID) == 0 || count < 0 {
+		return fmt.Errorf("invalid arguments")
+	}
+	update := `UPDATE entity_email_usage SET sent = GREATEST(sent - $3, 0)
+				WHERE entity_id = $1 AND day = (CAST($2 AS timestamptz) AT TIME ZONE 'UTC')::date`
+	if _, err := d.db.Exec(update, entityID, day, count); err != nil {
+		return fmt.Errorf("error releasing emails: %w", err)
+	}
+	return nil
+}
+
+// AddFaucetRequest records a faucet request of the entity
+func (d *Database) AddFaucetRequest(entityID []byte) error {
+	if len(entityID) == 0 {
+		return fmt.Errorf("invalid arguments")
+	}
+	if _, err := d.db.Exec(`INSERT INTO faucet_requests (entity_id) VALUES ($1)`, entityID); err != nil {
+		return fmt.Errorf("error adding faucet request: %w", err)
+	}
+	return nil
+}
+
+// EntityCustomFieldsSchema returns the schema of the custom fields of the
+// members of the entity
+func (d *Database) EntityCustomFieldsSchema(entityID []byte) (types.CustomFieldsSchema, error) {
+	var schema pgtype.JSONB
+	selectQuery := `SELECT custom_fields_schema FROM entities WHERE id = $1`
+	if err := d.db.QueryRowx(selectQuery, entityID).Scan(&schema); err != nil {
+		return nil, err
+	}
+	var customFieldsSchema types.CustomFieldsSchema
+	if err := schema.AssignTo(&customFieldsSchema); err != nil {
+		return nil, fmt.Errorf("cannot decode custom fields schema: %w", err)
+	}
+	return customFieldsSchema, nil
+}
+
+// EntityOperators returns the census managers addresses of the entity along
+// with their roles
+func (d *Database) EntityOperators(entityID []byte) ([]types.Operator, error) {
+	var operators []types.Operator
+	selectQuery := `SELECT o.address, o.role FROM entities e,
+					unnest(e.census_managers_addresses, e.census_managers_roles) WITH ORDINALITY AS o(address, role, position)
+					WHERE e.id = $1 ORDER BY o.position`
+	if err := d.db.Select(&operators, selectQuery, entityID); err != nil {
+		return nil, err
+	}
+	return operators, nil
+}
+
+// EntityOperatorRole returns the role of the address in the entity, or
+// sql.ErrNoRows if it is not one of its operators
+func (d *Database) EntityOperatorRole(entityID, address []byte) (types.OperatorRole, error) {
+	var role types.OperatorRole
+	selectQuery := `SELECT census_managers_roles[array_position(census_managers_addresses, $2)] FROM entities
+					WHERE id = $1 AND $2 = ANY(census_managers_addresses)`
+	if err := d.db.Get(&role, selectQuery, entityID, address); err != nil {
+		return "", err
+	}
+	return role, nil
+}
+
+// SetEntityOperator adds the address as an operator of the entity with the
+// given role, or updates its role if it already is one
+func (d *Database) SetEntityOperator(entityID, address []byte, role types.OperatorRole) error {
+	if len(entityID) == 0 || len(address) == 0 || !role.Valid() {
+		return fmt.Errorf("invalid arguments")
+	}
+	tx, err := d.db.Beginx()
+	if err != nil {
+		return fmt.Errorf("cannot initialize postgres transaction: %w", err)
+	}
+	defer tx.Rollback()
+	// lock the entity so that the address is not added twice
+	var position sql.NullInt64
+	if err := tx.Get(&position, `SELECT array_position(census_managers_addresses, $2) FROM entities WHERE id = $1 FOR UPDATE`,
+		entityID, address); err != nil {
+		return err
+	}
+	if position.Valid {
+		_, err = tx.Exec(`UPDATE entities SET census_managers_roles[$2] = $3, updated_at = now() WHERE id = $1`,
+			entityID, position.Int64, string(role))
+	} else {
+		_, err = tx.Exec(`UPDATE entities SET census_managers_addresses = array_append(census_managers_addresses, $2),
+				census_managers_roles = array_append(census_managers_roles, CAST($3 AS operator_role)), updated_at = now()
+				WHERE id = $1`, entityID, address, string(role))
+	}
+	if err != nil {
+		return fmt.Errorf("error setting entity operator: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commiting transactions to the DB: %w", err)
+	}
+	return nil
+}
+
+// RemoveEntityOperator removes the address from the operators of the entity,
+// returning the number of updated entities
+func (d *Database) RemoveEntityOperator(entityID, address []byte) (int, error) {
+	update := `UPDATE entities e SET (census_managers_addresses, census_managers_roles) = (
+					SELECT COALESCE(array_agg(o.address ORDER BY o.position), '{}'), COALESCE(array_agg(o.role ORDER BY o.position), '{}')
+					FROM unnest(e.census_managers_addresses, e.census_managers_roles) WITH ORDINALITY AS o(address, role, position)
+					WHERE o.address <> $2),
+				updated_at = now()
+				WHERE e.id = $1 AND $2 = ANY(e.census_managers_addresses)`
+	result, err := d.db.Exec(update, entityID, address)
+	if err != nil {
+		return 0, fmt.Errorf("error removing entity operator: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("cannot get affected rows: %w", err)
+	}
+	return int(rows), nil
+}
+
 func (d *Database) EntityOrigins(entityID []byte) ([]types.Origin, error) {
 	var stringOrigins []string
 	selectOrigins := `SELECT origin FROM entities_origins WHERE entity_id=$1`
@@ -286,6 +494,63 @@ func (d *Database) AdminEntityList() ([]types.Entity, error) {
 	return entities, nil
 }
 
+// CountEntities counts the entities that match the search, or all of them if
+// the search is empty
+func (d *Database) CountEntities(search string) (int, error) {
+	searchWhere, searchArgs := entitySearchSQL(search, 0)
+	var count int
+	if err := d.db.Get(&count, `SELECT COUNT(*) FROM entities e WHERE `+searchWhere, searchArgs...); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// ListEntities returns the entities matching the search of the list options,
+// along with their members and censuses counts, sorted and paginated like
+// ListMembers.
+func (d *Database) ListEntities(filter *types.ListOptions) ([]types.Entity, string, error) {
+	page, err := newListPage(filter, reflect.TypeOf(types.Entity{}), "name", "id", "bytea")
+	if err != nil {
+		return nil, "", err
+	}
+	var search string
+	if filter != nil {
+		search = filter.Search
+	}
+	searchWhere, searchArgs := entitySearchSQL(search, 0)
+	cursorWhere, cursorArgs := page.where(len(searchArgs))
+	orderLimit, limitArgs := page.orderLimit(len(searchArgs) + len(cursorArgs))
+	query := `SELECT e.id, e.is_authorized, e.email, e.name, e.type, e.size, e.consented, e.created_at, e.updated_at,
+					(SELECT COUNT(*) FROM members m WHERE m.entity_id = e.id AND m.deleted_at IS NULL) AS members_count,
+					(SELECT COUNT(*) FROM censuses c WHERE c.entity_id = e.id) AS censuses_count,
+					` + page.cursorColumns() + `
+					FROM entities e
+					WHERE ` + searchWhere + ` AND ` + cursorWhere + `
+					` + orderLimit
+	args := append(append(searchArgs, cursorArgs...), limitArgs...)
+	var rows []struct {
+		types.Entity
+		CursorValue string `db:"cursor_value"`
+		CursorID    string `db:"cursor_id"`
+	}
+	if err := d.db.Select(&rows, query, args...); err != nil {
+		return nil, "", err
+	}
+	var next string
+	if page.hasNext(len(rows)) {
+		rows = rows[:page.limit]
+		last := rows[len(rows)-1]
+		if next, err = page.nextCursor(last.CursorValue, last.CursorID); err != nil {
+			return nil, "", err
+		}
+	}
+	entities := make([]types.Entity, len(rows))
+	for i, row := range rows {
+		entities[i] = row.Entity
+	}
+	return entities, next, nil
+}
+
 func (d *Database) AddUser(user *types.User) error {
 	if user.PubKey == nil {
 		return fmt.Errorf("invalid public Key")
@@ -346,7 +611,7 @@ func (d *Database) CreateMembersWithTokens(entityID []byte, tokens []uuid.UUID)
 		}
 	}
 
-	tx, err := d.db.Beginx()
+	tx, err := d.beginMemberEvents(types.MemberEventCreate, types.MemberEventActorEntity, entityID)
 	if err != nil {
 		return fmt.Errorf("cannot initialize postgres transaction: %w", err)
 	}
@@ -383,7 +648,7 @@ func (d *Database) AddMember(entityID []byte, pubKey []byte, info *types.MemberI
 	var result *sqlx.Rows
 	var id uuid.UUID
 	member := &types.Member{EntityID: entityID, PubKey: pubKey, MemberInfo: *info}
-	tx, err = d.db.Beginx()
+	tx, err = d.beginMemberEvents(types.MemberEventCreate, types.MemberEventActorUser, pubKey)
 	if err != nil {
 		return uuid.Nil, fmt.Errorf("cannot initialize postgres transaction: %w", err)
 	}
@@ -503,7 +768,7 @@ func (d *Database) ImportMembersWithPubKey(entityID []byte, info []types.MemberI
 		members = append(members, *pgMember)
 	}
 
-	tx, err := d.db.Beginx()
+	tx, err := d.beginMemberEvents(types.MemberEventCreate, types.MemberEventActorEntity, entityID)
 	if err != nil {
 		return fmt.Errorf("cannot initialize postgres transaction: %w", err)
 	}
@@ -522,6 +787,27 @@ func (d *Database) ImportMembersWithPubKey(entityID []byte, info []types.MemberI
 	return nil
 }
 
+// ExistingMemberEmails returns which of the given emails already belong
+// to a member of the entity
+func (d *Database) ExistingMemberEmails(entityID []byte, emails []string) ([]string, error) {
+	if len(entityID) == 0 {
+		return nil, fmt.Errorf("invalid arguments")
+	}
+	if len(emails) == 0 {
+		return nil, nil
+	}
+	var pgEmails pgtype.TextArray
+	if err := pgEmails.Set(emails); err != nil {
+		return nil, fmt.Errorf("cannot convert emails: %w", err)
+	}
+	selectQuery := `SELECT email FROM members WHERE entity_id = $1 AND email = ANY($2) AND deleted_at IS NULL`
+	var existing []string
+	if err := d.db.Select(&existing, selectQuery, entityID, pgEmails); err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
 func (d *Database) ImportMembers(entityID []byte, info []types.MemberInfo) error {
 	// TODO: Check if support for Update a Member is needed
 	// TODO: Investigate COPY FROM with pgx
@@ -569,7 +855,7 @@ func (d *Database) ImportMembers(entityID []byte, info []types.MemberInfo) error
 	// if count != int64(len(info)) {
 	// 	return fmt.Errorf("Bulk insert members error. Needed to insert %d members but insterted %d members", len(info), count)
 	// }
-	tx, err := d.db.Beginx()
+	tx, err := d.beginMemberEvents(types.MemberEventCreate, types.MemberEventActorEntity, entityID)
 	if err != nil {
 		return fmt.Errorf("cannot initialize postgres transaction: %w", err)
 	}
@@ -605,7 +891,7 @@ func (d *Database) AddMemberBulk(entityID []byte, members []types.Member) error
 	if len(members) <= 0 {
 		return fmt.Errorf("no member data provided")
 	}
-	tx, err := d.db.Beginx()
+	tx, err := d.beginMemberEvents(types.MemberEventCreate, types.MemberEventActorEntity, entityID)
 	if err != nil {
 		return fmt.Errorf("cannot initialize postgres transaction: %w", err)
 	}
@@ -676,16 +962,23 @@ func (d *Database) UpdateMember(entityID []byte, memberID *uuid.UUID, info *type
 				email = COALESCE(:pg_email, email),
 				date_of_birth = COALESCE(NULLIF(:date_of_birth, date_of_birth), date_of_birth),
 				tags = COALESCE(:pg_tags, CAST(tags as int[])),
+				custom_fields = COALESCE(NULLIF(CAST(:pg_custom_fields AS jsonb), 'null'::jsonb), custom_fields),
 				updated_at = now()
-				WHERE (id = :id AND entity_id = :entity_id)
+				WHERE (id = :id AND entity_id = :entity_id AND deleted_at IS NULL)
 				AND  (:street_address IS DISTINCT FROM street_address OR
 				:first_name IS DISTINCT FROM first_name OR
 				:last_name IS DISTINCT FROM last_name OR
 				:pg_email IS DISTINCT FROM email OR
 				:date_of_birth IS DISTINCT FROM date_of_birth OR
-				:pg_tags  IS DISTINCT FROM tags)`
+				:pg_tags  IS DISTINCT FROM tags OR
+				COALESCE(NULLIF(CAST(:pg_custom_fields AS jsonb), 'null'::jsonb), custom_fields) IS DISTINCT FROM custom_fields)`
+	tx, err := d.beginMemberEvents(types.MemberEventUpdate, types.MemberEventActorEntity, entityID)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
 	var result sql.Result
-	if result, err = d.db.NamedExec(update, pgmember); err != nil {
+	if result, err = tx.NamedExec(update, pgmember); err != nil {
 		return 0, fmt.Errorf("error updating member: %w", err)
 	}
 	var rows int64
@@ -694,6 +987,110 @@ func (d *Database) UpdateMember(entityID []byte, memberID *uuid.UUID, info *type
 	} else if rows != 1 && rows != 0 { /* Nothing to update? */
 		return int(rows), fmt.Errorf("expected to update 0 or 1 rows, but updated %d rows", rows)
 	}
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error commiting member update to the DB: %w", err)
+	}
+	return int(rows), nil
+}
+
+// UpdateMembers applies the patch to the given members in a single transaction.
+// len(members) - updated - len(invalidIDs) = duplicates
+func (d *Database) UpdateMembers(entityID []byte, members []uuid.UUID, patch *types.MemberPatch) (int, []uuid.UUID, error) {
+	var invalidTokens []uuid.UUID
+	var updated int
+	if len(entityID) == 0 {
+		return updated, invalidTokens, fmt.Errorf("invalid arguments")
+	}
+	if len(members) == 0 {
+		return updated, invalidTokens, nil
+	}
+	set, setArgs, err := memberPatchSQL(patch, 2)
+	if err != nil {
+		return updated, invalidTokens, err
+	}
+	ids := make([]string, len(members))
+	for i, memberID := range members {
+		ids[i] = memberID.String()
+	}
+	var pgIDs pgtype.TextArray
+	if err := pgIDs.Set(ids); err != nil {
+		return updated, invalidTokens, fmt.Errorf("cannot convert member IDs: %w", err)
+	}
+	tx, err := d.beginMemberEvents(types.MemberEventUpdate, types.MemberEventActorEntity, entityID)
+	if err != nil {
+		return updated, invalidTokens, err
+	}
+	defer tx.Rollback()
+	update := `UPDATE members m SET ` + set + `
+				WHERE m.entity_id = $1 AND m.deleted_at IS NULL AND m.id = ANY(CAST($2 AS uuid[]))
+				RETURNING m.id`
+	result, err := tx.Queryx(update, append([]interface{}{entityID, pgIDs}, setArgs...)...)
+	if err != nil {
+		return updated, invalidTokens, fmt.Errorf("error updating members of %x: (%v)", entityID, err)
+	}
+	defer result.Close()
+	var id uuid.UUID
+	invalidTokensMap := make(map[uuid.UUID]bool)
+	for _, token := range members {
+		invalidTokensMap[token] = true
+	}
+	for result.Next() {
+		if err := result.Scan(&id); err != nil {
+			return updated, invalidTokens, fmt.Errorf("error parsing query result: %w", err)
+		}
+		updated++
+
+		delete(invalidTokensMap, id)
+	}
+	if err := result.Err(); err != nil {
+		return updated, invalidTokens, fmt.Errorf("error parsing query result: %w", err)
+	}
+	result.Close()
+	if err := tx.Commit(); err != nil {
+		return updated, invalidTokens, fmt.Errorf("error commiting transactions to the DB: %w", err)
+	}
+	invalidTokens = make([]uuid.UUID, len(invalidTokensMap))
+	i := 0
+	for k := range invalidTokensMap {
+		invalidTokens[i] = k
+		i++
+	}
+	return updated, invalidTokens, nil
+}
+
+// UpdateMembersByFilters applies the patch to every member matching the
+// filters in a single statement and returns the number of updated members
+func (d *Database) UpdateMembersByFilters(entityID []byte, filters *types.TargetFilters, patch *types.MemberPatch) (int, error) {
+	if len(entityID) == 0 {
+		return 0, fmt.Errorf("invalid arguments")
+	}
+	where, args, err := targetFiltersSQL(filters, 1)
+	if err != nil {
+		return 0, fmt.Errorf("cannot compile filters: %w", err)
+	}
+	set, setArgs, err := memberPatchSQL(patch, 1+len(args))
+	if err != nil {
+		return 0, err
+	}
+	tx, err := d.beginMemberEvents(types.MemberEventUpdate, types.MemberEventActorEntity, entityID)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	update := `UPDATE members m SET ` + set + `
+				WHERE m.entity_id = $1 AND m.deleted_at IS NULL AND ` + where
+	args = append(append([]interface{}{entityID}, args...), setArgs...)
+	result, err := tx.Exec(update, args...)
+	if err != nil {
+		return 0, fmt.Errorf("error updating members of %x: (%v)", entityID, err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("cannot get affected rows: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error commiting transactions to the DB: %w", err)
+	}
 	return int(rows), nil
 }
 
@@ -732,9 +1129,17 @@ func (d *Database) ListTags(entityID []byte) ([]types.Tag, error) {
 		log.Debugf("cannot retrieve tags for empty entityID")
 		return nil, fmt.Errorf("invalid entity ID")
 	}
-	selectQuery := `SELECT id, name 
-					FROM tags
-					WHERE entity_id=$1`
+	// the members are counted in a single pass by unnesting their tags
+	selectQuery := `SELECT t.id, t.name, t.description, t.color, COALESCE(c.members_count, 0) AS members_count
+					FROM tags t
+					LEFT JOIN (
+						SELECT unnest(m.tags) AS tag_id, COUNT(*) AS members_count
+						FROM members m
+						WHERE m.entity_id = $1 AND m.deleted_at IS NULL
+						GROUP BY tag_id
+					) c ON c.tag_id = t.id
+					WHERE t.entity_id=$1
+					ORDER BY t.id`
 	var tags []types.Tag
 	if err := d.db.Select(&tags, selectQuery, entityID); err != nil {
 		return nil, err
@@ -742,6 +1147,42 @@ func (d *Database) ListTags(entityID []byte) ([]types.Tag, error) {
 	return tags, nil
 }
 
+// UpdateTag updates the name, description and color of a tag, the empty
+// fields are not updated
+func (d *Database) UpdateTag(entityID []byte, tagID int32, tag *types.Tag) (int, error) {
+	if len(entityID) == 0 || tagID == 0 || tag == nil {
+		return 0, fmt.Errorf("invalid arguments")
+	}
+	update := &types.Tag{
+		ID:          tagID,
+		EntityID:    entityID,
+		Name:        tag.Name,
+		Description: tag.Description,
+		Color:       tag.Color,
+	}
+	updateQuery := `UPDATE tags SET
+					name = COALESCE(NULLIF(:name, ''), name),
+					description = COALESCE(NULLIF(:description, ''), description),
+					color = COALESCE(NULLIF(:color, ''), color),
+					updated_at = now()
+					WHERE id = :id AND entity_id = :entity_id`
+	result, err := d.db.NamedExec(updateQuery, update)
+	if err != nil {
+		var pgError pgx.PgError
+		if errors.As(err, &pgError) && pgError.ConstraintName == "tags_name_entity_id_unique" {
+			return 0, fmt.Errorf("error updating tag: duplicate name")
+		}
+		return 0, fmt.Errorf("error updating tag: %w", err)
+	}
+	var rows int64
+	if rows, err = result.RowsAffected(); err != nil {
+		return 0, fmt.Errorf("cannot get affected rows: %w", err)
+	} else if rows != 1 && rows != 0 { /* Nothing to update? */
+		return int(rows), fmt.Errorf("expected to update 0 or 1 rows, but updated %d rows", rows)
+	}
+	return int(rows), nil
+}
+
 func (d *Database) DeleteTag(entityID []byte, tagID int32) error {
 	if len(entityID) == 0 {
 		log.Debug("tried to delete tag for empty entityID")
@@ -758,7 +1199,7 @@ func (d *Database) DeleteTag(entityID []byte, tagID int32) error {
 	}
 
 	// Delete tag from members
-	tx, err := d.db.Beginx()
+	tx, err := d.beginMemberEvents(types.MemberEventRemoveTag, types.MemberEventActorEntity, entityID)
 	if err != nil {
 		return fmt.Errorf("cannot initialize postgres transaction: %w", err)
 	}
@@ -810,7 +1251,7 @@ func (d *Database) Tag(entityID []byte, tagID int32) (*types.Tag, error) {
 		log.Debugf("Tag: invalid arguments: tag %d for entity %x", tagID, entityID)
 		return nil, fmt.Errorf("invalid arguments")
 	}
-	selectQuery := `SELECT id, name
+	selectQuery := `SELECT id, name, description, color
 					FROM tags
 					WHERE entity_id=$1 AND id=$2`
 	var tag types.Tag
@@ -825,7 +1266,7 @@ func (d *Database) TagByName(entityID []byte, tagName string) (*types.Tag, error
 		log.Debugf("Tag: invalid arguments: tag %s for entity %x", tagName, entityID)
 		return nil, fmt.Errorf("invalid arguments")
 	}
-	selectQuery := `SELECT id, name
+	selectQuery := `SELECT id, name, description, color
 					FROM tags
 					WHERE entity_id=$1 AND name=$2`
 	var tag types.Tag
@@ -874,13 +1315,19 @@ func (d *Database) AddTagToMembers(entityID []byte, members []uuid.UUID, tagID i
 					(:member_id, :tag_id)
 				)
 				AS u(member_id,tag_id)			
-		WHERE m.entity_id = decode('%x','hex') AND m.id = uuid(u.member_id) AND NOT (m.tags && intset(%d)) 
+		WHERE m.entity_id = decode('%x','hex') AND m.id = uuid(u.member_id) AND m.deleted_at IS NULL AND NOT (m.tags && intset(%d)) 
 		RETURNING m.id`, entityID, tagID)
 
-	result, err := d.db.NamedQuery(update, idTagsList)
+	tx, err := d.beginMemberEvents(types.MemberEventAddTag, types.MemberEventActorEntity, entityID)
+	if err != nil {
+		return updated, invalidTokens, err
+	}
+	defer tx.Rollback()
+	result, err := tx.NamedQuery(update, idTagsList)
 	if err != nil {
 		return updated, invalidTokens, fmt.Errorf("error adding  tag %d  to members of %x: (%v)", tagID, entityID, err)
 	}
+	defer result.Close()
 	var id uuid.UUID
 	invalidTokensMap := make(map[uuid.UUID]bool)
 	for _, token := range members {
@@ -894,6 +1341,13 @@ func (d *Database) AddTagToMembers(entityID []byte, members []uuid.UUID, tagID i
 
 		delete(invalidTokensMap, id)
 	}
+	if err := result.Err(); err != nil {
+		return updated, invalidTokens, fmt.Errorf("error parsing query result: %w", err)
+	}
+	result.Close()
+	if err := tx.Commit(); err != nil {
+		return updated, invalidTokens, fmt.Errorf("error commiting transactions to the DB: %w", err)
+	}
 	invalidTokens = make([]uuid.UUID, len(invalidTokensMap))
 	i := 0
 	for k := range invalidTokensMap {
@@ -940,13 +1394,19 @@ func (d *Database) RemoveTagFromMembers(entityID []byte, members []uuid.UUID, ta
 					(:member_id, :tag_id)
 				)
 				AS u(member_id,tag_id)			
-				WHERE m.entity_id = decode('%x','hex') AND m.id = uuid(u.member_id) AND (m.tags && intset(%d)) 
+				WHERE m.entity_id = decode('%x','hex') AND m.id = uuid(u.member_id) AND m.deleted_at IS NULL AND (m.tags && intset(%d)) 
 				RETURNING m.id`, entityID, tag.ID)
 
-	result, err := d.db.NamedQuery(update, idTagsMap)
+	tx, err := d.beginMemberEvents(types.MemberEventRemoveTag, types.MemberEventActorEntity, entityID)
+	if err != nil {
+		return updated, invalidTokens, err
+	}
+	defer tx.Rollback()
+	result, err := tx.NamedQuery(update, idTagsMap)
 	if err != nil {
 		return updated, invalidTokens, fmt.Errorf("error removing  tag %d  to members of %x: (%v)", tagID, entityID, err)
 	}
+	defer result.Close()
 
 	var id uuid.UUID
 	invalidTokensMap := make(map[uuid.UUID]bool)
@@ -961,6 +1421,13 @@ func (d *Database) RemoveTagFromMembers(entityID []byte, members []uuid.UUID, ta
 
 		delete(invalidTokensMap, id)
 	}
+	if err := result.Err(); err != nil {
+		return updated, invalidTokens, fmt.Errorf("error parsing query result: %w", err)
+	}
+	result.Close()
+	if err := tx.Commit(); err != nil {
+		return updated, invalidTokens, fmt.Errorf("error commiting transactions to the DB: %w", err)
+	}
 	invalidTokens = make([]uuid.UUID, len(invalidTokensMap))
 	i := 0
 	for k := range invalidTokensMap {
@@ -970,6 +1437,60 @@ func (d *Database) RemoveTagFromMembers(entityID []byte, members []uuid.UUID, ta
 	return updated, invalidTokens, nil
 }
 
+// AddTagToMembersByFilters adds the tag to all the members matching the
+// filters in a single statement and returns the number of tagged members
+func (d *Database) AddTagToMembersByFilters(entityID []byte, filters *types.TargetFilters, tagID int32) (int, error) {
+	return d.updateTagByFilters(entityID, filters, tagID, true)
+}
+
+// RemoveTagFromMembersByFilters removes the tag from all the members matching
+// the filters in a single statement and returns the number of untagged members
+func (d *Database) RemoveTagFromMembersByFilters(entityID []byte, filters *types.TargetFilters, tagID int32) (int, error) {
+	return d.updateTagByFilters(entityID, filters, tagID, false)
+}
+
+func (d *Database) updateTagByFilters(entityID []byte, filters *types.TargetFilters, tagID int32, add bool) (int, error) {
+	if len(entityID) == 0 || filters == nil {
+		return 0, fmt.Errorf("invalid arguments")
+	}
+	if _, err := d.Tag(entityID, tagID); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("non-existing tag %d for entity %x", tagID, entityID)
+		}
+		return 0, fmt.Errorf("error retrieving tag %d for %x : (%v)", tagID, entityID, err)
+	}
+	where, args, err := targetFiltersSQL(filters, 2)
+	if err != nil {
+		return 0, fmt.Errorf("cannot compile filters: %w", err)
+	}
+	// only the members whose tags change are updated
+	operation := types.MemberEventAddTag
+	update := `UPDATE members m SET tags = array_append(m.tags, $2), updated_at = now()
+				WHERE m.entity_id = $1 AND m.deleted_at IS NULL AND NOT (m.tags && intset($2)) AND ` + where
+	if !add {
+		operation = types.MemberEventRemoveTag
+		update = `UPDATE members m SET tags = array_remove(m.tags, $2), updated_at = now()
+				WHERE m.entity_id = $1 AND m.deleted_at IS NULL AND (m.tags && intset($2)) AND ` + where
+	}
+	tx, err := d.beginMemberEvents(operation, types.MemberEventActorEntity, entityID)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	result, err := tx.Exec(update, append([]interface{}{entityID, tagID}, args...)...)
+	if err != nil {
+		return 0, fmt.Errorf("error updating tag %d of members of %x: (%v)", tagID, entityID, err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("cannot get affected rows: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error commiting transactions to the DB: %w", err)
+	}
+	return int(rows), nil
+}
+
 // Register member to existing ID and generates corresponding user
 func (d *Database) RegisterMember(entityID, pubKey []byte, token *uuid.UUID) error {
 	if token == nil {
@@ -978,7 +1499,7 @@ func (d *Database) RegisterMember(entityID, pubKey []byte, token *uuid.UUID) err
 	var tx *sqlx.Tx
 	var err error
 	member := &types.Member{ID: *token, EntityID: entityID, PubKey: pubKey}
-	tx, err = d.db.Beginx()
+	tx, err = d.beginMemberEvents(types.MemberEventRegister, types.MemberEventActorUser, pubKey)
 	if err != nil {
 		return fmt.Errorf("cannot initialize postgres transaction: %w", err)
 	}
@@ -1021,7 +1542,7 @@ func (d *Database) RegisterMember(entityID, pubKey []byte, token *uuid.UUID) err
 				public_key = :public_key,
 				updated_at = now(),
 				verified = now()
-				WHERE (id = :id AND entity_id = :entity_id)`
+				WHERE (id = :id AND entity_id = :entity_id AND deleted_at IS NULL)`
 	var result sql.Result
 	if result, err = tx.NamedExec(update, pgmember); err != nil {
 		if rollErr := tx.Rollback(); err != nil {
@@ -1056,7 +1577,7 @@ func (d *Database) Member(entityID []byte, memberID *uuid.UUID) (*types.Member,
 	var pgMember PGMember
 	selectQuery := `SELECT
 	 				id, entity_id, public_key, street_address, first_name, last_name, email as "pg_email", phone, date_of_birth, verified, custom_fields as "pg_custom_fields", consented, tags as "pg_tags"
-					FROM members WHERE id = $1 and entity_id =$2`
+					FROM members WHERE id = $1 and entity_id =$2 AND deleted_at IS NULL`
 	row := d.db.QueryRowx(selectQuery, memberID, entityID)
 	if err := row.StructScan(&pgMember); err != nil {
 		return nil, err
@@ -1072,7 +1593,7 @@ func (d *Database) MemberByEmail(entityID []byte, email string) (*types.Member,
 	var pgMembers []PGMember
 	selectQuery := `SELECT
 	 				id, entity_id, public_key, street_address, first_name, last_name, email as "pg_email", phone, date_of_birth, verified, custom_fields as "pg_custom_fields", consented, tags as "pg_tags"
-					FROM members WHERE entity_id =$1 AND email LIKE $2`
+					FROM members WHERE entity_id =$1 AND email LIKE $2 AND deleted_at IS NULL`
 	err := d.db.Select(&pgMembers, selectQuery, entityID, email)
 	if err != nil {
 		log.Warnf("cannot retrieve member by email: (%v)", err)
@@ -1111,7 +1632,7 @@ func (d *Database) Members(entityID []byte, memberIDs []uuid.UUID) ([]types.Memb
 
 	update := `SELECT id, entity_id, public_key, street_address, first_name, last_name, email as "pg_email", phone, date_of_birth, verified, custom_fields as "pg_custom_fields", consented, tags as "pg_tags"
 				FROM members 
-				WHERE id IN (
+				WHERE deleted_at IS NULL AND id IN (
 					SELECT CAST(member_id AS uuid) FROM (VALUES 
 							(:member_id)
 						)
@@ -1165,7 +1686,7 @@ func (d *Database) MembersKeys(entityID []byte, memberKeys [][]byte) ([]types.Me
 	}
 	update := `SELECT id, entity_id, public_key, street_address, first_name, last_name, email as "pg_email", phone, date_of_birth, verified, custom_fields as "pg_custom_fields", consented, tags as "pg_tags"
 				FROM members 
-				WHERE id IN (
+				WHERE deleted_at IS NULL AND id IN (
 					SELECT encode(member_key,'hex') FROM (VALUES 
 							(:member_key)
 						)
@@ -1203,14 +1724,20 @@ func (d *Database) MembersKeys(entityID []byte, memberKeys [][]byte) ([]types.Me
 	return members, invalidTokens, nil
 }
 
+// DeleteMember soft deletes a member, which is hidden until it is purged
+// but kept in the censuses it belongs to
 func (d *Database) DeleteMember(entityID []byte, memberID *uuid.UUID) error {
 	if memberID == nil {
 		return fmt.Errorf("memberID is nil")
 	}
+	tx, err := d.beginMemberEvents(types.MemberEventDelete, types.MemberEventActorEntity, entityID)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 	var result sql.Result
-	var err error
-	deleteQuery := `DELETE FROM members WHERE id = $1 and entity_id =$2`
-	if result, err = d.db.Exec(deleteQuery, *memberID, entityID); err == nil {
+	deleteQuery := `UPDATE members SET deleted_at = now() WHERE id = $1 and entity_id =$2 AND deleted_at IS NULL`
+	if result, err = tx.Exec(deleteQuery, *memberID, entityID); err == nil {
 		var rows int64
 		if rows, err = result.RowsAffected(); rows != 1 {
 			return fmt.Errorf("nothing to delete")
@@ -1219,9 +1746,13 @@ func (d *Database) DeleteMember(entityID []byte, memberID *uuid.UUID) error {
 	if err != nil {
 		return fmt.Errorf("error deleting member: %w", err)
 	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("error commiting member deletion to the DB: %w", err)
+	}
 	return nil
 }
 
+// DeleteMembers soft deletes the members like DeleteMember.
 // len(members) - updated - len(invalidIDs) = duplicates
 func (d *Database) DeleteMembers(entityID []byte, members []uuid.UUID) (int, []uuid.UUID, error) {
 	var invalidTokens []uuid.UUID
@@ -1244,8 +1775,8 @@ func (d *Database) DeleteMembers(entityID []byte, members []uuid.UUID) (int, []u
 		}
 	}
 
-	update := fmt.Sprintf(`DELETE FROM members 
-					WHERE entity_id =  decode('%x','hex') AND id IN (
+	update := fmt.Sprintf(`UPDATE members SET deleted_at = now()
+					WHERE entity_id =  decode('%x','hex') AND deleted_at IS NULL AND id IN (
 						SELECT CAST(member_id AS uuid) FROM (VALUES 
 							(:member_id)
 						)
@@ -1253,10 +1784,16 @@ func (d *Database) DeleteMembers(entityID []byte, members []uuid.UUID) (int, []u
 					)
 					RETURNING id`, entityID)
 
-	result, err := d.db.NamedQuery(update, membersList)
+	tx, err := d.beginMemberEvents(types.MemberEventDelete, types.MemberEventActorEntity, entityID)
+	if err != nil {
+		return updated, invalidTokens, err
+	}
+	defer tx.Rollback()
+	result, err := tx.NamedQuery(update, membersList)
 	if err != nil {
 		return updated, invalidTokens, fmt.Errorf("error removing members of %x: (%v)", entityID, err)
 	}
+	defer result.Close()
 
 	// if err = result.Scan(&invalidTokens); err != nil {
 	// 	log.Errorf("DeleteMembers: cannot parse query result: %w", err)
@@ -1276,6 +1813,13 @@ func (d *Database) DeleteMembers(entityID []byte, members []uuid.UUID) (int, []u
 
 		delete(invalidTokensMap, id)
 	}
+	if err := result.Err(); err != nil {
+		return updated, invalidTokens, fmt.Errorf("error parsing query result: %w", err)
+	}
+	result.Close()
+	if err := tx.Commit(); err != nil {
+		return updated, invalidTokens, fmt.Errorf("error commiting transactions to the DB: %w", err)
+	}
 	invalidTokens = make([]uuid.UUID, len(invalidTokensMap))
 	i := 0
 	for k := range invalidTokensMap {
@@ -1285,6 +1829,7 @@ func (d *Database) DeleteMembers(entityID []byte, members []uuid.UUID) (int, []u
 	return updated, invalidTokens, nil
 }
 
+// DeleteMembersByKeys soft deletes the members like DeleteMember.
 // len(memberKeys) - updated - len(invalidKeys) = duplicates
 func (d *Database) DeleteMembersByKeys(entityID []byte, memberKeys [][]byte) (int, [][]byte, error) {
 	var invalidKeys [][]byte
@@ -1305,8 +1850,8 @@ func (d *Database) DeleteMembersByKeys(entityID []byte, memberKeys [][]byte) (in
 			MemberKey: fmt.Sprintf("%x", memberKey),
 		}
 	}
-	deleteQuery := fmt.Sprintf(`DELETE FROM members
-					WHERE entity_id =  decode('%x','hex') AND public_key IN (
+	deleteQuery := fmt.Sprintf(`UPDATE members SET deleted_at = now()
+					WHERE entity_id =  decode('%x','hex') AND deleted_at IS NULL AND public_key IN (
 						SELECT decode(member_key,'hex') FROM (VALUES
 							(:member_key)
 						)
@@ -1314,10 +1859,16 @@ func (d *Database) DeleteMembersByKeys(entityID []byte, memberKeys [][]byte) (in
 					)
 					RETURNING public_key`, entityID)
 
-	result, err := d.db.NamedQuery(deleteQuery, membersList)
+	tx, err := d.beginMemberEvents(types.MemberEventDelete, types.MemberEventActorEntity, entityID)
+	if err != nil {
+		return updated, invalidKeys, err
+	}
+	defer tx.Rollback()
+	result, err := tx.NamedQuery(deleteQuery, membersList)
 	if err != nil {
 		return updated, invalidKeys, fmt.Errorf("DeleteMembersByKeys: error removing members of %x: %w", entityID, err)
 	}
+	defer result.Close()
 	invalidKeysMap := make(map[string]bool)
 	for _, token := range memberKeys {
 		invalidKeysMap[fmt.Sprintf("%x", token)] = true
@@ -1331,6 +1882,13 @@ func (d *Database) DeleteMembersByKeys(entityID []byte, memberKeys [][]byte) (in
 		delete(invalidKeysMap, temp)
 		updated++
 	}
+	if err := result.Err(); err != nil {
+		return updated, invalidKeys, fmt.Errorf("error parsing query result: %w", err)
+	}
+	result.Close()
+	if err := tx.Commit(); err != nil {
+		return updated, invalidKeys, fmt.Errorf("error commiting transactions to the DB: %w", err)
+	}
 	invalidKeys = make([][]byte, len(invalidKeysMap))
 	i := 0
 	for k := range invalidKeysMap {
@@ -1344,23 +1902,171 @@ func (d *Database) DeleteMembersByKeys(entityID []byte, memberKeys [][]byte) (in
 	return updated, invalidKeys, nil
 }
 
-func (d *Database) MemberPubKey(entityID, pubKey []byte) (*types.Member, error) {
-	var pgMember PGMember
-	selectQuery := `SELECT
-	 				id, entity_id, public_key, street_address, first_name, last_name, email as "pg_email", phone, date_of_birth, verified, custom_fields as "pg_custom_fields"
-					FROM members WHERE public_key =$1 AND entity_id =$2`
-	row := d.db.QueryRowx(selectQuery, pubKey, entityID)
-	if err := row.StructScan(&pgMember); err != nil {
-		return nil, err
-	}
-	member := ToMember(&pgMember)
-	return member, nil
+// RestoreMembers undoes the deletion of members not purged yet. Members whose
+// email or public key has been taken by another member cannot be restored
+// and are returned along with the invalid IDs.
+func (d *Database) RestoreMembers(entityID []byte, members []uuid.UUID) (int, []uuid.UUID, error) {
+	var invalidTokens []uuid.UUID
+	var updated int
+	if len(entityID) == 0 {
+		return updated, invalidTokens, fmt.Errorf("invalid arguments")
+	}
+	if len(members) == 0 {
+		return updated, invalidTokens, nil
+	}
+	type MemberData struct {
+		MemberID string `db:"member_id"`
+	}
+	membersList := make([]*MemberData, len(members))
+	for i, memberID := range members {
+		membersList[i] = &MemberData{
+			MemberID: memberID.String(),
+		}
+	}
+	tx, err := d.beginMemberEvents(types.MemberEventRestore, types.MemberEventActorEntity, entityID)
+	if err != nil {
+		return updated, invalidTokens, err
+	}
+	defer tx.Rollback()
+	update := fmt.Sprintf(`UPDATE members m SET deleted_at = NULL
+					WHERE m.entity_id = decode('%x','hex') AND m.deleted_at IS NOT NULL AND m.erased_at IS NULL AND m.id IN (
+						SELECT CAST(member_id AS uuid) FROM (VALUES
+							(:member_id)
+						)
+						AS u(member_id)
+					)
+					AND NOT EXISTS (
+						SELECT 1 FROM members o
+						WHERE o.entity_id = m.entity_id AND o.deleted_at IS NULL AND
+						(o.email = m.email OR o.public_key = m.public_key)
+					)
+					RETURNING m.id`, entityID)
+	result, err := tx.NamedQuery(update, membersList)
+	if err != nil {
+		return updated, invalidTokens, fmt.Errorf("error restoring members of %x: (%v)", entityID, err)
+	}
+	defer result.Close()
+	var id uuid.UUID
+	invalidTokensMap := make(map[uuid.UUID]bool)
+	for _, token := range members {
+		invalidTokensMap[token] = true
+	}
+	for result.Next() {
+		if err := result.Scan(&id); err != nil {
+			return updated, invalidTokens, fmt.Errorf("error parsing query result: %w", err)
+		}
+		updated++
+
+		delete(invalidTokensMap, id)
+	}
+	if err := result.Err(); err != nil {
+		return updated, invalidTokens, fmt.Errorf("error parsing query result: %w", err)
+	}
+	result.Close()
+	if err := tx.Commit(); err != nil {
+		return updated, invalidTokens, fmt.Errorf("error commiting transactions to the DB: %w", err)
+	}
+	invalidTokens = make([]uuid.UUID, len(invalidTokensMap))
+	i := 0
+	for k := range invalidTokensMap {
+		invalidTokens[i] = k
+		i++
+	}
+	return updated, invalidTokens, nil
+}
+
+// PurgeDeletedMembers permanently deletes the members of all the entities
+// deleted before the given time, together with their census rows.
+// Erased members are never purged.
+func (d *Database) PurgeDeletedMembers(before time.Time) (int, error) {
+	tx, err := d.beginMemberEvents(types.MemberEventPurge, "", nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	result, err := tx.Exec(`DELETE FROM members WHERE deleted_at < $1 AND erased_at IS NULL`, before)
+	if err != nil {
+		return 0, fmt.Errorf("error purging deleted members: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("cannot get affected rows: %w", err)
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error commiting transactions to the DB: %w", err)
+	}
+	return int(rows), nil
+}
+
+// PurgeUnverifiedMembers permanently deletes the members that never registered
+// their public key within the unverified members retention days of their
+// entity. Members included in a census of a process that has not ended yet are
+// kept. It returns the number of purged members by hex encoded entity ID.
+func (d *Database) PurgeUnverifiedMembers() (map[string]int, error) {
+	tx, err := d.beginMemberEvents(types.MemberEventPurge, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	var entityIDs [][]byte
+	if err := tx.Select(&entityIDs, `DELETE FROM members m USING entities e
+			WHERE m.entity_id = e.id AND m.public_key IS NULL AND m.erased_at IS NULL
+			AND COALESCE((e.retention->>'unverifiedMembersDays')::int, 0) > 0
+			AND m.created_at < now() - make_interval(days => (e.retention->>'unverifiedMembersDays')::int)
+			AND NOT EXISTS (SELECT 1 FROM census_members cm INNER JOIN censuses c ON c.id = cm.census_id
+				WHERE cm.member_id = m.id AND (c.process_end_date IS NULL OR c.process_end_date > now()))
+			RETURNING m.entity_id`); err != nil {
+		return nil, fmt.Errorf("error purging unverified members: %w", err)
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error commiting transactions to the DB: %w", err)
+	}
+	return countByEntity(entityIDs), nil
+}
+
+// DropEphemeralKeys removes the ephemeral private keys of the census members
+// once the ephemeral keys retention days of their entity have passed since the
+// census process ended. It returns the number of dropped keys by hex encoded
+// entity ID.
+func (d *Database) DropEphemeralKeys() (map[string]int, error) {
+	var entityIDs [][]byte
+	if err := d.db.Select(&entityIDs, `UPDATE census_members cm SET private_key = NULL
+			FROM censuses c INNER JOIN entities e ON e.id = c.entity_id
+			WHERE cm.census_id = c.id AND cm.ephemeral AND cm.private_key IS NOT NULL
+			AND COALESCE((e.retention->>'ephemeralKeysDays')::int, 0) > 0
+			AND c.process_end_date < now() - make_interval(days => (e.retention->>'ephemeralKeysDays')::int)
+			RETURNING c.entity_id`); err != nil {
+		return nil, fmt.Errorf("error dropping ephemeral keys: %w", err)
+	}
+	return countByEntity(entityIDs), nil
+}
+
+// countByEntity counts the occurrences of each entity ID, keyed by its hex encoding
+func countByEntity(entityIDs [][]byte) map[string]int {
+	counts := make(map[string]int)
+	for _, entityID := range entityIDs {
+		counts[hex.EncodeToString(entityID)]++
+	}
+	return counts
+}
+
+func (d *Database) MemberPubKey(entityID, pubKey []byte) (*types.Member, error) {
+	var pgMember PGMember
+	selectQuery := `SELECT
+	 				id, entity_id, public_key, street_address, first_name, last_name, email as "pg_email", phone, date_of_birth, verified, custom_fields as "pg_custom_fields"
+					FROM members WHERE public_key =$1 AND entity_id =$2 AND deleted_at IS NULL`
+	row := d.db.QueryRowx(selectQuery, pubKey, entityID)
+	if err := row.StructScan(&pgMember); err != nil {
+		return nil, err
+	}
+	member := ToMember(&pgMember)
+	return member, nil
 }
 
 func (d *Database) MembersTokensEmails(entityID []byte) ([]types.Member, error) {
 	selectQuery := `SELECT
 	 				id, email as "pg_email"
-					FROM members WHERE entity_id = $1 AND public_key is null`
+					FROM members WHERE entity_id = $1 AND public_key is null AND deleted_at IS NULL`
 
 	var pgMembers []PGMember
 	if err := d.db.Select(&pgMembers, selectQuery, entityID); err != nil {
@@ -1373,86 +2079,84 @@ func (d *Database) MembersTokensEmails(entityID []byte) ([]types.Member, error)
 	return members, nil
 }
 
-func (d *Database) CountMembers(entityID []byte) (int, error) {
+// CountMembers counts the members of the entity that match the search,
+// or all of them if the search is empty
+func (d *Database) CountMembers(entityID []byte, search string) (int, error) {
 	if len(entityID) == 0 {
 		return 0, fmt.Errorf("invalid entity id")
 	}
-	selectQuery := `SELECT COUNT(*) FROM members WHERE entity_id=$1`
+	searchWhere, searchArgs := memberSearchSQL(search, 1)
+	selectQuery := `SELECT COUNT(*) FROM members m WHERE m.entity_id=$1 AND m.deleted_at IS NULL AND ` + searchWhere
 	var membersCount int
-	if err := d.db.Get(&membersCount, selectQuery, entityID); err != nil {
+	if err := d.db.Get(&membersCount, selectQuery, append([]interface{}{entityID}, searchArgs...)...); err != nil {
 		return 0, err
 	}
 	return membersCount, nil
 }
 
-func (d *Database) ListMembers(entityID []byte, filter *types.ListOptions) ([]types.Member, error) {
-	// TODO: Replace limit offset with better strategy, can slow down DB
-	// would nee to now last value from previous query
-	selectQuery := `SELECT
-	 				id, entity_id, public_key, street_address, first_name, last_name, email as "pg_email", phone, date_of_birth, verified, custom_fields as "pg_custom_fields", tags as "pg_tags"
-					FROM members WHERE entity_id =$1
-					ORDER BY %s %s LIMIT $2 OFFSET $3`
-	// Define default values for arguments
-	t := reflect.TypeOf(types.MemberInfo{})
-	field, found := t.FieldByName(strings.Title("lastName"))
-	if !found {
-		return nil, fmt.Errorf("lastName field not found in DB. Something is very wrong")
-	}
-	orderField := field.Tag.Get("db")
-	order := "ASC"
-	var limit, offset sql.NullInt32
-	// default limit should be nil (Postgres BIGINT NULL)
-	err := limit.Scan(nil)
-	if err != nil {
-		return nil, err
-	}
-	err = offset.Scan(0)
+// ListMembers returns the members of the entity sorted and paginated according
+// to the list options. If there are more members after the page, a cursor
+// pointing to the last returned member is also returned.
+func (d *Database) ListMembers(entityID []byte, filter *types.ListOptions) ([]types.Member, string, error) {
+	return d.listMembers(entityID, filter, false)
+}
+
+// ListDeletedMembers lists the deleted members not purged yet like ListMembers
+func (d *Database) ListDeletedMembers(entityID []byte, filter *types.ListOptions) ([]types.Member, string, error) {
+	return d.listMembers(entityID, filter, true)
+}
+
+func (d *Database) listMembers(entityID []byte, filter *types.ListOptions, deleted bool) ([]types.Member, string, error) {
+	page, err := newListPage(filter, reflect.TypeOf(types.MemberInfo{}), "lastName", "id", "uuid")
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	// offset := 0
+	var search string
 	if filter != nil {
-		if len(filter.SortBy) > 0 {
-			field, found := t.FieldByName(strings.Title(filter.SortBy))
-			if found {
-				if filter.Order == "descend" {
-					order = "DESC"
-				}
-				orderField = field.Tag.Get("db")
-			}
-		}
-		if filter.Skip > 0 {
-			err = offset.Scan(filter.Skip)
-			if err != nil {
-				return nil, err
-			}
-		}
-		if filter.Count > 0 {
-			err = limit.Scan(filter.Count)
-			if err != nil {
-				return nil, err
-			}
+		search = filter.Search
+	}
+	searchWhere, searchArgs := memberSearchSQL(search, 1)
+	cursorWhere, cursorArgs := page.where(1 + len(searchArgs))
+	orderLimit, limitArgs := page.orderLimit(1 + len(searchArgs) + len(cursorArgs))
+	deletedWhere := "deleted_at IS NULL"
+	if deleted {
+		deletedWhere = "deleted_at IS NOT NULL AND erased_at IS NULL"
+	}
+	query := `SELECT
+	 				id, entity_id, public_key, street_address, first_name, last_name, email as "pg_email", phone, date_of_birth, verified, custom_fields as "pg_custom_fields", tags as "pg_tags", deleted_at,
+					` + page.cursorColumns() + `
+					FROM members m WHERE entity_id =$1 AND ` + deletedWhere + ` AND ` + searchWhere + ` AND ` + cursorWhere + `
+					` + orderLimit
+	args := append([]interface{}{entityID}, searchArgs...)
+	args = append(append(args, cursorArgs...), limitArgs...)
+	var pgMembers []struct {
+		PGMember
+		CursorValue string `db:"cursor_value"`
+		CursorID    string `db:"cursor_id"`
+	}
+	if err = d.db.Select(&pgMembers, query, args...); err != nil {
+		return nil, "", err
+	}
+	var next string
+	if page.hasNext(len(pgMembers)) {
+		pgMembers = pgMembers[:page.limit]
+		last := pgMembers[len(pgMembers)-1]
+		if next, err = page.nextCursor(last.CursorValue, last.CursorID); err != nil {
+			return nil, "", err
 		}
 	}
-
-	query := fmt.Sprintf(selectQuery, orderField, order)
-	var pgMembers []PGMember
-	err = d.db.Select(&pgMembers, query, entityID, limit, offset)
-	if err != nil {
-		return nil, err
-	}
 	members := make([]types.Member, len(pgMembers))
 	for i, member := range pgMembers {
-		members[i] = *ToMember(&member)
+		members[i] = *ToMember(&member.PGMember)
 	}
-	return members, nil
+	return members, next, nil
 }
 
 func (d *Database) DumpClaims(entityID []byte) ([][]byte, error) {
 	var claims [][]byte
 	query := `SELECT u.digested_public_key FROM users u 
 			INNER JOIN members m ON m.public_key = u.public_key 
-			WHERE m.entity_id = $1`
+			WHERE m.entity_id = $1 AND m.deleted_at IS NULL`
 	if err := d.db.Select(&claims, query, entityID); err != nil {
 		return nil, err
 	}
@@ -1475,6 +2179,32 @@ func (d *Database) DumpCensusClaims(entityID []byte, censusID []byte) ([][]byte,
 	return claims, nil
 }
 
+// DumpCensusWeightedClaims returns the digested public keys of the census
+// members along with their weights
+func (d *Database) DumpCensusWeightedClaims(entityID, censusID []byte) ([][]byte, []uint64, error) {
+	// Verify that census belongs to this entity
+	if _, err := d.Census(entityID, censusID); err != nil {
+		log.Warnf("dumpCensusWeightedClaims: cound not retrieve census: (%v)", err)
+		return nil, nil, fmt.Errorf("could not retrieve census")
+	}
+	var rows []struct {
+		DigestedPubKey []byte `db:"digested_public_key"`
+		Weight         uint64 `db:"weight"`
+	}
+	query := `SELECT digested_public_key, weight FROM census_members
+			WHERE census_id = $1`
+	if err := d.db.Select(&rows, query, censusID); err != nil {
+		return nil, nil, err
+	}
+	claims := make([][]byte, len(rows))
+	weights := make([]uint64, len(rows))
+	for i, row := range rows {
+		claims[i] = row.DigestedPubKey
+		weights[i] = row.Weight
+	}
+	return claims, weights, nil
+}
+
 func (d *Database) ExpandCensusMembers(entityID, censusID []byte) ([]types.CensusMember, error) {
 	// Get target Members with pks
 	census, err := d.Census(entityID, censusID)
@@ -1482,6 +2212,9 @@ func (d *Database) ExpandCensusMembers(entityID, censusID []byte) ([]types.Censu
 		log.Warnf("expandCensusClaims: cound not retrieve census: (%v)", err)
 		return nil, fmt.Errorf("could not retrieve census")
 	}
+	if census.State != types.CensusDraft {
+		return nil, fmt.Errorf("census is %s and cannot be expanded", census.State)
+	}
 	members, err := d.TargetMembers(entityID, &census.TargetID)
 	if err != nil {
 		log.Warnf("expandCensusClaims: cound not retrieve target members: (%v)", err)
@@ -1492,6 +2225,14 @@ func (d *Database) ExpandCensusMembers(entityID, censusID []byte) ([]types.Censu
 	var censusMembers []types.CensusMember
 	signKeys := ethereum.NewSignKeys()
 	for _, member := range members {
+		weight, err := census.Weight.MemberWeight(&member)
+		if err != nil {
+			return nil, fmt.Errorf("invalid weight of member %s: %w", member.ID, err)
+		}
+		if weight == 0 {
+			// members without weight cannot vote
+			continue
+		}
 		if util.ValidPubKey(member.PubKey) {
 			// if the member has a public key registered add directly
 			// to the census
@@ -1500,6 +2241,7 @@ func (d *Database) ExpandCensusMembers(entityID, censusID []byte) ([]types.Censu
 				MemberID:       member.ID,
 				Ephemeral:      false,
 				DigestedPubKey: member.PubKey,
+				Weight:         weight,
 			}
 			censusMembers = append(censusMembers, censusMember)
 
@@ -1525,6 +2267,7 @@ func (d *Database) ExpandCensusMembers(entityID, censusID []byte) ([]types.Censu
 				PubKey:         pubKeyBytes,
 				PrivKey:        privKeyBytes,
 				DigestedPubKey: pubKeyBytes,
+				Weight:         weight,
 			})
 
 		}
@@ -1533,15 +2276,17 @@ func (d *Database) ExpandCensusMembers(entityID, censusID []byte) ([]types.Censu
 	if err != nil {
 		return nil, fmt.Errorf("could not initialize postgres transaction: %w", err)
 	}
+	defer tx.Rollback()
 
 	// update census members
-	insertMembers := `INSERT INTO census_members (census_id, member_id, ephemeral, public_key, digested_public_key, private_key)
-				  VALUES (:census_id, :member_id, :ephemeral, :public_key, :digested_public_key, :private_key)`
-	if err := bulkInsert(tx, insertMembers, censusMembers, 6); err != nil {
+	insertMembers := `INSERT INTO census_members (census_id, member_id, ephemeral, public_key, digested_public_key, private_key, weight)
+				  VALUES (:census_id, :member_id, :ephemeral, :public_key, :digested_public_key, :private_key, :weight)`
+	if err := bulkInsert(tx, insertMembers, censusMembers, 7); err != nil {
 		return nil, fmt.Errorf("error during bulk insert: %w", err)
 	}
-	// update census size if everythin went fine
-	result, err := tx.Exec(`UPDATE censuses SET size = $1  WHERE id = $2 AND entity_id = $3`,
+	// update census size and state if everythin went fine, unless the census
+	// was expanded meanwhile
+	result, err := tx.Exec(`UPDATE censuses SET size = $1, state = 'expanded'  WHERE id = $2 AND entity_id = $3 AND state = 'draft'`,
 		len(censusMembers),
 		censusID,
 		entityID,
@@ -1558,9 +2303,6 @@ func (d *Database) ExpandCensusMembers(entityID, censusID []byte) ([]types.Censu
 	}
 
 	if err = tx.Commit(); err != nil {
-		if rollErr := tx.Rollback(); err != nil {
-			return nil, fmt.Errorf("something is very wrong: error rolling back: %v after final commit to DB: %w", rollErr, err)
-		}
 		return nil, fmt.Errorf("error commiting transactions to the DB: %w", err)
 	}
 	return censusMembers, nil
@@ -1577,7 +2319,7 @@ func (d *Database) ListEphemeralMemberInfo(entityID, censusID []byte) ([]types.E
 	selectQuery := `SELECT id, first_name, last_name, email as "pg_email", private_key, c.digested_public_key as "digested_public_key"
 					FROM  census_members c
 					INNER JOIN members m  ON m.id = c.member_id
-					WHERE c.census_id = $1 AND c.ephemeral = true`
+					WHERE c.census_id = $1 AND c.ephemeral = true AND m.deleted_at IS NULL`
 	var pgInfo []PGEphemeralMemberInfo
 	if err := d.db.Select(&pgInfo, selectQuery, census.ID); err != nil {
 		return nil, fmt.Errorf("could not retrieve census members info: (%v)", err)
@@ -1618,6 +2360,77 @@ func (d *Database) EphemeralMemberInfoByEmail(entityID, censusID []byte, email s
 	return &info, nil
 }
 
+// FlagInvalidClaims flags the census members with the given claims as invalid
+// with the reason of each claim, unless the census is no longer mutable. It
+// returns the flagged members, along with their contact details.
+func (d *Database) FlagInvalidClaims(entityID, censusID []byte, claims [][]byte, reasons []string) ([]types.InvalidClaim, error) {
+	if len(entityID) == 0 || len(censusID) == 0 || len(claims) != len(reasons) {
+		return nil, fmt.Errorf("invalid arguments")
+	}
+	var pgClaims pgtype.ByteaArray
+	if err := pgClaims.Set(claims); err != nil {
+		return nil, fmt.Errorf("cannot convert claims: %w", err)
+	}
+	var pgReasons pgtype.TextArray
+	if err := pgReasons.Set(reasons); err != nil {
+		return nil, fmt.Errorf("cannot convert reasons: %w", err)
+	}
+	update := `WITH flagged AS (
+					UPDATE census_members cm SET invalid_reason = r.reason
+					FROM censuses c, unnest(CAST($3 AS bytea[]), CAST($4 AS text[])) AS r(claim, reason)
+					WHERE c.id = cm.census_id AND c.entity_id = $1 AND cm.census_id = $2
+					AND c.state IN ('draft', 'expanded') AND cm.digested_public_key = r.claim
+					RETURNING cm.member_id, cm.digested_public_key, cm.invalid_reason
+				)
+				SELECT f.member_id, f.digested_public_key, f.invalid_reason,
+					m.first_name, m.last_name, COALESCE(m.email, '') AS email
+				FROM flagged f INNER JOIN members m ON m.id = f.member_id
+				ORDER BY m.last_name ASC`
+	var invalidClaims []types.InvalidClaim
+	if err := d.db.Select(&invalidClaims, update, entityID, censusID, pgClaims, pgReasons); err != nil {
+		return nil, fmt.Errorf("error flagging invalid claims: %w", err)
+	}
+	return invalidClaims, nil
+}
+
+// CensusInvalidClaims returns the census members flagged with an invalid
+// claim, along with their contact details
+func (d *Database) CensusInvalidClaims(entityID, censusID []byte) ([]types.InvalidClaim, error) {
+	if len(entityID) == 0 || len(censusID) == 0 {
+		return nil, fmt.Errorf("invalid arguments")
+	}
+	selectQuery := `SELECT cm.member_id, cm.digested_public_key, cm.invalid_reason,
+					m.first_name, m.last_name, COALESCE(m.email, '') AS email
+					FROM census_members cm
+					INNER JOIN censuses c ON c.id = cm.census_id
+					INNER JOIN members m ON m.id = cm.member_id
+					WHERE c.entity_id = $1 AND cm.census_id = $2 AND cm.invalid_reason <> ''
+					ORDER BY m.last_name ASC`
+	var invalidClaims []types.InvalidClaim
+	if err := d.db.Select(&invalidClaims, selectQuery, entityID, censusID); err != nil {
+		return nil, err
+	}
+	return invalidClaims, nil
+}
+
+// CensusMember returns the row of a member in a census of the entity, without
+// its private key
+func (d *Database) CensusMember(entityID, censusID []byte, memberID *uuid.UUID) (*types.CensusMember, error) {
+	if len(entityID) == 0 || len(censusID) == 0 || memberID == nil {
+		return nil, fmt.Errorf("invalid arguments")
+	}
+	selectQuery := `SELECT cm.census_id, cm.member_id, cm.ephemeral, cm.public_key, cm.digested_public_key, cm.weight,
+					cm.invalid_reason
+					FROM census_members cm
+					INNER JOIN censuses c ON c.id = cm.census_id
+					WHERE c.entity_id = $1 AND cm.census_id = $2 AND cm.member_id = $3`
+	var censusMember types.CensusMember
+	if err := d.db.Get(&censusMember, selectQuery, entityID, censusID, memberID); err != nil {
+		return nil, err
+	}
+	return &censusMember, nil
+}
+
 func (d *Database) AddTarget(entityID []byte, target *types.Target) (uuid.UUID, error) {
 	var err error
 	if len(entityID) == 0 {
@@ -1672,6 +2485,67 @@ func (d *Database) Target(entityID []byte, targetID *uuid.UUID) (*types.Target,
 	return &target, nil
 }
 
+// UpdateTarget updates the name and/or the filters of a target.
+// Empty name or filters are not updated.
+func (d *Database) UpdateTarget(entityID []byte, targetID *uuid.UUID, target *types.Target) (int, error) {
+	if len(entityID) == 0 || targetID == nil || *targetID == uuid.Nil || target == nil {
+		return 0, fmt.Errorf("invalid arguments")
+	}
+	update := &types.Target{
+		ID:       *targetID,
+		EntityID: entityID,
+		Name:     target.Name,
+		Filters:  target.Filters,
+	}
+	if len(update.Filters) == 0 {
+		update.Filters = nil
+	}
+	updateQuery := `UPDATE targets SET
+					name = COALESCE(NULLIF(:name, ''), name),
+					filters = COALESCE(:filters, filters),
+					updated_at = now()
+					WHERE id = :id AND entity_id = :entity_id`
+	result, err := d.db.NamedExec(updateQuery, update)
+	if err != nil {
+		var pgError pgx.PgError
+		if errors.As(err, &pgError) && pgError.ConstraintName == "targets_entity_id_name_unique" {
+			return 0, fmt.Errorf("error updating target: duplicate name")
+		}
+		return 0, fmt.Errorf("error updating target: %w", err)
+	}
+	var rows int64
+	if rows, err = result.RowsAffected(); err != nil {
+		return 0, fmt.Errorf("cannot get affected rows: %w", err)
+	} else if rows != 1 && rows != 0 { /* Nothing to update? */
+		return int(rows), fmt.Errorf("expected to update 0 or 1 rows, but updated %d rows", rows)
+	}
+	return int(rows), nil
+}
+
+// DeleteTarget deletes a target. Targets referenced by a census cannot be deleted.
+func (d *Database) DeleteTarget(entityID []byte, targetID *uuid.UUID) error {
+	if len(entityID) == 0 || targetID == nil || *targetID == uuid.Nil {
+		return fmt.Errorf("invalid arguments")
+	}
+	deleteQuery := `DELETE FROM targets WHERE id = $1 AND entity_id = $2`
+	result, err := d.db.Exec(deleteQuery, targetID, entityID)
+	if err != nil {
+		var pgError pgx.PgError
+		if errors.As(err, &pgError) && pgError.ConstraintName == "censuses_target_id_fkey" {
+			return fmt.Errorf("target is referenced by existing censuses")
+		}
+		return fmt.Errorf("error deleting target: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error verifying deleted target: %w", err)
+	}
+	if rows != 1 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
 func (d *Database) CountTargets(entityID []byte) (int, error) {
 	if len(entityID) == 0 {
 		return 0, fmt.Errorf("invalid entity id")
@@ -1698,27 +2572,124 @@ func (d *Database) ListTargets(entityID []byte) ([]types.Target, error) {
 	return targets, nil
 }
 
-func (d *Database) TargetMembers(entityID []byte, targetID *uuid.UUID) ([]types.Member, error) {
-	// TODO: Implement filters
+// targetCondition returns the SQL condition, and its arguments, that selects the
+// members of the target. The entity ID is always the first argument ($1).
+func (d *Database) targetCondition(entityID []byte, targetID *uuid.UUID) (string, []interface{}, error) {
 	if targetID == nil {
-		return nil, fmt.Errorf("targetID is nil")
+		return "", nil, fmt.Errorf("targetID is nil")
 	}
-	return d.ListMembers(entityID, &types.ListOptions{})
+	target, err := d.Target(entityID, targetID)
+	if err != nil {
+		return "", nil, fmt.Errorf("cannot retrieve target: %w", err)
+	}
+	filters, err := types.ParseTargetFilters(target.Filters)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid target filters: %w", err)
+	}
+	where, args, err := targetFiltersSQL(filters, 1)
+	if err != nil {
+		return "", nil, fmt.Errorf("cannot compile target filters: %w", err)
+	}
+	return "m.entity_id = $1 AND m.deleted_at IS NULL AND " + where, append([]interface{}{entityID}, args...), nil
+}
+
+// TargetMembers returns the members of the entity that fulfill the target filters
+func (d *Database) TargetMembers(entityID []byte, targetID *uuid.UUID) ([]types.Member, error) {
+	where, args, err := d.targetCondition(entityID, targetID)
+	if err != nil {
+		return nil, err
+	}
+	selectQuery := `SELECT
+	 				m.id, m.entity_id, m.public_key, m.street_address, m.first_name, m.last_name, m.email as "pg_email", m.phone, m.date_of_birth, m.verified, m.custom_fields as "pg_custom_fields", m.tags as "pg_tags"
+					FROM members m WHERE ` + where + `
+					ORDER BY m.last_name ASC`
+	var pgMembers []PGMember
+	if err := d.db.Select(&pgMembers, selectQuery, args...); err != nil {
+		return nil, err
+	}
+	members := make([]types.Member, len(pgMembers))
+	for i, member := range pgMembers {
+		members[i] = *ToMember(&member)
+	}
+	return members, nil
+}
+
+// DumpTargetClaims returns the digested public keys of the registered members
+// that match the target filters, together with the number of matching members
+// that were skipped because they have not registered a key yet.
+func (d *Database) DumpTargetClaims(entityID []byte, targetID *uuid.UUID) ([][]byte, int, error) {
+	where, args, err := d.targetCondition(entityID, targetID)
+	if err != nil {
+		return nil, 0, err
+	}
+	query := `SELECT u.digested_public_key FROM members m
+			LEFT JOIN users u ON u.public_key = m.public_key
+			WHERE ` + where
+	var keys [][]byte
+	if err := d.db.Select(&keys, query, args...); err != nil {
+		return nil, 0, err
+	}
+	claims := make([][]byte, 0, len(keys))
+	for _, key := range keys {
+		if len(key) > 0 {
+			claims = append(claims, key)
+		}
+	}
+	return claims, len(keys) - len(claims), nil
+}
+
+// StreamMembers calls fn for every member of the entity matching the filters,
+// sorted by last name, without loading all the members in memory.
+// The iteration stops at the first error returned by fn.
+func (d *Database) StreamMembers(entityID []byte, filters *types.TargetFilters, fn func(member *types.Member) error) error {
+	if len(entityID) == 0 {
+		return fmt.Errorf("invalid entity id")
+	}
+	where, args, err := targetFiltersSQL(filters, 1)
+	if err != nil {
+		return fmt.Errorf("cannot compile filters: %w", err)
+	}
+	selectQuery := `SELECT
+					m.id, m.entity_id, m.public_key, m.street_address, m.first_name, m.last_name, m.email as "pg_email", m.phone, m.date_of_birth, m.verified,
+					m.custom_fields as "pg_custom_fields", m.tags as "pg_tags", m.consented, m.origin as "pg_origin", m.created_at, m.updated_at
+					FROM members m WHERE m.entity_id = $1 AND m.deleted_at IS NULL AND ` + where + `
+					ORDER BY m.last_name ASC, m.id ASC`
+	rows, err := d.db.Queryx(selectQuery, append([]interface{}{entityID}, args...)...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var pgMember struct {
+			PGMember
+			Origin string `db:"pg_origin"`
+		}
+		if err := rows.StructScan(&pgMember); err != nil {
+			return fmt.Errorf("error parsing query result: %w", err)
+		}
+		member := ToMember(&pgMember.PGMember)
+		member.Origin = types.ToOrigin(pgMember.Origin)
+		if err := fn(member); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
 }
 
 func (d *Database) Census(entityID, censusID []byte) (*types.Census, error) {
 	if len(entityID) == 0 || len(censusID) < 1 {
 		return nil, fmt.Errorf("error retrieving target")
 	}
-	var census types.Census
-	selectQuery := `SELECT id, entity_id, target_id, name, size, merkle_root, merkle_tree_uri, ephemeral, created_at, updated_at
+	var pgCensus PGCensus
+	selectQuery := `SELECT id, entity_id, target_id, name, size, merkle_root, merkle_tree_uri, ephemeral,
+					process_id, process_end_date, weight as "pg_weight", state, created_at, updated_at
 					FROM censuses
 					WHERE entity_id = $1 AND id = $2`
 	row := d.db.QueryRowx(selectQuery, entityID, censusID)
-	if err := row.StructScan(&census); err != nil {
+	if err := row.StructScan(&pgCensus); err != nil {
 		return nil, err
 	}
-	return &census, nil
+	return ToCensus(&pgCensus)
 }
 
 func (d *Database) AddCensus(entityID, censusID []byte, targetID *uuid.UUID, info *types.CensusInfo) error {
@@ -1739,19 +2710,22 @@ func (d *Database) AddCensus(entityID, censusID []byte, targetID *uuid.UUID, inf
 	// TODO check valid target selecting
 	info.CreatedAt = time.Now()
 	info.UpdatedAt = time.Now()
-	census := types.Census{
+	census, err := ToPGCensus(&types.Census{
 		ID:         censusID,
 		EntityID:   entityID,
 		TargetID:   *targetID,
 		CensusInfo: *info,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot convert census to postgres types: %w", err)
 	}
 	// insert := `INSERT INTO censuses
 	//  			(id, entity_id, target_id, name, size, merkle_root, merkle_tree_uri, ephemeral, created_at, updated_at)
 	// 			VALUES (:id, :entity_id, :target_id, :name, :size, :merkle_root, :merkle_tree_uri, :ephemeral, :created_at, :updated_at)`
 	var result sql.Result
 	if result, err = d.db.NamedExec(`INSERT INTO censuses
-		(id, entity_id, target_id, name, size, merkle_root, merkle_tree_uri, ephemeral, created_at, updated_at)
-   		VALUES (:id, :entity_id, :target_id, :name, :size, :merkle_root, :merkle_tree_uri, :ephemeral, :created_at, :updated_at)`,
+		(id, entity_id, target_id, name, size, merkle_root, merkle_tree_uri, ephemeral, weight, created_at, updated_at)
+   		VALUES (:id, :entity_id, :target_id, :name, :size, :merkle_root, :merkle_tree_uri, :ephemeral, :pg_weight, :created_at, :updated_at)`,
 		census,
 	); err == nil {
 		if rows, err = result.RowsAffected(); err == nil && rows != 1 {
@@ -1769,38 +2743,59 @@ func (d *Database) AddCensusWithMembers(entityID, censusID []byte, targetID *uui
 	if len(entityID) == 0 || len(censusID) == 0 || targetID == nil || *targetID == uuid.Nil {
 		return 0, fmt.Errorf("invalid arguments")
 	}
-	// TODO check valid target selecting
-
-	// TODO Enable upon implementing targets (also enalbe manager_test targets)
-	// members, err := d.TargetMembers(entityID, targetID)
-	// if err != nil {
-	// 	return 0, fmt.Errorf("failed to recover target members: %w", err)
-	// }
-	// if len(members) == 0 {
-	// 	return 0, fmt.Errorf("target contains 0 members")
-	// }
-	// TODO Disable upon implementing targets
-	var members []types.Member
-	query := `SELECT m.id FROM members m
-			INNER JOIN users u ON m.public_key = u.public_key 
-			WHERE m.entity_id = $1`
-	if err := d.db.Select(&members, query, entityID); err != nil {
-		return 0, err
+	if info == nil {
+		info = &types.CensusInfo{}
 	}
-	if len(members) == 0 {
+	if info.MerkleRoot == nil {
+		info.MerkleRoot = []byte{}
+	}
+	// only the target members that registered a public key and have some
+	// weight are in the census
+	targetMembers, err := d.TargetMembers(entityID, targetID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to recover target members: %w", err)
+	}
+	var censusMembers []types.CensusMember
+	for _, member := range targetMembers {
+		if len(member.PubKey) == 0 {
+			continue
+		}
+		weight, err := info.Weight.MemberWeight(&member)
+		if err != nil {
+			return 0, fmt.Errorf("invalid weight of member %s: %w", member.ID, err)
+		}
+		if weight == 0 {
+			continue
+		}
+		censusMembers = append(censusMembers, types.CensusMember{
+			CensusID:       censusID,
+			MemberID:       member.ID,
+			DigestedPubKey: member.PubKey,
+			Weight:         weight,
+		})
+	}
+	if len(censusMembers) == 0 {
 		return 0, fmt.Errorf("target contains 0 members")
 	}
 
-	census := types.Census{ID: censusID, EntityID: entityID, TargetID: *targetID, CensusInfo: *info}
-	census.CreatedAt = time.Now()
-	census.UpdatedAt = time.Now()
+	// the census is created already expanded
+	info.Size = len(censusMembers)
+	info.State = types.CensusExpanded
+	info.CreatedAt = time.Now()
+	info.UpdatedAt = time.Now()
+	census, err := ToPGCensus(&types.Census{ID: censusID, EntityID: entityID, TargetID: *targetID, CensusInfo: *info})
+	if err != nil {
+		return 0, fmt.Errorf("cannot convert census to postgres types: %w", err)
+	}
 	tx, err := d.db.Beginx()
 	if err != nil {
 		return 0, fmt.Errorf("cannot initialize postgres transaction: %w", err)
 	}
+	defer tx.Rollback()
+
 	insertCensus := `INSERT  INTO censuses
-					(id, entity_id, target_id, name, size, merkle_root, merkle_tree_uri, created_at, updated_at)
-					VALUES (:id, :entity_id, :target_id, :name, :size, :merkle_root, :merkle_tree_uri, :created_at, :updated_at)`
+					(id, entity_id, target_id, name, size, merkle_root, merkle_tree_uri, ephemeral, weight, state, created_at, updated_at)
+					VALUES (:id, :entity_id, :target_id, :name, :size, :merkle_root, :merkle_tree_uri, :ephemeral, :pg_weight, :state, :created_at, :updated_at)`
 	result, err := tx.NamedExec(insertCensus, census)
 	if err != nil {
 		return 0, fmt.Errorf("cannot add census: %w", err)
@@ -1809,37 +2804,14 @@ func (d *Database) AddCensusWithMembers(entityID, censusID []byte, targetID *uui
 		return 0, fmt.Errorf("cannot add census: %w", err)
 	}
 
-	censusMembers := make([]types.CensusMember, len(members))
-	for idx, member := range members {
-		censusMembers[idx].CensusID = censusID
-		censusMembers[idx].MemberID = member.ID
-	}
-
-	insertMembers := `INSERT INTO census_members (census_id, member_id)
-				  VALUES (:census_id, :member_id)`
-	if err := bulkInsert(tx, insertMembers, censusMembers, 2); err != nil {
+	insertMembers := `INSERT INTO census_members (census_id, member_id, ephemeral, public_key, digested_public_key, private_key, weight)
+				  VALUES (:census_id, :member_id, :ephemeral, :public_key, :digested_public_key, :private_key, :weight)`
+	if err := bulkInsert(tx, insertMembers, censusMembers, 7); err != nil {
 		return 0, fmt.Errorf("error during bulk insert: %w", err)
 	}
 
-	updateCensus := `UPDATE censuses SET size = $1, updated_at = now() WHERE id = $2`
-	result, err = tx.Exec(updateCensus, len(censusMembers), censusID)
-	if err != nil {
-		if rollbackErr := tx.Rollback(); rollbackErr != nil {
-			return 0, fmt.Errorf("cannot perform db rollback %v after error %w", rollbackErr, err)
-		}
-		return 0, fmt.Errorf("rolled back due to error updating census size: %w", err)
-	}
-	if updated, err := result.RowsAffected(); err != nil || updated != 1 {
-		if rollbackErr := tx.Rollback(); rollbackErr != nil {
-			return 0, fmt.Errorf("cannot perform db rollback %v after error %w", rollbackErr, err)
-		}
-		return 0, fmt.Errorf("rolled back due to error updating census size: %w", err)
-	}
 	if err := tx.Commit(); err != nil {
-		if rollbackErr := tx.Rollback(); rollbackErr != nil {
-			return 0, fmt.Errorf("cannot perform db rollback %v after error %w", rollbackErr, err)
-		}
-		return 0, fmt.Errorf("rolled back because could not commit addCensus and addCensusMembers: %w", err)
+		return 0, fmt.Errorf("could not commit addCensus and addCensusMembers: %w", err)
 	}
 	return int64(len(censusMembers)), nil
 }
@@ -1863,8 +2835,10 @@ func (d *Database) UpdateCensus(entityID, censusID []byte, info *types.CensusInf
 	update := `UPDATE censuses SET
 				merkle_root = COALESCE(NULLIF(:merkle_root, '' ::::bytea ),  merkle_root),
 				merkle_tree_uri = COALESCE(NULLIF(:merkle_tree_uri, ''),  merkle_tree_uri) ,
+				process_id = COALESCE(NULLIF(:process_id, '' ::::bytea ),  process_id),
+				process_end_date = COALESCE(CAST(:process_end_date AS timestamptz),  process_end_date),
 				updated_at = now()
-				WHERE id = :id AND entity_id = :entity_id`
+				WHERE id = :id AND entity_id = :entity_id AND state IN ('draft', 'expanded')`
 	var result sql.Result
 	if result, err = d.db.NamedExec(update, census); err != nil {
 		return 0, fmt.Errorf("error updating census: %w", err)
@@ -1878,6 +2852,41 @@ func (d *Database) UpdateCensus(entityID, censusID []byte, info *types.CensusInf
 	return int(rows), nil
 }
 
+// SetCensusState moves a census from one state to another, failing if it is
+// not in the from state anymore. Closing a census removes the ephemeral private
+// keys of its members.
+func (d *Database) SetCensusState(entityID, censusID []byte, from, to types.CensusState) error {
+	if len(entityID) == 0 || len(censusID) == 0 || !from.CanTransition(to) {
+		return fmt.Errorf("invalid arguments")
+	}
+	tx, err := d.db.Beginx()
+	if err != nil {
+		return fmt.Errorf("cannot initialize postgres transaction: %w", err)
+	}
+	defer tx.Rollback()
+	result, err := tx.Exec(`UPDATE censuses SET state = $4, updated_at = now()
+			WHERE entity_id = $1 AND id = $2 AND state = $3`,
+		entityID, censusID, string(from), string(to))
+	if err != nil {
+		return fmt.Errorf("error updating census state: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("cannot get affected rows: %w", err)
+	} else if rows != 1 {
+		return fmt.Errorf("census is not %s", from)
+	}
+	if to == types.CensusClosed {
+		if _, err := tx.Exec(`UPDATE census_members SET private_key = NULL
+				WHERE census_id = $1 AND ephemeral AND private_key IS NOT NULL`, censusID); err != nil {
+			return fmt.Errorf("error dropping ephemeral keys: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commiting transactions to the DB: %w", err)
+	}
+	return nil
+}
+
 func (d *Database) CountCensus(entityID []byte) (int, error) {
 	if len(entityID) == 0 {
 		return 0, fmt.Errorf("invalid entity id")
@@ -1890,63 +2899,56 @@ func (d *Database) CountCensus(entityID []byte) (int, error) {
 	return censusCount, nil
 }
 
-func (d *Database) ListCensus(entityID []byte, filter *types.ListOptions) ([]types.Census, error) {
+// ListCensus returns the censuses of the entity sorted and paginated according
+// to the list options. If there are more censuses after the page, a cursor
+// pointing to the last returned census is also returned.
+func (d *Database) ListCensus(entityID []byte, filter *types.ListOptions) ([]types.Census, string, error) {
 	// check entityID
 	if len(entityID) == 0 {
-		return nil, fmt.Errorf("error retrieving target")
+		return nil, "", fmt.Errorf("error retrieving target")
 	}
-	// create select query
-	selectQuery := `SELECT id, entity_id, target_id, name, merkle_root, merkle_tree_uri, created_at, updated_at
-					FROM censuses
-					WHERE entity_id=$1
-					ORDER BY %s %s LIMIT $2 OFFSET $3`
-	// define default values for query args
-	t := reflect.TypeOf(types.Census{})
-	field, found := t.FieldByName(strings.Title("name"))
-	if !found {
-		return nil, fmt.Errorf("name field not found in DB. Something is very wrong")
-	}
-	orderField := field.Tag.Get("db")
-	order := "ASC"
-	var limit, offset sql.NullInt32
-	// default limit should be nil
-	if err := limit.Scan(nil); err != nil {
-		return nil, err
-	}
-	if err := offset.Scan(0); err != nil {
-		return nil, err
+	page, err := newListPage(filter, reflect.TypeOf(types.Census{}), "name", "id", "bytea")
+	if err != nil {
+		return nil, "", err
 	}
-	// offset = 0
-	// check filter
+	// an empty state matches all the censuses
+	var state types.CensusState
 	if filter != nil {
-		// check sortBy
-		if len(filter.SortBy) > 0 {
-			if field, found = t.FieldByName(strings.Title(filter.SortBy)); found {
-				if filter.Order == "descend" {
-					order = "DESC"
-				}
-				orderField = field.Tag.Get("db")
-			}
-		}
-		// check skip
-		if filter.Skip > 0 {
-			if err := offset.Scan(filter.Skip); err != nil {
-				return nil, err
-			}
-		}
-		// check count
-		if filter.Count > 0 {
-			if err := limit.Scan(filter.Count); err != nil {
-				return nil, err
-			}
-		}
+		state = filter.State
 	}
-	query := fmt.Sprintf(selectQuery, orderField, order)
-	var censuses []types.Census
-	if err := d.db.Select(&censuses, query, entityID, limit, offset); err != nil {
-		return nil, err
+	cursorWhere, cursorArgs := page.where(2)
+	orderLimit, limitArgs := page.orderLimit(2 + len(cursorArgs))
+	query := `SELECT id, entity_id, target_id, name, merkle_root, merkle_tree_uri, process_id, process_end_date,
+					weight as "pg_weight", state, created_at, updated_at, ` + page.cursorColumns() + `
+					FROM censuses
+					WHERE entity_id=$1 AND ($2 = '' OR state::text = $2) AND ` + cursorWhere + `
+					` + orderLimit
+	args := append(append([]interface{}{entityID, string(state)}, cursorArgs...), limitArgs...)
+	var rows []struct {
+		PGCensus
+		CursorValue string `db:"cursor_value"`
+		CursorID    string `db:"cursor_id"`
+	}
+	if err := d.db.Select(&rows, query, args...); err != nil {
+		return nil, "", err
+	}
+	var next string
+	if page.hasNext(len(rows)) {
+		rows = rows[:page.limit]
+		last := rows[len(rows)-1]
+		if next, err = page.nextCursor(last.CursorValue, last.CursorID); err != nil {
+			return nil, "", err
+		}
+	}
+	censuses := make([]types.Census, len(rows))
+	for i, row := range rows {
+		census, err := ToCensus(&row.PGCensus)
+		if err != nil {
+			return nil, "", fmt.Errorf("cannot convert census from postgres types: %w", err)
+		}
+		censuses[i] = *census
 	}
-	return censuses, nil
+	return censuses, next, nil
 }
 
 func (d *Database) DeleteCensus(entityID []byte, censusID []byte) error {
@@ -1955,7 +2957,8 @@ func (d *Database) DeleteCensus(entityID []byte, censusID []byte) error {
 		return fmt.Errorf("invalid arguments")
 	}
 
-	deleteQuery := `DELETE FROM censuses WHERE id = $1 and entity_id =$2`
+	// only draft and expanded censuses can be deleted
+	deleteQuery := `DELETE FROM censuses WHERE id = $1 and entity_id =$2 AND state IN ('draft', 'expanded')`
 	result, err := d.db.Exec(deleteQuery, censusID, entityID)
 	if err != nil {
 		return fmt.Errorf("error deleting census: %w", err)
@@ -2020,6 +3023,220 @@ func bulkInsert(tx *sqlx.Tx, bulkQuery string, bulkData interface{}, numField in
 	return nil
 }
 
+// AsOperator returns a copy of the database that records the changes of
+// members done on behalf of an entity as done by the operator address
+func (d *Database) AsOperator(address []byte) database.Database {
+	c := *d
+	c.operator = address
+	return &c
+}
+
+// memberEventActor returns the actor to record for a change done by the
+// given one, which is the operator when acting on behalf of the entity
+func (d *Database) memberEventActor(actorType string, actor []byte) (string, []byte) {
+	if actorType == types.MemberEventActorEntity && len(d.operator) > 0 {
+		return types.MemberEventActorOperator, d.operator
+	}
+	return actorType, actor
+}
+
+// beginMemberEvents begins a transaction in which the changes of members are
+// recorded in member_events as the given operation done by the actor
+func (d *Database) beginMemberEvents(operation, actorType string, actor []byte) (*sqlx.Tx, error) {
+	actorType, actor = d.memberEventActor(actorType, actor)
+	tx, err := d.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("cannot initialize postgres transaction: %w", err)
+	}
+	// the settings are local to the transaction and read by the members trigger
+	setQuery := `SELECT set_config('manager.member_event_operation', $1, true),
+					set_config('manager.member_event_actor_type', $2, true),
+					set_config('manager.member_event_actor', $3, true)`
+	if _, err := tx.Exec(setQuery, operation, actorType, hex.EncodeToString(actor)); err != nil {
+		if rollErr := tx.Rollback(); rollErr != nil {
+			return nil, fmt.Errorf("error rolling back: %v after failing to set member event actor: %w", rollErr, err)
+		}
+		return nil, fmt.Errorf("cannot set member event actor: %w", err)
+	}
+	return tx, nil
+}
+
+// MemberEvents returns the recorded changes of a member, oldest first.
+// The history is kept after the member is deleted.
+func (d *Database) MemberEvents(entityID []byte, memberID *uuid.UUID) ([]types.MemberEvent, error) {
+	if len(entityID) == 0 || memberID == nil {
+		return nil, fmt.Errorf("invalid arguments")
+	}
+	return memberEvents(d.db, entityID, memberID)
+}
+
+func memberEvents(q sqlx.Queryer, entityID []byte, memberID *uuid.UUID) ([]types.MemberEvent, error) {
+	selectQuery := `SELECT id, created_at, member_id, operation, actor_type, actor, changes as "pg_changes"
+					FROM member_events
+					WHERE entity_id = $1 AND member_id = $2
+					ORDER BY id`
+	var pgEvents []PGMemberEvent
+	if err := sqlx.Select(q, &pgEvents, selectQuery, entityID, *memberID); err != nil {
+		return nil, err
+	}
+	events := make([]types.MemberEvent, len(pgEvents))
+	for i := range pgEvents {
+		events[i] = pgEvents[i].MemberEvent
+		events[i].Changes = pgEvents[i].Changes.Bytes
+	}
+	return events, nil
+}
+
+// AddMemberEmails records an email of the given type sent to each one of the
+// members, the census and process IDs are optional
+func (d *Database) AddMemberEmails(entityID []byte, memberIDs []uuid.UUID, emailType string, censusID, processID []byte) error {
+	if len(entityID) == 0 || len(emailType) == 0 {
+		return fmt.Errorf("invalid arguments")
+	}
+	if len(memberIDs) == 0 {
+		return nil
+	}
+	ids := make([]string, len(memberIDs))
+	for i, memberID := range memberIDs {
+		ids[i] = memberID.String()
+	}
+	var pgIDs pgtype.TextArray
+	if err := pgIDs.Set(ids); err != nil {
+		return fmt.Errorf("cannot convert member IDs: %w", err)
+	}
+	insert := `INSERT INTO member_emails (entity_id, member_id, type, census_id, process_id)
+				SELECT m.entity_id, m.id, $3, $4, $5
+				FROM members m
+				WHERE m.entity_id = $1 AND m.id = ANY(CAST($2 AS uuid[]))`
+	if _, err := d.db.Exec(insert, entityID, pgIDs, emailType, censusID, processID); err != nil {
+		return fmt.Errorf("error recording emails sent to members of %x: %w", entityID, err)
+	}
+	return nil
+}
+
+// ExportMemberData returns everything stored about a member, including the
+// deleted but not yet purged ones, and records the export in its history
+func (d *Database) ExportMemberData(entityID []byte, memberID *uuid.UUID) (*types.MemberData, error) {
+	if len(entityID) == 0 || memberID == nil {
+		return nil, fmt.Errorf("invalid arguments")
+	}
+	tx, err := d.beginMemberEvents(types.MemberEventExport, types.MemberEventActorEntity, entityID)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var pgMember struct {
+		PGMember
+		Origin string `db:"pg_origin"`
+	}
+	selectQuery := `SELECT
+	 				id, entity_id, public_key, street_address, first_name, last_name, email as "pg_email", phone, date_of_birth, verified,
+					custom_fields as "pg_custom_fields", consented, tags as "pg_tags", origin as "pg_origin", created_at, updated_at, deleted_at
+					FROM members WHERE id = $1 AND entity_id = $2 AND erased_at IS NULL`
+	if err := tx.QueryRowx(selectQuery, memberID, entityID).StructScan(&pgMember); err != nil {
+		return nil, err
+	}
+	data := &types.MemberData{
+		ExportedAt: time.Now(),
+		Member:     ToMember(&pgMember.PGMember),
+		Tags:       []string{},
+		Censuses:   []types.MemberCensus{},
+		Emails:     []types.MemberEmail{},
+	}
+	data.Member.Origin = types.ToOrigin(pgMember.Origin)
+	tagsQuery := `SELECT t.name FROM tags t
+					INNER JOIN members m ON m.entity_id = t.entity_id AND t.id = ANY(m.tags)
+					WHERE m.id = $1 AND t.entity_id = $2
+					ORDER BY t.name`
+	if err := tx.Select(&data.Tags, tagsQuery, memberID, entityID); err != nil {
+		return nil, fmt.Errorf("cannot retrieve member tags: %w", err)
+	}
+	censusesQuery := `SELECT c.id, c.name, c.created_at, c.ephemeral FROM census_members cm
+					INNER JOIN censuses c ON c.id = cm.census_id
+					WHERE cm.member_id = $1 AND c.entity_id = $2
+					ORDER BY c.created_at`
+	if err := tx.Select(&data.Censuses, censusesQuery, memberID, entityID); err != nil {
+		return nil, fmt.Errorf("cannot retrieve member censuses: %w", err)
+	}
+	emailsQuery := `SELECT id, created_at, member_id, type, census_id, process_id FROM member_emails
+					WHERE member_id = $1 AND entity_id = $2
+					ORDER BY id`
+	if err := tx.Select(&data.Emails, emailsQuery, memberID, entityID); err != nil {
+		return nil, fmt.Errorf("cannot retrieve member emails: %w", err)
+	}
+	if len(data.Member.PubKey) > 0 {
+		var user types.User
+		userQuery := `SELECT public_key, digested_public_key, created_at, updated_at FROM users WHERE public_key = $1`
+		if err := tx.Get(&user, userQuery, data.Member.PubKey); err != nil && err != sql.ErrNoRows {
+			return nil, fmt.Errorf("cannot retrieve member user: %w", err)
+		} else if err == nil {
+			data.User = &user
+		}
+	}
+	if data.Events, err = memberEvents(tx, entityID, memberID); err != nil {
+		return nil, fmt.Errorf("cannot retrieve member events: %w", err)
+	}
+	// the export does not modify the member so its event is recorded here
+	actorType, actor := d.memberEventActor(types.MemberEventActorEntity, entityID)
+	record := `INSERT INTO member_events (entity_id, member_id, operation, actor_type, actor)
+				VALUES ($1, $2, $3, $4, $5)`
+	if _, err := tx.Exec(record, entityID, memberID, types.MemberEventExport, actorType, actor); err != nil {
+		return nil, fmt.Errorf("cannot record member export: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error commiting transactions to the DB: %w", err)
+	}
+	return data, nil
+}
+
+// EraseMember anonymizes a member on a data subject erasure request. Its
+// personal data, tags and public key are cleared, the member is deleted and
+// the values recorded in its history are removed, keeping only the operations.
+// Its census rows are kept so that the censuses it is part of remain
+// verifiable, and its user is deleted unless it is a member of other entities.
+func (d *Database) EraseMember(entityID []byte, memberID *uuid.UUID) error {
+	if len(entityID) == 0 || memberID == nil {
+		return fmt.Errorf("invalid arguments")
+	}
+	tx, err := d.beginMemberEvents(types.MemberEventErase, types.MemberEventActorEntity, entityID)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var pubKey []byte
+	selectQuery := `SELECT public_key FROM members
+					WHERE id = $1 AND entity_id = $2 AND erased_at IS NULL
+					FOR UPDATE`
+	if err := tx.Get(&pubKey, selectQuery, memberID, entityID); err != nil {
+		return err
+	}
+	erase := `UPDATE members SET
+				street_address = '', first_name = '', last_name = '', email = '', phone = '',
+				date_of_birth = $3, custom_fields = '{}'::jsonb, tags = '{}', public_key = NULL, consented = false,
+				deleted_at = COALESCE(deleted_at, now()), erased_at = now(), updated_at = now()
+				WHERE id = $1 AND entity_id = $2`
+	if _, err := tx.Exec(erase, memberID, entityID, time.Time{}); err != nil {
+		return fmt.Errorf("cannot erase member: %w", err)
+	}
+	// the history, including the erase event, keeps no member data
+	scrub := `UPDATE member_events SET changes = '{}'::jsonb WHERE entity_id = $1 AND member_id = $2`
+	if _, err := tx.Exec(scrub, entityID, memberID); err != nil {
+		return fmt.Errorf("cannot erase member history: %w", err)
+	}
+	if len(pubKey) > 0 {
+		deleteUser := `DELETE FROM users u WHERE u.public_key = $1
+						AND NOT EXISTS (SELECT 1 FROM members m WHERE m.public_key = u.public_key)`
+		if _, err := tx.Exec(deleteUser, pubKey); err != nil {
+			return fmt.Errorf("cannot delete member user: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commiting transactions to the DB: %w", err)
+	}
+	return nil
+}
+
 func (d *Database) Ping() error {
 	return d.db.Ping()
 }
@@ -9,6 +9,9 @@ type PGEntity struct {
 	types.Entity
 	CensusManagersAddresses pgtype.ByteaArray `json:"censusManagersAddresses" db:"pg_census_managers_addresses"`
 	Origins                 pgtype.EnumArray  `db:"origins"`
+	CustomFieldsSchema      pgtype.JSONB      `db:"pg_custom_fields_schema"`
+	Retention               pgtype.JSONB      `db:"pg_retention"`
+	Quotas                  pgtype.JSONB      `db:"pg_quotas"`
 }
 
 func ToPGEntity(x *types.Entity) (*PGEntity, error) {
@@ -26,6 +29,24 @@ func ToPGEntity(x *types.Entity) (*PGEntity, error) {
 		return nil, err
 	}
 	y.Origins = *pgOrigins
+	// a nil schema is kept as NULL so that updates leave the stored one untouched
+	if x.CustomFieldsSchema == nil {
+		y.CustomFieldsSchema = pgtype.JSONB{Status: pgtype.Null}
+	} else if err := y.CustomFieldsSchema.Set(x.CustomFieldsSchema); err != nil {
+		return nil, err
+	}
+	// same for the retention settings
+	if x.Retention == nil {
+		y.Retention = pgtype.JSONB{Status: pgtype.Null}
+	} else if err := y.Retention.Set(x.Retention); err != nil {
+		return nil, err
+	}
+	// and the quotas
+	if x.Quotas == nil {
+		y.Quotas = pgtype.JSONB{Status: pgtype.Null}
+	} else if err := y.Quotas.Set(x.Quotas); err != nil {
+		return nil, err
+	}
 	return y, nil
 }
 
@@ -42,6 +63,23 @@ func ToEntity(x *PGEntity) (*types.Entity, error) {
 		}
 		y.EntityInfo.Origins = origins
 	}
+	if x.CustomFieldsSchema.Status == pgtype.Present {
+		if err := x.CustomFieldsSchema.AssignTo(&y.EntityInfo.CustomFieldsSchema); err != nil {
+			return nil, err
+		}
+	}
+	if x.Retention.Status == pgtype.Present {
+		y.EntityInfo.Retention = &types.RetentionSettings{}
+		if err := x.Retention.AssignTo(y.EntityInfo.Retention); err != nil {
+			return nil, err
+		}
+	}
+	if x.Quotas.Status == pgtype.Present {
+		y.EntityInfo.Quotas = &types.Quotas{}
+		if err := x.Quotas.AssignTo(y.EntityInfo.Quotas); err != nil {
+			return nil, err
+		}
+	}
 
 	// err = x.Origins.AssignTo(&y.EntityInfo.Origins)
 	if err != nil {
@@ -119,7 +157,9 @@ func ToPGMember(x *types.Member) (*PGMember, error) {
 func ToMember(x *PGMember) *types.Member {
 	y := x.Member
 	// y.MemberInfo.CustomFields = x.CustomFields.Bytes
-	x.CustomFields.AssignTo(y.MemberInfo.CustomFields)
+	if x.CustomFields.Status == pgtype.Present {
+		x.CustomFields.AssignTo(&y.MemberInfo.CustomFields)
+	}
 	x.Tags.AssignTo(&y.MemberInfo.Tags)
 	if x.Email.Status != pgtype.Null || len(x.Email.String) > 0 {
 		y.Email = x.Email.String
@@ -156,6 +196,38 @@ func ToEphemeralMemberInfo(x *PGEphemeralMemberInfo) *types.EphemeralMemberInfo
 	return &y
 }
 
+type PGMemberEvent struct {
+	types.MemberEvent
+	Changes pgtype.JSONB `db:"pg_changes"`
+}
+
+type PGCensus struct {
+	types.Census
+	Weight pgtype.JSONB `db:"pg_weight"`
+}
+
+func ToPGCensus(x *types.Census) (*PGCensus, error) {
+	y := &PGCensus{Census: *x}
+	// a nil weight source is kept as NULL, which weighs every member 1
+	if x.Weight == nil {
+		y.Weight = pgtype.JSONB{Status: pgtype.Null}
+	} else if err := y.Weight.Set(x.Weight); err != nil {
+		return nil, err
+	}
+	return y, nil
+}
+
+func ToCensus(x *PGCensus) (*types.Census, error) {
+	y := x.Census
+	if x.Weight.Status == pgtype.Present {
+		y.CensusInfo.Weight = &types.CensusWeight{}
+		if err := x.Weight.AssignTo(y.CensusInfo.Weight); err != nil {
+			return nil, err
+		}
+	}
+	return &y, nil
+}
+
 //go:generate stringer -type=OrderBySQLi
 type OrderBySQLi int
 
@@ -5,11 +5,13 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	_ "github.com/lib/pq"
 	migrate "github.com/rubenv/sql-migrate"
+	"go.vocdoni.io/manager/database"
 	"go.vocdoni.io/manager/types"
 )
 
@@ -25,6 +27,8 @@ var Signers = []struct {
 	{"0399d0ad8447520e66df7db954b0936f4b141a01ba6213dda88c9df7293b66262e", "1c1c5c24be0d76e5f7c853902e9e23ced013a597aca7573861c8cd0a160ca357"},
 	// MemberPubKey() no rows
 	{"026163a9bc3425426bbb7f0fde6c9bb4504493415a34b99a84162fe01640a784a3", "1c1c5c24be0d76e5f7c853902e9e23ced013a597aca7573861c8cd0a160ca372"},
+	// entity pending approval
+	{"03523ce75a2e82495bc70d59857a703459778fe3433365612be4c805d62e431745", "1c1c5c24be0d76e5f7c853902e9e23ced013a597aca7573861c8cd0a160ca379"},
 }
 
 type Database struct {
@@ -59,6 +63,10 @@ func (d *Database) Entity(entityID []byte) (*types.Entity, error) {
 	entity.CensusManagersAddresses = [][]byte{managerAddresses}
 	entity.Name = "test entity"
 	entity.Email = "entity@entity.org"
+	entity.IsAuthorized = hex.EncodeToString(entityID) != "b42ebae4e542b9b5ce7457fffe5fb3a1d9fb8fa5"
+	if hex.EncodeToString(entityID) == "6d3e07d7d1dd84469cc3adf49fa83daf2678b4c9" {
+		entity.Quotas = &types.Quotas{Censuses: 1, EmailsPerDay: 5}
+	}
 
 	failEidID := hex.EncodeToString(entityID)
 	if failEidID == "ca526af2aaa0f3e9bb68ab80de4392590f7b153a" {
@@ -68,6 +76,60 @@ func (d *Database) Entity(entityID []byte) (*types.Entity, error) {
 	return &entity, nil
 }
 
+func (d *Database) EntityOperators(entityID []byte) ([]types.Operator, error) {
+	failEid := hex.EncodeToString(entityID)
+	if failEid == "09fa012e40f844b073fab7fcbd7f7a5716c1a365" {
+		return nil, fmt.Errorf("error retrieving operators of entity: %s", failEid)
+	}
+	return []types.Operator{{Address: entityID, Role: types.OperatorAdmin}}, nil
+}
+
+// EntityOperatorRole makes the address of Signers[2] an editor, the one of
+// Signers[3] a viewer and the one of Signers[4] an admin of the entity of
+// Signers[1]
+func (d *Database) EntityOperatorRole(entityID, address []byte) (types.OperatorRole, error) {
+	if hex.EncodeToString(entityID) != "5fa506aa68191bcc657795e57f080472e712c27d" {
+		return "", sql.ErrNoRows
+	}
+	switch hex.EncodeToString(address) {
+	case "c87363d9919daef530bf19e907df7f2d8920be75":
+		return types.OperatorEditor, nil
+	case "6d3e07d7d1dd84469cc3adf49fa83daf2678b4c9":
+		return types.OperatorViewer, nil
+	case "b42ebae4e542b9b5ce7457fffe5fb3a1d9fb8fa5":
+		return types.OperatorAdmin, nil
+	default:
+		return "", sql.ErrNoRows
+	}
+}
+
+func (d *Database) SetEntityOperator(entityID, address []byte, role types.OperatorRole) error {
+	failEid := hex.EncodeToString(entityID)
+	if failEid == "09fa012e40f844b073fab7fcbd7f7a5716c1a365" {
+		return fmt.Errorf("error setting operator of entity: %s", failEid)
+	}
+	return nil
+}
+
+func (d *Database) RemoveEntityOperator(entityID, address []byte) (int, error) {
+	failEid := hex.EncodeToString(entityID)
+	if failEid == "09fa012e40f844b073fab7fcbd7f7a5716c1a365" {
+		return 0, fmt.Errorf("error removing operator of entity: %s", failEid)
+	}
+	return 1, nil
+}
+
+func (d *Database) EntityCustomFieldsSchema(entityID []byte) (types.CustomFieldsSchema, error) {
+	failEid := hex.EncodeToString(entityID)
+	if failEid == "6d3e07d7d1dd84469cc3adf49fa83daf2678b4c9" {
+		return types.CustomFieldsSchema{
+			{Name: "shares", Type: types.CustomFieldNumber, Required: true},
+			{Name: "branch", Type: types.CustomFieldEnum, Values: []string{"Barcelona", "Girona"}},
+		}, nil
+	}
+	return nil, nil
+}
+
 func (d *Database) EntitiesID() ([]string, error) {
 	return nil, nil
 }
@@ -76,6 +138,16 @@ func (d *Database) AdminEntityList() ([]types.Entity, error) {
 	return nil, nil
 }
 
+func (d *Database) CountEntities(search string) (int, error) {
+	return 1, nil
+}
+
+func (d *Database) ListEntities(filter *types.ListOptions) ([]types.Entity, string, error) {
+	membersCount, censusesCount := 10, 1
+	return []types.Entity{{ID: []byte{1}, EntityInfo: types.EntityInfo{Name: "test entity"},
+		MembersCount: &membersCount, CensusesCount: &censusesCount}}, "", nil
+}
+
 func (d *Database) EntityHas(entityID []byte, memberID *uuid.UUID) bool {
 	return true
 }
@@ -98,6 +170,51 @@ func (d *Database) DeleteEntity(entityID []byte) error {
 	return nil
 }
 
+// CountActiveProcessCensuses returns a census with an active process for the
+// entity of Signers[3]
+func (d *Database) CountActiveProcessCensuses(entityID []byte, since time.Time) (int, error) {
+	switch hex.EncodeToString(entityID) {
+	case "09fa012e40f844b073fab7fcbd7f7a5716c1a365":
+		return 0, fmt.Errorf("error counting censuses of entity: %x", entityID)
+	case "6d3e07d7d1dd84469cc3adf49fa83daf2678b4c9":
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func (d *Database) SetEntityQuotas(entityID []byte, quotas *types.Quotas) (int, error) {
+	if hex.EncodeToString(entityID) == "09fa012e40f844b073fab7fcbd7f7a5716c1a365" {
+		return 0, fmt.Errorf("error setting quotas of entity: %x", entityID)
+	}
+	return 1, nil
+}
+
+// EntityUsage returns the same usage for every entity, which exceeds the
+// censuses and emails quotas of the entity of Signers[3]
+func (d *Database) EntityUsage(entityID []byte, day, month time.Time) (*types.Usage, error) {
+	if hex.EncodeToString(entityID) == "09fa012e40f844b073fab7fcbd7f7a5716c1a365" {
+		return nil, fmt.Errorf("error retrieving usage of entity: %x", entityID)
+	}
+	return &types.Usage{Members: 10, Censuses: 1, EmailsToday: 5}, nil
+}
+
+func (d *Database) AddFaucetRequest(entityID []byte) error {
+	return nil
+}
+
+// ReserveEmails reserves the emails on top of the 5 emails sent today by every
+// entity, see EntityUsage
+func (d *Database) ReserveEmails(entityID []byte, day time.Time, count, limit int) (bool, error) {
+	if hex.EncodeToString(entityID) == "09fa012e40f844b073fab7fcbd7f7a5716c1a365" {
+		return false, fmt.Errorf("error reserving emails of entity: %x", entityID)
+	}
+	return limit == 0 || 5+count <= limit, nil
+}
+
+func (d *Database) ReleaseEmails(entityID []byte, day time.Time, count int) error {
+	return nil
+}
+
 func (d *Database) AuthorizeEntity(entityID []byte) error {
 	if fmt.Sprintf("%x", entityID) == "09fa012e40f844b073fab7fcbd7f7a5716c1a365" {
 		return fmt.Errorf("error adding entity with id: %x", entityID)
@@ -162,7 +279,7 @@ func (d *Database) MemberPubKey(entityID, pubKey []byte) (*types.Member, error)
 	return &member, nil
 }
 
-func (d *Database) CountMembers(entityID []byte) (int, error) {
+func (d *Database) CountMembers(entityID []byte, search string) (int, error) {
 	failEid := hex.EncodeToString(entityID)
 	if failEid == "09fa012e40f844b073fab7fcbd7f7a5716c1a365" {
 		return 0, fmt.Errorf("error counting members of entity: %s", failEid)
@@ -170,15 +287,24 @@ func (d *Database) CountMembers(entityID []byte) (int, error) {
 	return 0, nil
 }
 
-func (d *Database) ListMembers(entityID []byte, filter *types.ListOptions) ([]types.Member, error) {
+func (d *Database) ListMembers(entityID []byte, filter *types.ListOptions) ([]types.Member, string, error) {
 	failEid := hex.EncodeToString(entityID)
 	if failEid == "5fa506aa68191bcc657795e57f080472e712c27d" {
-		return nil, sql.ErrNoRows
+		return nil, "", sql.ErrNoRows
 	}
 	if failEid == "09fa012e40f844b073fab7fcbd7f7a5716c1a365" {
-		return nil, fmt.Errorf("cannot list members")
+		return nil, "", fmt.Errorf("cannot list members")
 	}
-	return nil, nil
+	return nil, "", nil
+}
+
+func (d *Database) ListDeletedMembers(entityID []byte, filter *types.ListOptions) ([]types.Member, string, error) {
+	failEid := hex.EncodeToString(entityID)
+	if failEid == "09fa012e40f844b073fab7fcbd7f7a5716c1a365" {
+		return nil, "", fmt.Errorf("cannot list deleted members")
+	}
+	deletedAt := time.Now()
+	return []types.Member{{ID: uuid.New(), EntityID: entityID, DeletedAt: &deletedAt}}, "", nil
 }
 
 func (d *Database) Census(entityID, censusID []byte) (*types.Census, error) {
@@ -188,6 +314,18 @@ func (d *Database) Census(entityID, censusID []byte) (*types.Census, error) {
 	}
 	var census types.Census
 	census.ID = []byte("0x0")
+	census.State = types.CensusDraft
+	// the census 0b5e55ed is published
+	if hex.EncodeToString(censusID) == "0b5e55ed" {
+		census.State = types.CensusPublished
+		census.ProcessID = []byte{1}
+		census.MerkleRoot = []byte{1}
+	}
+	// the root of the censuses of the entity of Signers[3] is the one of the
+	// tree of DumpCensusWeightedClaims
+	if failEid == "6d3e07d7d1dd84469cc3adf49fa83daf2678b4c9" {
+		census.MerkleRoot, _ = hex.DecodeString("ba44f94000e34c1a7ab5f347e106fac68d1030899bec5dc554eaafa6c0127907")
+	}
 	return &census, nil
 }
 
@@ -199,14 +337,14 @@ func (d *Database) CountCensus(entityID []byte) (int, error) {
 	return 0, nil
 }
 
-func (d *Database) ListCensus(entityID []byte, filter *types.ListOptions) ([]types.Census, error) {
+func (d *Database) ListCensus(entityID []byte, filter *types.ListOptions) ([]types.Census, string, error) {
 	if fmt.Sprintf("%x", entityID) == "5fa506aa68191bcc657795e57f080472e712c27d" {
-		return nil, sql.ErrNoRows
+		return nil, "", sql.ErrNoRows
 	}
 	if fmt.Sprintf("%x", entityID) == "09fa012e40f844b073fab7fcbd7f7a5716c1a365" {
-		return nil, fmt.Errorf("cannot list census from entity: %x", entityID)
+		return nil, "", fmt.Errorf("cannot list census from entity: %x", entityID)
 	}
-	return nil, nil
+	return nil, "", nil
 }
 
 func (d *Database) AddCensus(entityID, censusID []byte, targetID *uuid.UUID, info *types.CensusInfo) error {
@@ -276,6 +414,26 @@ func (d *Database) DeleteMembersByKeys(entityID []byte, memberKeys [][]byte) (in
 	return len(memberKeys), [][]byte{}, nil
 }
 
+func (d *Database) RestoreMembers(entityID []byte, members []uuid.UUID) (int, []uuid.UUID, error) {
+	failEid := hex.EncodeToString(entityID)
+	if failEid == "09fa012e40f844b073fab7fcbd7f7a5716c1a365" {
+		return 0, nil, fmt.Errorf("error restoring members of entity: %s", failEid)
+	}
+	return len(members), []uuid.UUID{}, nil
+}
+
+func (d *Database) PurgeDeletedMembers(before time.Time) (int, error) {
+	return 0, nil
+}
+
+func (d *Database) PurgeUnverifiedMembers() (map[string]int, error) {
+	return map[string]int{}, nil
+}
+
+func (d *Database) DropEphemeralKeys() (map[string]int, error) {
+	return map[string]int{}, nil
+}
+
 func (d *Database) ImportMembersWithPubKey(entityID []byte, info []types.MemberInfo) error {
 	failEid := hex.EncodeToString(entityID)
 	if failEid == "5fa506aa68191bcc657795e57f080472e712c27d" {
@@ -292,6 +450,16 @@ func (d *Database) ImportMembers(entityID []byte, info []types.MemberInfo) error
 	return nil
 }
 
+func (d *Database) ExistingMemberEmails(entityID []byte, emails []string) ([]string, error) {
+	var existing []string
+	for _, email := range emails {
+		if strings.HasPrefix(email, "existing") {
+			existing = append(existing, email)
+		}
+	}
+	return existing, nil
+}
+
 func (d *Database) AddMemberBulk(entityID []byte, members []types.Member) error {
 	return nil
 }
@@ -304,12 +472,98 @@ func (d *Database) UpdateMember(entityID []byte, memberID *uuid.UUID, info *type
 	return 1, nil
 }
 
+func (d *Database) UpdateMembers(entityID []byte, members []uuid.UUID, patch *types.MemberPatch) (int, []uuid.UUID, error) {
+	failEid := hex.EncodeToString(entityID)
+	if failEid == "09fa012e40f844b073fab7fcbd7f7a5716c1a365" {
+		return 0, nil, fmt.Errorf("error updating members of entity: %s", failEid)
+	}
+	return len(members), []uuid.UUID{}, nil
+}
+
+func (d *Database) UpdateMembersByFilters(entityID []byte, filters *types.TargetFilters, patch *types.MemberPatch) (int, error) {
+	failEid := hex.EncodeToString(entityID)
+	if failEid == "09fa012e40f844b073fab7fcbd7f7a5716c1a365" {
+		return 0, fmt.Errorf("error updating members of entity: %s", failEid)
+	}
+	return 2, nil
+}
+
+func (d *Database) AddMemberEmails(entityID []byte, memberIDs []uuid.UUID, emailType string, censusID, processID []byte) error {
+	return nil
+}
+
+func (d *Database) ExportMemberData(entityID []byte, memberID *uuid.UUID) (*types.MemberData, error) {
+	failEid := hex.EncodeToString(entityID)
+	if failEid == "09fa012e40f844b073fab7fcbd7f7a5716c1a365" {
+		return nil, fmt.Errorf("error exporting member of entity: %s", failEid)
+	}
+	if failEid == "5fa506aa68191bcc657795e57f080472e712c27d" {
+		return nil, sql.ErrNoRows
+	}
+	member, _ := d.Member(entityID, memberID)
+	return &types.MemberData{
+		ExportedAt: time.Now(),
+		Member:     member,
+		Tags:       []string{"VoteEmailSent"},
+		Censuses:   []types.MemberCensus{},
+		Emails:     []types.MemberEmail{{ID: 1, MemberID: *memberID, Type: types.MemberEmailVotingLink}},
+		Events:     []types.MemberEvent{{ID: 1, MemberID: *memberID, Operation: types.MemberEventCreate}},
+	}, nil
+}
+
+func (d *Database) EraseMember(entityID []byte, memberID *uuid.UUID) error {
+	failEid := hex.EncodeToString(entityID)
+	if failEid == "09fa012e40f844b073fab7fcbd7f7a5716c1a365" {
+		return fmt.Errorf("error erasing member of entity: %s", failEid)
+	}
+	if failEid == "5fa506aa68191bcc657795e57f080472e712c27d" {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (d *Database) MemberEvents(entityID []byte, memberID *uuid.UUID) ([]types.MemberEvent, error) {
+	failEid := hex.EncodeToString(entityID)
+	if failEid == "09fa012e40f844b073fab7fcbd7f7a5716c1a365" {
+		return nil, fmt.Errorf("error retrieving member events of entity: %s", failEid)
+	}
+	return []types.MemberEvent{
+		{
+			ID:        1,
+			MemberID:  *memberID,
+			Operation: types.MemberEventCreate,
+			ActorType: types.MemberEventActorEntity,
+			Actor:     entityID,
+			Changes:   []byte(`{"email": {"after": "old@vocdoni.io", "before": null}}`),
+		},
+		{
+			ID:        2,
+			MemberID:  *memberID,
+			Operation: types.MemberEventUpdate,
+			ActorType: types.MemberEventActorEntity,
+			Actor:     entityID,
+			Changes:   []byte(`{"email": {"after": "new@vocdoni.io", "before": "old@vocdoni.io"}}`),
+		},
+	}, nil
+}
+
+func (d *Database) AsOperator(address []byte) database.Database {
+	return d
+}
+
 func (d *Database) AddTag(entityID []byte, tagName string) (int32, error) {
 	return 1, nil
 }
 func (d *Database) ListTags(entityID []byte) ([]types.Tag, error) {
 	return nil, nil
 }
+func (d *Database) UpdateTag(entityID []byte, tagID int32, tag *types.Tag) (int, error) {
+	failEid := hex.EncodeToString(entityID)
+	if failEid == "09fa012e40f844b073fab7fcbd7f7a5716c1a365" {
+		return 0, fmt.Errorf("error updating tag of entity: %s", failEid)
+	}
+	return 1, nil
+}
 func (d *Database) DeleteTag(entityID []byte, tagID int32) error {
 	return nil
 }
@@ -329,6 +583,22 @@ func (d *Database) RemoveTagFromMembers(entityID []byte, members []uuid.UUID, ta
 	return len(members), []uuid.UUID{}, nil
 }
 
+func (d *Database) AddTagToMembersByFilters(entityID []byte, filters *types.TargetFilters, tagID int32) (int, error) {
+	failEid := hex.EncodeToString(entityID)
+	if failEid == "09fa012e40f844b073fab7fcbd7f7a5716c1a365" {
+		return 0, fmt.Errorf("error adding tag to members of entity: %s", failEid)
+	}
+	return 2, nil
+}
+
+func (d *Database) RemoveTagFromMembersByFilters(entityID []byte, filters *types.TargetFilters, tagID int32) (int, error) {
+	failEid := hex.EncodeToString(entityID)
+	if failEid == "09fa012e40f844b073fab7fcbd7f7a5716c1a365" {
+		return 0, fmt.Errorf("error removing tag from members of entity: %s", failEid)
+	}
+	return 2, nil
+}
+
 func (d *Database) CreateMembersWithTokens(entityID []byte, tokens []uuid.UUID) error {
 	failEid := hex.EncodeToString(entityID)
 	if failEid == "5fa506aa68191bcc657795e57f080472e712c27d" {
@@ -367,8 +637,35 @@ func (d *Database) DumpClaims(entityID []byte) ([][]byte, error) {
 	return nil, nil
 }
 
+func (d *Database) DumpTargetClaims(entityID []byte, targetID *uuid.UUID) ([][]byte, int, error) {
+	failEid := hex.EncodeToString(entityID)
+	if failEid == "5fa506aa68191bcc657795e57f080472e712c27d" {
+		return nil, 0, fmt.Errorf("error dumping target claims of entity: %s", failEid)
+	}
+	return nil, 0, nil
+}
+
+// DumpCensusClaims returns the public keys of Signers[3] and Signers[4] as
+// the claims of the censuses of the entity of Signers[3]
 func (d *Database) DumpCensusClaims(entityID []byte, censusID []byte) ([][]byte, error) {
-	return nil, nil
+	if hex.EncodeToString(entityID) != "6d3e07d7d1dd84469cc3adf49fa83daf2678b4c9" {
+		return nil, nil
+	}
+	claims := make([][]byte, 2)
+	for i, signer := range Signers[3:5] {
+		claims[i], _ = hex.DecodeString(signer.Pub)
+	}
+	return claims, nil
+}
+
+// DumpCensusWeightedClaims returns the claims of DumpCensusClaims weighing
+// 1 and 2 respectively
+func (d *Database) DumpCensusWeightedClaims(entityID, censusID []byte) ([][]byte, []uint64, error) {
+	claims, err := d.DumpCensusClaims(entityID, censusID)
+	if err != nil || len(claims) == 0 {
+		return nil, nil, err
+	}
+	return claims, []uint64{1, 2}, nil
 }
 
 func (d *Database) ExpandCensusMembers(entityID, censusID []byte) ([]types.CensusMember, error) {
@@ -383,10 +680,54 @@ func (d *Database) EphemeralMemberInfoByEmail(entityID, censusID []byte, email s
 	return nil, nil
 }
 
+// CensusMember returns Signers[4] as the member of the censuses of the entity
+// of Signers[3] for any member ID
+func (d *Database) CensusMember(entityID, censusID []byte, memberID *uuid.UUID) (*types.CensusMember, error) {
+	switch hex.EncodeToString(entityID) {
+	case "09fa012e40f844b073fab7fcbd7f7a5716c1a365":
+		return nil, fmt.Errorf("error retrieving census member of entity: %x", entityID)
+	case "6d3e07d7d1dd84469cc3adf49fa83daf2678b4c9":
+		pubKey, _ := hex.DecodeString(Signers[4].Pub)
+		return &types.CensusMember{MemberID: *memberID, CensusID: censusID, DigestedPubKey: pubKey}, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
 func (d *Database) UpdateCensus(entityID, censusID []byte, info *types.CensusInfo) (int, error) {
 	return 1, nil
 }
 
+// FlagInvalidClaims flags the given claims as the ones of new members, except
+// the public key of Signers[4] which is not in the census
+func (d *Database) FlagInvalidClaims(entityID, censusID []byte, claims [][]byte, reasons []string) ([]types.InvalidClaim, error) {
+	if hex.EncodeToString(entityID) == "09fa012e40f844b073fab7fcbd7f7a5716c1a365" {
+		return nil, fmt.Errorf("error flagging invalid claims of entity: %x", entityID)
+	}
+	var invalidClaims []types.InvalidClaim
+	for i, claim := range claims {
+		if hex.EncodeToString(claim) == Signers[4].Pub {
+			continue
+		}
+		memberID := uuid.New()
+		invalidClaims = append(invalidClaims, types.InvalidClaim{MemberID: &memberID, Claim: claim, Reason: reasons[i]})
+	}
+	return invalidClaims, nil
+}
+
+func (d *Database) CensusInvalidClaims(entityID, censusID []byte) ([]types.InvalidClaim, error) {
+	if hex.EncodeToString(entityID) == "09fa012e40f844b073fab7fcbd7f7a5716c1a365" {
+		return nil, fmt.Errorf("error retrieving invalid claims of entity: %x", entityID)
+	}
+	return nil, nil
+}
+
+func (d *Database) SetCensusState(entityID, censusID []byte, from, to types.CensusState) error {
+	if hex.EncodeToString(entityID) == "09fa012e40f844b073fab7fcbd7f7a5716c1a365" {
+		return fmt.Errorf("error setting census state of entity: %x", entityID)
+	}
+	return nil
+}
+
 func (d *Database) AddTarget(entityID []byte, target *types.Target) (uuid.UUID, error) {
 	failEid := hex.EncodeToString(entityID)
 	if failEid == "5fa506aa68191bcc657795e57f080472e712c27d" {
@@ -405,6 +746,20 @@ func (d *Database) Target(entityID []byte, targetID *uuid.UUID) (*types.Target,
 	}
 	return nil, nil
 }
+func (d *Database) UpdateTarget(entityID []byte, targetID *uuid.UUID, target *types.Target) (int, error) {
+	failEid := hex.EncodeToString(entityID)
+	if failEid == "09fa012e40f844b073fab7fcbd7f7a5716c1a365" {
+		return 0, fmt.Errorf("error updating target of entity: %s", failEid)
+	}
+	return 1, nil
+}
+func (d *Database) DeleteTarget(entityID []byte, targetID *uuid.UUID) error {
+	failEid := hex.EncodeToString(entityID)
+	if failEid == "09fa012e40f844b073fab7fcbd7f7a5716c1a365" {
+		return fmt.Errorf("target is referenced by existing censuses")
+	}
+	return nil
+}
 func (d *Database) ListTargets(entityID []byte) ([]types.Target, error) {
 	failEid := hex.EncodeToString(entityID)
 	if failEid == "c87363d9919daef530bf19e907df7f2d8920be75" {
@@ -432,6 +787,24 @@ func (d *Database) TargetMembers(entityID []byte, targetID *uuid.UUID) ([]types.
 	return nil, nil
 }
 
+func (d *Database) StreamMembers(entityID []byte, filters *types.TargetFilters, fn func(member *types.Member) error) error {
+	failEid := hex.EncodeToString(entityID)
+	if failEid == "5fa506aa68191bcc657795e57f080472e712c27d" {
+		return fmt.Errorf("error streaming members of entity: %s", failEid)
+	}
+	members := []types.Member{
+		{ID: uuid.New(), PubKey: []byte{1, 2, 3}, MemberInfo: types.MemberInfo{FirstName: "John", LastName: "Smith", Email: "john@vocdoni.io",
+			CustomFields: []byte(`{"branch": {"city": "Barcelona"}, "shares": 10}`)}},
+		{ID: uuid.New(), MemberInfo: types.MemberInfo{FirstName: "Jane", LastName: "Doe", Email: "jane@vocdoni.io"}},
+	}
+	for i := range members {
+		if err := fn(&members[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (d *Database) AddUser(user *types.User) error {
 	failPub := hex.EncodeToString(user.PubKey)
 	if failPub == Signers[1].Pub {
@@ -0,0 +1,123 @@
+package manager
+
+import (
+	"database/sql"
+	"fmt"
+
+	"go.vocdoni.io/dvote/log"
+	"go.vocdoni.io/manager/types"
+)
+
+func (m *Manager) listEntities(request *types.APIrequest) (*types.APIresponse, error) {
+	var err error
+	var response types.APIresponse
+
+	if err = checkOptions(request.ListOptions, request.Method); err != nil {
+		log.Warnf("invalid filter options %q: (%v)", request.ListOptions, err)
+		return nil, fmt.Errorf("invalid filter options")
+	}
+
+	if response.Entities, response.NextCursor, err = m.db.ListEntities(request.ListOptions); err != nil {
+		log.Errorf("cannot list entities: (%v)", err)
+		return nil, fmt.Errorf("cannot list entities")
+	}
+
+	log.Debugf("admin listEntities: %d entities", len(response.Entities))
+	return &response, nil
+}
+
+func (m *Manager) countEntities(request *types.APIrequest) (*types.APIresponse, error) {
+	var err error
+	var response types.APIresponse
+
+	if err = checkOptions(request.ListOptions, request.Method); err != nil {
+		log.Warnf("invalid filter options %q: (%v)", request.ListOptions, err)
+		return nil, fmt.Errorf("invalid filter options")
+	}
+	var search string
+	if request.ListOptions != nil {
+		search = request.ListOptions.Search
+	}
+
+	if response.Count, err = m.db.CountEntities(search); err != nil {
+		log.Errorf("cannot count entities: (%v)", err)
+		return nil, fmt.Errorf("cannot count entities")
+	}
+
+	log.Debugf("admin countEntities: %d entities", response.Count)
+	return &response, nil
+}
+
+func (m *Manager) authorizeEntity(request *types.APIrequest) (*types.APIresponse, error) {
+	var response types.APIresponse
+
+	entity, err := m.adminEntity(request.EntityID)
+	if err != nil {
+		return nil, err
+	}
+	if entity.IsAuthorized {
+		return nil, fmt.Errorf("entity already authorized")
+	}
+
+	if err = m.db.AuthorizeEntity(request.EntityID); err != nil {
+		log.Errorf("cannot authorize entity %x: (%v)", request.EntityID, err)
+		return nil, fmt.Errorf("cannot authorize entity")
+	}
+
+	log.Infof("admin authorizeEntity: %x", request.EntityID)
+	return &response, nil
+}
+
+func (m *Manager) adminDeleteEntity(request *types.APIrequest) (*types.APIresponse, error) {
+	var response types.APIresponse
+
+	if _, err := m.adminEntity(request.EntityID); err != nil {
+		return nil, err
+	}
+
+	if err := m.deleteEntityData(request.EntityID); err != nil {
+		log.Errorf("cannot delete entity %x: (%v)", request.EntityID, err)
+		return nil, fmt.Errorf("cannot delete entity")
+	}
+
+	log.Infof("admin deleteEntity: %x", request.EntityID)
+	return &response, nil
+}
+
+func (m *Manager) setQuotas(request *types.APIrequest) (*types.APIresponse, error) {
+	var response types.APIresponse
+
+	if request.Quotas == nil {
+		return nil, fmt.Errorf("invalid quotas")
+	}
+	if err := request.Quotas.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid quotas: %v", err)
+	}
+	if _, err := m.adminEntity(request.EntityID); err != nil {
+		return nil, err
+	}
+
+	if _, err := m.db.SetEntityQuotas(request.EntityID, request.Quotas); err != nil {
+		log.Errorf("cannot set quotas of entity %x: (%v)", request.EntityID, err)
+		return nil, fmt.Errorf("cannot set quotas")
+	}
+
+	log.Infof("admin setQuotas: %x %+v", request.EntityID, *request.Quotas)
+	return &response, nil
+}
+
+// adminEntity retrieves the entity an admin request refers to
+func (m *Manager) adminEntity(entityID []byte) (*types.Entity, error) {
+	if len(entityID) == 0 {
+		return nil, fmt.Errorf("invalid entity id")
+	}
+	entity, err := m.db.Entity(entityID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("entity not found")
+		}
+		log.Errorf("cannot retrieve entity %x: (%v)", entityID, err)
+		return nil, fmt.Errorf("cannot retrieve entity")
+	}
+	return entity, nil
+}
@@ -1,27 +1,42 @@
 package manager
 
 import (
+	"bytes"
+	"database/sql"
 	"fmt"
+	"time"
 
+	ethcommon "github.com/ethereum/go-ethereum/common"
 	"go.vocdoni.io/dvote/crypto/ethereum"
 	"go.vocdoni.io/dvote/httprouter"
 	"go.vocdoni.io/dvote/log"
+	"go.vocdoni.io/manager/census"
 	"go.vocdoni.io/manager/database"
 	"go.vocdoni.io/manager/ethclient"
 	"go.vocdoni.io/manager/rpcapi"
 	"go.vocdoni.io/manager/smtpclient"
+	"go.vocdoni.io/manager/types"
+	"go.vocdoni.io/manager/util"
 )
 
 type Manager struct {
-	api    *rpcapi.RPCAPI
-	signer *ethereum.SignKeys
-	db     database.Database
-	smtp   *smtpclient.SMTP
-	eth    *ethclient.Eth
+	api      *rpcapi.RPCAPI
+	adminAPI *rpcapi.RPCAPI
+	signer   *ethereum.SignKeys
+	db       database.Database
+	smtp     *smtpclient.SMTP
+	eth      *ethclient.Eth
+	// admins are the platform superadmins allowed to call the admin API
+	admins map[ethcommon.Address]bool
+	// deletionWindow is how long after the end of a process an entity with a
+	// census used by it cannot be deleted
+	deletionWindow time.Duration
+	// censusTrees stores the Merkle trees computed for the censuses
+	censusTrees *census.Trees
 }
 
-func NewManager(signer *ethereum.SignKeys, router *httprouter.HTTProuter, route string, db database.Database, smtp *smtpclient.SMTP, eth *ethclient.Eth) (*Manager, error) {
-	if signer == nil || db == nil {
+func NewManager(signer *ethereum.SignKeys, router *httprouter.HTTProuter, route string, db database.Database, smtp *smtpclient.SMTP, eth *ethclient.Eth, admins []ethcommon.Address, deletionWindow time.Duration, censusTrees *census.Trees) (*Manager, error) {
+	if signer == nil || db == nil || censusTrees == nil {
 		return nil, fmt.Errorf("invalid arguments for manager API")
 	}
 
@@ -29,17 +44,29 @@ func NewManager(signer *ethereum.SignKeys, router *httprouter.HTTProuter, route
 	if err != nil {
 		return nil, fmt.Errorf("could not create the manager API: %v", err)
 	}
+	adminAPI, err := rpcapi.NewAPI(signer, router, "admin", route+"/admin", nil, false)
+	if err != nil {
+		return nil, fmt.Errorf("could not create the admin API: %v", err)
+	}
+	adminsMap := make(map[ethcommon.Address]bool, len(admins))
+	for _, admin := range admins {
+		adminsMap[admin] = true
+	}
 	// api := jsonrpcapi.NewSignedJRPC(signer, types.NewApiRequest, types.NewApiResponse, false)
 	// rpcapi.AddNamespace("manager", api)
 	// rpcapi.APIs = append(rpcapi.APIs, "manager")
 	// api.AddAuthorizedAddress(signer.Address())
 	// rpcapi.ManagerAPI = api
 	return &Manager{
-		api:    api,
-		signer: signer,
-		db:     db,
-		smtp:   smtp,
-		eth:    eth,
+		api:            api,
+		adminAPI:       adminAPI,
+		signer:         signer,
+		db:             db,
+		smtp:           smtp,
+		eth:            eth,
+		admins:         adminsMap,
+		deletionWindow: deletionWindow,
+		censusTrees:    censusTrees,
 	}, nil
 }
 
@@ -47,46 +74,192 @@ func (m *Manager) EnableAPI() error {
 	log.Infof("enabling manager API")
 
 	m.api.RegisterPublic("signUp", true, m.signUp)
-	m.api.RegisterPublic("getEntity", true, m.getEntity)
-	m.api.RegisterPublic("updateEntity", true, m.updateEntity)
-	m.api.RegisterPublic("adminEntityList", true, m.adminEntityList)
-	m.api.RegisterPublic("countMembers", true, m.countMembers)
-	m.api.RegisterPublic("listMembers", true, m.listMembers)
-	m.api.RegisterPublic("getMember", true, m.getMember)
-	m.api.RegisterPublic("updateMember", true, m.updateMember)
-	m.api.RegisterPublic("deleteMembers", true, m.deleteMembers)
-	m.api.RegisterPublic("generateTokens", true, m.generateTokens)
-	m.api.RegisterPublic("exportTokens", true, m.exportTokens)
-	m.api.RegisterPublic("importMembers", true, m.importMembers)
-	m.api.RegisterPublic("countTargets", true, m.countTargets)
-	m.api.RegisterPublic("listTargets", true, m.listTargets)
-	m.api.RegisterPublic("getTarget", true, m.getTarget)
-	m.api.RegisterPublic("dumpTarget", true, m.dumpTarget)
-	m.api.RegisterPublic("dumpCensus", true, m.dumpCensus)
-	m.api.RegisterPublic("addCensus", true, m.addCensus)
-	m.api.RegisterPublic("updateCensus", true, m.updateCensus)
-	m.api.RegisterPublic("getCensus", true, m.getCensus)
-	m.api.RegisterPublic("countCensus", true, m.countCensus)
-	m.api.RegisterPublic("listCensus", true, m.listCensus)
-	m.api.RegisterPublic("deleteCensus", true, m.deleteCensus)
-	m.api.RegisterPublic("createTag", true, m.createTag)
-	m.api.RegisterPublic("listTags", true, m.listTags)
-	m.api.RegisterPublic("deleteTag", true, m.deleteTag)
-	m.api.RegisterPublic("addTag", true, m.addTag)
-	m.api.RegisterPublic("removeTag", true, m.removeTag)
+	m.api.RegisterPublic("getEntity", true, m.withRole(types.OperatorViewer, m.getEntity))
+	m.api.RegisterPublic("updateEntity", true, m.withRole(types.OperatorAdmin, m.updateEntity))
+	m.api.RegisterPublic("listOperators", true, m.withRole(types.OperatorViewer, m.listOperators))
+	m.api.RegisterPublic("setOperator", true, m.withRole(types.OperatorAdmin, m.setOperator))
+	m.api.RegisterPublic("removeOperator", true, m.withRole(types.OperatorAdmin, m.removeOperator))
+	m.api.RegisterPublic("deleteEntity", true, m.withEntityKey(m.deleteEntity))
+	m.api.RegisterPublic("getUsage", true, m.withRole(types.OperatorViewer, m.getUsage))
+	m.api.RegisterPublic("adminEntityList", true, m.withAdmin(m.adminEntityList))
+	m.api.RegisterPublic("countMembers", true, m.withRole(types.OperatorViewer, m.countMembers))
+	m.api.RegisterPublic("listMembers", true, m.withRole(types.OperatorViewer, m.listMembers))
+	m.api.RegisterPublic("getMember", true, m.withRole(types.OperatorViewer, m.getMember))
+	m.api.RegisterPublic("getMemberHistory", true, m.withRole(types.OperatorViewer, m.getMemberHistory))
+	m.api.RegisterPublic("updateMember", true, m.withRole(types.OperatorEditor, m.updateMember))
+	m.api.RegisterPublic("deleteMembers", true, m.withRole(types.OperatorEditor, m.deleteMembers))
+	m.api.RegisterPublic("listDeletedMembers", true, m.withRole(types.OperatorViewer, m.listDeletedMembers))
+	m.api.RegisterPublic("restoreMembers", true, m.withRole(types.OperatorEditor, m.restoreMembers))
+	m.api.RegisterPublic("exportMemberData", true, m.withRole(types.OperatorEditor, m.exportMemberData))
+	m.api.RegisterPublic("eraseMember", true, m.withRole(types.OperatorAdmin, m.eraseMember))
+	m.api.RegisterPublic("updateMembers", true, m.withRole(types.OperatorEditor, m.updateMembers))
+	m.api.RegisterPublic("generateTokens", true, m.withRole(types.OperatorEditor, m.generateTokens))
+	m.api.RegisterPublic("exportTokens", true, m.withRole(types.OperatorEditor, m.exportTokens))
+	m.api.RegisterPublic("importMembers", true, m.withRole(types.OperatorEditor, m.importMembers))
+	m.api.RegisterPublic("importMembersCSV", true, m.withRole(types.OperatorEditor, m.importMembersCSV))
+	m.api.RegisterPublic("exportMembers", true, m.withRole(types.OperatorViewer, m.exportMembers))
+	m.api.RegisterPublic("countTargets", true, m.withRole(types.OperatorViewer, m.countTargets))
+	m.api.RegisterPublic("listTargets", true, m.withRole(types.OperatorViewer, m.listTargets))
+	m.api.RegisterPublic("getTarget", true, m.withRole(types.OperatorViewer, m.getTarget))
+	m.api.RegisterPublic("addTarget", true, m.withRole(types.OperatorEditor, m.addTarget))
+	m.api.RegisterPublic("updateTarget", true, m.withRole(types.OperatorEditor, m.updateTarget))
+	m.api.RegisterPublic("deleteTarget", true, m.withRole(types.OperatorEditor, m.deleteTarget))
+	m.api.RegisterPublic("dumpTarget", true, m.withRole(types.OperatorViewer, m.dumpTarget))
+	m.api.RegisterPublic("dumpCensus", true, m.withRole(types.OperatorViewer, m.dumpCensus))
+	m.api.RegisterPublic("addCensus", true, m.withRole(types.OperatorEditor, m.addCensus))
+	m.api.RegisterPublic("updateCensus", true, m.withRole(types.OperatorEditor, m.updateCensus))
+	m.api.RegisterPublic("updateCensusState", true, m.withRole(types.OperatorEditor, m.updateCensusState))
+	m.api.RegisterPublic("getCensus", true, m.withRole(types.OperatorViewer, m.getCensus))
+	m.api.RegisterPublic("countCensus", true, m.withRole(types.OperatorViewer, m.countCensus))
+	m.api.RegisterPublic("listCensus", true, m.withRole(types.OperatorViewer, m.listCensus))
+	m.api.RegisterPublic("deleteCensus", true, m.withRole(types.OperatorEditor, m.deleteCensus))
+	m.api.RegisterPublic("createTag", true, m.withRole(types.OperatorEditor, m.createTag))
+	m.api.RegisterPublic("listTags", true, m.withRole(types.OperatorViewer, m.listTags))
+	m.api.RegisterPublic("updateTag", true, m.withRole(types.OperatorEditor, m.updateTag))
+	m.api.RegisterPublic("deleteTag", true, m.withRole(types.OperatorEditor, m.deleteTag))
+	m.api.RegisterPublic("addTag", true, m.withRole(types.OperatorEditor, m.addTag))
+	m.api.RegisterPublic("removeTag", true, m.withRole(types.OperatorEditor, m.removeTag))
 	if m.eth != nil {
 		// do not expose this endpoint if the manager does not have an ethereum client
-		m.api.RegisterPublic("requestGas", true, m.requestGas)
+		m.api.RegisterPublic("requestGas", true, m.withRole(types.OperatorEditor, m.requireAuthorized(m.requestGas)))
 	} else {
 		log.Warn("No eth connection provided for manager API")
 	}
 
 	if m.smtp != nil {
-		m.api.RegisterPublic("sendValidationLinks", true, m.sendValidationLinks)
-		m.api.RegisterPublic("sendVotingLinks", true, m.sendVotingLinks)
+		m.api.RegisterPublic("sendValidationLinks", true, m.withRole(types.OperatorEditor, m.requireAuthorized(m.sendValidationLinks)))
+		m.api.RegisterPublic("sendVotingLinks", true, m.withRole(types.OperatorEditor, m.requireAuthorized(m.sendVotingLinks)))
 	} else {
 		log.Warn("No smtp server connection provided for manager API")
 	}
 
+	// platform administration
+	m.adminAPI.RegisterPublic("listEntities", true, m.withAdmin(m.listEntities))
+	m.adminAPI.RegisterPublic("countEntities", true, m.withAdmin(m.countEntities))
+	m.adminAPI.RegisterPublic("authorizeEntity", true, m.withAdmin(m.authorizeEntity))
+	m.adminAPI.RegisterPublic("deleteEntity", true, m.withAdmin(m.adminDeleteEntity))
+	m.adminAPI.RegisterPublic("setQuotas", true, m.withAdmin(m.setQuotas))
+
 	return nil
 }
+
+// withRole wraps a handler so that it can be called either by an entity on its
+// own behalf or, giving the entityId of the request, by one of the operators
+// of that entity with at least the given role. The acting entity is set as the
+// request entityId before calling the handler.
+func (m *Manager) withRole(role types.OperatorRole, handler rpcapi.Handler) rpcapi.Handler {
+	return func(request *types.APIrequest) (*types.APIresponse, error) {
+		// check public key length
+		if len(request.SignaturePublicKey) != ethereum.PubKeyLengthBytes {
+			log.Warnf("invalid public key: %x", request.SignaturePublicKey)
+			return nil, fmt.Errorf("invalid public key")
+		}
+		address, err := util.PubKeyToEntityID(request.SignaturePublicKey)
+		if err != nil {
+			log.Errorf("cannot recover %x address: (%v)", request.SignaturePublicKey, err)
+			return nil, fmt.Errorf("cannot recover entityID")
+		}
+		if len(request.EntityID) == 0 || bytes.Equal(request.EntityID, address) {
+			request.EntityID = address
+			return handler(request)
+		}
+		operatorRole, err := m.db.EntityOperatorRole(request.EntityID, address)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				log.Debugf("%x is not an operator of entity %x", address, request.EntityID)
+				return nil, fmt.Errorf("not an operator of the entity")
+			}
+			log.Errorf("cannot retrieve the role of %x in entity %x: (%v)", address, request.EntityID, err)
+			return nil, fmt.Errorf("cannot retrieve operator role")
+		}
+		if !operatorRole.Allows(role) {
+			log.Debugf("operator %x of entity %x with role %s cannot call %s", address, request.EntityID, operatorRole, request.Method)
+			return nil, fmt.Errorf("%s operators cannot call %s", operatorRole, request.Method)
+		}
+		log.Debugf("operator %x acting on behalf of entity %x with role %s", address, request.EntityID, operatorRole)
+		request.OperatorAddress = address
+		return handler(request)
+	}
+}
+
+// withEntityKey wraps a handler so that it can only be called by an entity on
+// its own behalf, and not by its operators. The entity is set as the request
+// entityId before calling the handler.
+func (m *Manager) withEntityKey(handler rpcapi.Handler) rpcapi.Handler {
+	return func(request *types.APIrequest) (*types.APIresponse, error) {
+		// check public key length
+		if len(request.SignaturePublicKey) != ethereum.PubKeyLengthBytes {
+			log.Warnf("invalid public key: %x", request.SignaturePublicKey)
+			return nil, fmt.Errorf("invalid public key")
+		}
+		address, err := util.PubKeyToEntityID(request.SignaturePublicKey)
+		if err != nil {
+			log.Errorf("cannot recover %x address: (%v)", request.SignaturePublicKey, err)
+			return nil, fmt.Errorf("cannot recover entityID")
+		}
+		if len(request.EntityID) > 0 && !bytes.Equal(request.EntityID, address) {
+			log.Debugf("%x cannot call %s on behalf of entity %x", address, request.Method, request.EntityID)
+			return nil, fmt.Errorf("only the entity can call %s", request.Method)
+		}
+		request.EntityID = address
+		return handler(request)
+	}
+}
+
+// withAdmin wraps a handler so that it can only be called by the platform
+// superadmins
+func (m *Manager) withAdmin(handler rpcapi.Handler) rpcapi.Handler {
+	return func(request *types.APIrequest) (*types.APIresponse, error) {
+		// check public key length
+		if len(request.SignaturePublicKey) != ethereum.PubKeyLengthBytes {
+			log.Warnf("invalid public key: %x", request.SignaturePublicKey)
+			return nil, fmt.Errorf("invalid public key")
+		}
+		address, err := ethereum.AddrFromPublicKey(request.SignaturePublicKey)
+		if err != nil {
+			log.Errorf("cannot recover %x address: (%v)", request.SignaturePublicKey, err)
+			return nil, fmt.Errorf("invalid auth")
+		}
+		if !m.admins[address] {
+			log.Warnf("invalid admin auth from %s calling %s", address.String(), request.Method)
+			return nil, fmt.Errorf("invalid auth")
+		}
+		return handler(request)
+	}
+}
+
+// requireAuthorized wraps a handler so that it can only act on behalf of
+// entities that have been authorized by the platform admins. It must be
+// wrapped by withRole, which resolves the acting entity.
+func (m *Manager) requireAuthorized(handler rpcapi.Handler) rpcapi.Handler {
+	return func(request *types.APIrequest) (*types.APIresponse, error) {
+		authorized, err := m.entityAuthorized(request.EntityID)
+		if err != nil {
+			return nil, err
+		}
+		if !authorized {
+			log.Debugf("entity %x pending approval cannot call %s", request.EntityID, request.Method)
+			return nil, fmt.Errorf("entity is pending approval: %s is not available until it is authorized", request.Method)
+		}
+		return handler(request)
+	}
+}
+
+// actingEntityID returns the entity on whose behalf the request is made, as
+// resolved by withRole
+func actingEntityID(request *types.APIrequest) ([]byte, error) {
+	if len(request.EntityID) == 0 {
+		return nil, fmt.Errorf("no acting entity")
+	}
+	return request.EntityID, nil
+}
+
+// actingDB returns the database to change the members of the acting entity,
+// which records the changes as done by the operator that signed the request
+// if any
+func (m *Manager) actingDB(request *types.APIrequest) database.Database {
+	if len(request.OperatorAddress) == 0 {
+		return m.db
+	}
+	return m.db.AsOperator(request.OperatorAddress)
+}
@@ -0,0 +1,151 @@
+package manager
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"go.vocdoni.io/manager/types"
+	"go.vocdoni.io/manager/util"
+)
+
+// csvMemberFields are the MemberInfo fields that CSV columns can be mapped to,
+// identified by their json name
+var csvMemberFields = map[string]func(info *types.MemberInfo, value string) error{
+	"firstName":     func(info *types.MemberInfo, value string) error { info.FirstName = value; return nil },
+	"lastName":      func(info *types.MemberInfo, value string) error { info.LastName = value; return nil },
+	"email":         func(info *types.MemberInfo, value string) error { info.Email = value; return nil },
+	"phone":         func(info *types.MemberInfo, value string) error { info.Phone = value; return nil },
+	"streetAddress": func(info *types.MemberInfo, value string) error { info.StreetAddress = value; return nil },
+	"dateOfBirth": func(info *types.MemberInfo, value string) error {
+		if len(value) == 0 {
+			return nil
+		}
+		for _, layout := range []string{"2006-01-02", time.RFC3339} {
+			if date, err := time.Parse(layout, value); err == nil {
+				info.DateOfBirth = date
+				return nil
+			}
+		}
+		return fmt.Errorf("invalid date of birth %q", value)
+	},
+}
+
+// csvMember is a member parsed from a CSV row
+type csvMember struct {
+	info types.MemberInfo
+	line int
+}
+
+// parseMembersCSV parses the CSV content, whose first row must contain the
+// column names. Columns are assigned to the MemberInfo fields according to
+// mapping and the values of the unmapped columns are stored as custom fields
+// under the column name, converted to the type defined in schema if any.
+// Rows that cannot be imported are returned as rejected, while errors are only
+// returned if the CSV as a whole is invalid.
+func parseMembersCSV(content string, mapping map[string]string, schema types.CustomFieldsSchema) ([]csvMember, []types.RejectedRow, error) {
+	reader := csv.NewReader(strings.NewReader(content))
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot read header: %v", err)
+	}
+	columns := make(map[string]bool, len(header))
+	for i := range header {
+		// the byte order mark added by some spreadsheets is not part of the name
+		header[i] = strings.TrimSpace(strings.TrimPrefix(header[i], "\ufeff"))
+		if len(header[i]) == 0 {
+			return nil, nil, fmt.Errorf("empty column name at position %d", i+1)
+		}
+		if columns[header[i]] {
+			return nil, nil, fmt.Errorf("duplicate column %q", header[i])
+		}
+		columns[header[i]] = true
+	}
+	mapped := make(map[string]bool, len(mapping))
+	for column, field := range mapping {
+		if !columns[column] {
+			return nil, nil, fmt.Errorf("mapped column %q not found", column)
+		}
+		if _, ok := csvMemberFields[field]; !ok {
+			return nil, nil, fmt.Errorf("invalid member field %q", field)
+		}
+		if mapped[field] {
+			return nil, nil, fmt.Errorf("member field %q mapped more than once", field)
+		}
+		mapped[field] = true
+	}
+
+	var members []csvMember
+	var rejected []types.RejectedRow
+	emails := make(map[string]int)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil && !errors.Is(err, csv.ErrFieldCount) {
+			return nil, nil, fmt.Errorf("malformed CSV: %v", err)
+		}
+		// the reader skips blank lines and quoted values may span several
+		// lines, so the line of the row is the one of its first field
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			rejected = append(rejected, types.RejectedRow{Line: line, Reason: "wrong number of columns"})
+			continue
+		}
+
+		var info types.MemberInfo
+		customFields := make(map[string]interface{})
+		var reason string
+		for i, value := range record {
+			value = strings.TrimSpace(value)
+			field, ok := mapping[header[i]]
+			if !ok {
+				if len(value) == 0 {
+					continue
+				}
+				customFields[header[i]] = value
+				if schemaField, ok := schema.Field(header[i]); ok {
+					if customFields[header[i]], err = schemaField.ParseValue(value); err != nil {
+						reason = err.Error()
+						break
+					}
+				}
+				continue
+			}
+			if err := csvMemberFields[field](&info, value); err != nil {
+				reason = err.Error()
+				break
+			}
+		}
+		if len(reason) == 0 && !util.ValidEmail(info.Email) {
+			reason = fmt.Sprintf("invalid email %q", info.Email)
+		}
+		if len(reason) == 0 {
+			if first, found := emails[info.Email]; found {
+				reason = fmt.Sprintf("duplicate email, already found at line %d", first)
+			}
+		}
+		if len(reason) > 0 {
+			rejected = append(rejected, types.RejectedRow{Line: line, Reason: reason})
+			continue
+		}
+		if len(customFields) > 0 {
+			if info.CustomFields, err = json.Marshal(customFields); err != nil {
+				return nil, nil, fmt.Errorf("cannot encode custom fields: %v", err)
+			}
+		}
+		if err := schema.ValidateFields(info.CustomFields); err != nil {
+			rejected = append(rejected, types.RejectedRow{Line: line, Reason: err.Error()})
+			continue
+		}
+		emails[info.Email] = line
+		members = append(members, csvMember{info: info, line: line})
+	}
+	return members, rejected, nil
+}
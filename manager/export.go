@@ -0,0 +1,304 @@
+package manager
+
+import (
+	"archive/zip"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.vocdoni.io/manager/database"
+	"go.vocdoni.io/manager/types"
+)
+
+// Member export formats
+const (
+	ExportFormatCSV   = "csv"
+	ExportFormatJSONL = "jsonl"
+)
+
+// ExportOptions selects the members to be exported and the output format
+type ExportOptions struct {
+	// Format is one of ExportFormatCSV (default) or ExportFormatJSONL
+	Format string
+	// TargetID restricts the export to the members of the target
+	TargetID *uuid.UUID
+	// TagID restricts the export to the members with the tag
+	TagID int32
+}
+
+// exportedMember is the representation of a member in the exports
+type exportedMember struct {
+	ID            uuid.UUID       `json:"id"`
+	FirstName     string          `json:"firstName"`
+	LastName      string          `json:"lastName"`
+	Email         string          `json:"email"`
+	Phone         string          `json:"phone"`
+	StreetAddress string          `json:"streetAddress"`
+	DateOfBirth   string          `json:"dateOfBirth"`
+	Origin        string          `json:"origin"`
+	Consented     bool            `json:"consented"`
+	Verified      string          `json:"verified"`
+	Registered    bool            `json:"registered"`
+	Tags          []string        `json:"tags"`
+	CustomFields  json.RawMessage `json:"customFields"`
+	CreatedAt     string          `json:"createdAt"`
+}
+
+var exportCSVColumns = []string{"id", "firstName", "lastName", "email", "phone", "streetAddress",
+	"dateOfBirth", "origin", "consented", "verified", "registered", "tags", "createdAt"}
+
+func (e *exportedMember) csvRecord() []string {
+	return []string{e.ID.String(), e.FirstName, e.LastName, e.Email, e.Phone, e.StreetAddress,
+		e.DateOfBirth, e.Origin, fmt.Sprint(e.Consented), e.Verified, fmt.Sprint(e.Registered),
+		strings.Join(e.Tags, ";"), e.CreatedAt}
+}
+
+// ExportMembers writes the members of the entity to w, one member per line in
+// JSON Lines or one member per row in CSV. Tags are written by name and members
+// are registered if they have a public key. In CSV the custom fields are
+// flattened into one column per field, named by the dot separated path of the
+// field, which requires going through the members twice.
+// The number of exported members is returned.
+func ExportMembers(db database.Database, w io.Writer, entityID []byte, opts *ExportOptions) (int, error) {
+	if opts == nil {
+		opts = &ExportOptions{}
+	}
+	filters := &types.TargetFilters{}
+	if opts.TargetID != nil {
+		target, err := db.Target(entityID, opts.TargetID)
+		if err != nil {
+			return 0, fmt.Errorf("cannot retrieve target: %w", err)
+		}
+		if filters, err = types.ParseTargetFilters(target.Filters); err != nil {
+			return 0, fmt.Errorf("invalid target filters: %w", err)
+		}
+	}
+	if opts.TagID != 0 {
+		if _, err := db.Tag(entityID, opts.TagID); err != nil {
+			return 0, fmt.Errorf("cannot retrieve tag: %w", err)
+		}
+		if filters.Tags == nil {
+			filters.Tags = &types.TagsFilter{}
+		}
+		filters.Tags.All = append(filters.Tags.All, opts.TagID)
+	}
+	tags, err := db.ListTags(entityID)
+	if err != nil {
+		return 0, fmt.Errorf("cannot retrieve tags: %w", err)
+	}
+	tagNames := make(map[int32]string, len(tags))
+	for _, tag := range tags {
+		tagNames[tag.ID] = tag.Name
+	}
+	export := func(member *types.Member) *exportedMember {
+		e := &exportedMember{
+			ID:            member.ID,
+			FirstName:     member.FirstName,
+			LastName:      member.LastName,
+			Email:         member.Email,
+			Phone:         member.Phone,
+			StreetAddress: member.StreetAddress,
+			DateOfBirth:   exportDate(member.DateOfBirth, "2006-01-02"),
+			Origin:        member.Origin.String(),
+			Consented:     member.Consented,
+			Verified:      exportDate(member.Verified, time.RFC3339),
+			Registered:    len(member.PubKey) > 0,
+			Tags:          []string{},
+			CustomFields:  member.CustomFields,
+			CreatedAt:     exportDate(member.CreatedAt, time.RFC3339),
+		}
+		for _, id := range member.Tags {
+			e.Tags = append(e.Tags, tagNames[id])
+		}
+		if len(e.CustomFields) == 0 {
+			e.CustomFields = json.RawMessage("{}")
+		}
+		return e
+	}
+
+	count := 0
+	switch opts.Format {
+	case ExportFormatJSONL:
+		encoder := json.NewEncoder(w)
+		err = db.StreamMembers(entityID, filters, func(member *types.Member) error {
+			count++
+			return encoder.Encode(export(member))
+		})
+	case ExportFormatCSV, "":
+		// first pass to find out the custom field columns
+		fieldSet := make(map[string]bool)
+		if err = db.StreamMembers(entityID, filters, func(member *types.Member) error {
+			for path := range flattenCustomFields(member.CustomFields) {
+				fieldSet[path] = true
+			}
+			return nil
+		}); err != nil {
+			return 0, err
+		}
+		fields := make([]string, 0, len(fieldSet))
+		for path := range fieldSet {
+			fields = append(fields, path)
+		}
+		sort.Strings(fields)
+		writer := csv.NewWriter(w)
+		header := append([]string{}, exportCSVColumns...)
+		for _, path := range fields {
+			// custom fields cannot shadow the member columns
+			if contains(exportCSVColumns, path) {
+				path = "customFields." + path
+			}
+			header = append(header, path)
+		}
+		if err = writer.Write(header); err != nil {
+			return 0, err
+		}
+		err = db.StreamMembers(entityID, filters, func(member *types.Member) error {
+			count++
+			values := flattenCustomFields(member.CustomFields)
+			record := export(member).csvRecord()
+			for _, path := range fields {
+				record = append(record, values[path])
+			}
+			return writer.Write(record)
+		})
+		writer.Flush()
+		if err == nil {
+			err = writer.Error()
+		}
+	default:
+		return 0, fmt.Errorf("invalid export format %q", opts.Format)
+	}
+	return count, err
+}
+
+// ExportEntity writes to w a zip archive with all the data of the entity: its
+// info (entity.json), its members as in ExportMembers (members.jsonl), and its
+// tags (tags.json), targets (targets.json) and censuses (censuses.json).
+func ExportEntity(db database.Database, w io.Writer, entityID []byte) error {
+	entity, err := db.Entity(entityID)
+	if err != nil {
+		return fmt.Errorf("cannot retrieve entity: %w", err)
+	}
+	tags, err := db.ListTags(entityID)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("cannot retrieve tags: %w", err)
+	}
+	targets, err := db.ListTargets(entityID)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("cannot retrieve targets: %w", err)
+	}
+	censuses, _, err := db.ListCensus(entityID, nil)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("cannot retrieve censuses: %w", err)
+	}
+	// export empty lists as [] instead of null
+	if tags == nil {
+		tags = []types.Tag{}
+	}
+	if targets == nil {
+		targets = []types.Target{}
+	}
+	if censuses == nil {
+		censuses = []types.Census{}
+	}
+
+	archive := zip.NewWriter(w)
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"entity.json", entity},
+		{"tags.json", tags},
+		{"targets.json", targets},
+		{"censuses.json", censuses},
+	}
+	for _, f := range files {
+		file, err := archive.Create(f.name)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		if err = encoder.Encode(f.data); err != nil {
+			return fmt.Errorf("cannot export %s: %w", f.name, err)
+		}
+	}
+	file, err := archive.Create("members.jsonl")
+	if err != nil {
+		return err
+	}
+	if _, err = ExportMembers(db, file, entityID, &ExportOptions{Format: ExportFormatJSONL}); err != nil {
+		return fmt.Errorf("cannot export members: %w", err)
+	}
+	return archive.Close()
+}
+
+// limitedWriter writes to w up to limit bytes, failing once exceeded
+type limitedWriter struct {
+	w        io.Writer
+	limit    int64
+	written  int64
+	exceeded bool
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if l.written+int64(len(p)) > l.limit {
+		l.exceeded = true
+		return 0, fmt.Errorf("more than %d bytes written", l.limit)
+	}
+	n, err := l.w.Write(p)
+	l.written += int64(n)
+	return n, err
+}
+
+// flattenCustomFields returns the values of the custom fields by their
+// dot separated path. Nested objects are flattened while the rest of the
+// values are kept as JSON, except for strings which are unquoted.
+func flattenCustomFields(customFields json.RawMessage) map[string]string {
+	values := make(map[string]string)
+	var fields map[string]interface{}
+	if err := json.Unmarshal(customFields, &fields); err != nil {
+		return values
+	}
+	var flatten func(prefix string, fields map[string]interface{})
+	flatten = func(prefix string, fields map[string]interface{}) {
+		for key, value := range fields {
+			switch v := value.(type) {
+			case map[string]interface{}:
+				flatten(prefix+key+".", v)
+			case string:
+				values[prefix+key] = v
+			case nil:
+				values[prefix+key] = ""
+			default:
+				encoded, _ := json.Marshal(v)
+				values[prefix+key] = string(encoded)
+			}
+		}
+	}
+	flatten("", fields)
+	return values
+}
+
+// exportDate formats the date, leaving unset (zero) dates empty
+func exportDate(date time.Time, layout string) string {
+	if date.IsZero() {
+		return ""
+	}
+	return date.Format(layout)
+}
+
+func contains(list []string, s string) bool {
+	for _, element := range list {
+		if element == s {
+			return true
+		}
+	}
+	return false
+}
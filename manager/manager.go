@@ -1,7 +1,9 @@
 package manager
 
 import (
+	"bytes"
 	"context"
+	"crypto/subtle"
 	"database/sql"
 	"encoding/json"
 	"math/rand"
@@ -9,7 +11,11 @@ import (
 
 	"fmt"
 	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	ethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/google/uuid"
@@ -21,6 +27,24 @@ import (
 	"go.vocdoni.io/manager/util"
 )
 
+// maxSearchLength is the maximum length of a members search
+const maxSearchLength = 128
+
+// deletionTokenTTL is how long the token confirming the deletion of an entity
+// is valid
+const deletionTokenTTL = 15 * time.Minute
+
+// maxEntityArchiveSize is the maximum size of the final export of an entity
+// returned when deleting it
+const maxEntityArchiveSize = 64 << 20
+
+// maxMembersExportSize is the maximum size of the members exports returned by
+// exportMembers, larger exports are streamed by the exportMembers subcommand
+const maxMembersExportSize = maxEntityArchiveSize
+
+// tagColorRegexp matches the hex RGB colors (#RRGGBB) that can be given to tags
+var tagColorRegexp = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
 func (m *Manager) signUp(request *types.APIrequest) (*types.APIresponse, error) {
 	var entityID []byte
 	var entityInfo *types.EntityInfo
@@ -66,6 +90,20 @@ func (m *Manager) signUp(request *types.APIrequest) (*types.APIresponse, error)
 	entityAddress := ethcommon.BytesToAddress(entityID)
 	// do not try to send tokens if ethclient is nil
 	if m.eth != nil {
+		// entities pending approval do not get faucet tokens
+		authorized, err := m.entityAuthorized(entityID)
+		if err != nil {
+			return nil, err
+		}
+		if !authorized {
+			log.Debugf("Entity: %s signUp pending approval", entityAddress.String())
+			return &response, nil
+		}
+		// neither do the ones that exceeded their faucet quota
+		if err := m.checkQuota(entityID, quotaFaucetRequests, 1); err != nil {
+			log.Debugf("Entity: %s signUp not sending tokens: (%v)", entityAddress.String(), err)
+			return &response, nil
+		}
 		// send the default amount of faucet tokens iff wallet balance is zero
 		sent, err := m.eth.SendTokens(context.Background(), entityAddress, 0, 0)
 		if err != nil {
@@ -74,6 +112,8 @@ func (m *Manager) signUp(request *types.APIrequest) (*types.APIresponse, error)
 				return nil, fmt.Errorf("could not send tokens to %s", entityAddress.String())
 			}
 			log.Warnf("signUp not sending tokens to entity %s : %v", entityAddress.String(), err)
+		} else if err := m.db.AddFaucetRequest(entityID); err != nil {
+			log.Errorf("cannot record faucet request of entity %s: (%v)", entityAddress.String(), err)
 		}
 		response.Count = int(sent.Int64())
 	}
@@ -82,6 +122,17 @@ func (m *Manager) signUp(request *types.APIrequest) (*types.APIresponse, error)
 	return &response, nil
 }
 
+// entityAuthorized reports whether the entity has been authorized by the
+// platform admins
+func (m *Manager) entityAuthorized(entityID []byte) (bool, error) {
+	entity, err := m.db.Entity(entityID)
+	if err != nil {
+		log.Errorf("cannot retrieve entity %x: (%v)", entityID, err)
+		return false, fmt.Errorf("cannot retrieve entity")
+	}
+	return entity.IsAuthorized, nil
+}
+
 func (m *Manager) getEntity(request *types.APIrequest) (*types.APIresponse, error) {
 	var entityID []byte
 	var err error
@@ -94,7 +145,7 @@ func (m *Manager) getEntity(request *types.APIrequest) (*types.APIresponse, erro
 	}
 
 	// retrieve entity ID
-	if entityID, err = util.PubKeyToEntityID(request.SignaturePublicKey); err != nil {
+	if entityID, err = actingEntityID(request); err != nil {
 		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
 		return nil, fmt.Errorf("cannot recover entityID")
 	}
@@ -125,7 +176,7 @@ func (m *Manager) updateEntity(request *types.APIrequest) (*types.APIresponse, e
 	}
 
 	// retrieve entity ID
-	if entityID, err = util.PubKeyToEntityID(request.SignaturePublicKey); err != nil {
+	if entityID, err = actingEntityID(request); err != nil {
 		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
 		return nil, fmt.Errorf("cannot recover entityID")
 	}
@@ -148,6 +199,21 @@ func (m *Manager) updateEntity(request *types.APIrequest) (*types.APIresponse, e
 	if len(request.Entity.CallbackSecret) > 0 {
 		entityInfo.CallbackSecret = request.Entity.CallbackSecret
 	}
+	// a nil schema leaves the current one untouched while an empty one removes it
+	if request.Entity.CustomFieldsSchema != nil {
+		if err = request.Entity.CustomFieldsSchema.Validate(); err != nil {
+			log.Debugf("invalid custom fields schema for %x: (%v)", entityID, err)
+			return nil, fmt.Errorf("invalid custom fields schema: %v", err)
+		}
+		entityInfo.CustomFieldsSchema = request.Entity.CustomFieldsSchema
+	}
+	if request.Entity.Retention != nil {
+		if err = request.Entity.Retention.Validate(); err != nil {
+			log.Debugf("invalid retention settings for %x: (%v)", entityID, err)
+			return nil, fmt.Errorf("invalid retention settings: %v", err)
+		}
+		entityInfo.Retention = request.Entity.Retention
+	}
 
 	// Add Entity
 	if response.Count, err = m.db.UpdateEntity(entityID, entityInfo); err != nil {
@@ -159,6 +225,193 @@ func (m *Manager) updateEntity(request *types.APIrequest) (*types.APIresponse, e
 	return &response, nil
 }
 
+func (m *Manager) listOperators(request *types.APIrequest) (*types.APIresponse, error) {
+	var entityID []byte
+	var err error
+	var response types.APIresponse
+
+	// retrieve entity ID
+	if entityID, err = actingEntityID(request); err != nil {
+		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
+		return nil, fmt.Errorf("cannot recover entityID")
+	}
+
+	if response.Operators, err = m.db.EntityOperators(entityID); err != nil {
+		log.Errorf("cannot retrieve operators of entity %x: (%v)", entityID, err)
+		return nil, fmt.Errorf("cannot retrieve operators")
+	}
+
+	log.Debugf("Entity: %x listOperators: %d operators", entityID, len(response.Operators))
+	return &response, nil
+}
+
+func (m *Manager) setOperator(request *types.APIrequest) (*types.APIresponse, error) {
+	var entityID []byte
+	var err error
+	var response types.APIresponse
+
+	if request.Operator == nil || len(request.Operator.Address) != ethcommon.AddressLength {
+		log.Debugf("invalid operator address for %x", request.SignaturePublicKey)
+		return nil, fmt.Errorf("invalid operator address")
+	}
+	if !request.Operator.Role.Valid() {
+		log.Debugf("invalid operator role %q for %x", request.Operator.Role, request.SignaturePublicKey)
+		return nil, fmt.Errorf("invalid operator role")
+	}
+
+	// retrieve entity ID
+	if entityID, err = actingEntityID(request); err != nil {
+		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
+		return nil, fmt.Errorf("cannot recover entityID")
+	}
+
+	if err = m.db.SetEntityOperator(entityID, request.Operator.Address, request.Operator.Role); err != nil {
+		log.Errorf("cannot set operator %x of entity %x: (%v)", request.Operator.Address, entityID, err)
+		return nil, fmt.Errorf("cannot set operator")
+	}
+
+	log.Debugf("Entity: %x setOperator: %x as %s", entityID, request.Operator.Address, request.Operator.Role)
+	return &response, nil
+}
+
+func (m *Manager) removeOperator(request *types.APIrequest) (*types.APIresponse, error) {
+	var entityID []byte
+	var err error
+	var response types.APIresponse
+
+	if request.Operator == nil || len(request.Operator.Address) != ethcommon.AddressLength {
+		log.Debugf("invalid operator address for %x", request.SignaturePublicKey)
+		return nil, fmt.Errorf("invalid operator address")
+	}
+
+	// retrieve entity ID
+	if entityID, err = actingEntityID(request); err != nil {
+		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
+		return nil, fmt.Errorf("cannot recover entityID")
+	}
+
+	if response.Count, err = m.db.RemoveEntityOperator(entityID, request.Operator.Address); err != nil {
+		log.Errorf("cannot remove operator %x of entity %x: (%v)", request.Operator.Address, entityID, err)
+		return nil, fmt.Errorf("cannot remove operator")
+	}
+	if response.Count == 0 {
+		return nil, fmt.Errorf("operator not found")
+	}
+
+	log.Debugf("Entity: %x removeOperator: %x", entityID, request.Operator.Address)
+	return &response, nil
+}
+
+// deleteEntity deletes the entity and all its data in two steps: a first call
+// returns a short lived confirmation token, and a second call with it returns
+// a final export archive of the entity and deletes it
+func (m *Manager) deleteEntity(request *types.APIrequest) (*types.APIresponse, error) {
+	var entityID []byte
+	var err error
+	var response types.APIresponse
+
+	// retrieve entity ID
+	if entityID, err = actingEntityID(request); err != nil {
+		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
+		return nil, fmt.Errorf("cannot recover entityID")
+	}
+
+	active, err := m.db.CountActiveProcessCensuses(entityID, time.Now().Add(-m.deletionWindow))
+	if err != nil {
+		log.Errorf("cannot count the censuses with active processes of entity %x: (%v)", entityID, err)
+		return nil, fmt.Errorf("cannot delete entity")
+	}
+	if active > 0 {
+		log.Debugf("entity %x with %d censuses of active processes cannot be deleted", entityID, active)
+		return nil, fmt.Errorf("cannot delete an entity with censuses used by processes that ended less than %s ago", m.deletionWindow)
+	}
+
+	if len(request.ConfirmationToken) == 0 {
+		if response.ConfirmationToken, err = m.deletionToken(entityID, time.Now().Add(deletionTokenTTL)); err != nil {
+			log.Errorf("cannot create deletion token for entity %x: (%v)", entityID, err)
+			return nil, fmt.Errorf("cannot create confirmation token")
+		}
+		log.Debugf("Entity: %x deleteEntity: confirmation token issued", entityID)
+		return &response, nil
+	}
+	if err = m.checkDeletionToken(entityID, request.ConfirmationToken); err != nil {
+		log.Debugf("invalid deletion token for entity %x: (%v)", entityID, err)
+		return nil, err
+	}
+
+	// the archive is returned inline, so its size is limited
+	var archive bytes.Buffer
+	writer := &limitedWriter{w: &archive, limit: maxEntityArchiveSize}
+	if err = ExportEntity(m.db, writer, entityID); err != nil {
+		if writer.exceeded {
+			log.Warnf("cannot export entity %x before deleting it: archive larger than %d bytes", entityID, maxEntityArchiveSize)
+			return nil, fmt.Errorf("the final export of the entity exceeds %d MB: export and delete its members before deleting it", maxEntityArchiveSize>>20)
+		}
+		log.Errorf("cannot export entity %x before deleting it: (%v)", entityID, err)
+		return nil, fmt.Errorf("cannot export entity")
+	}
+	if err = m.deleteEntityData(entityID); err != nil {
+		log.Errorf("cannot delete entity %x: (%v)", entityID, err)
+		return nil, fmt.Errorf("cannot delete entity")
+	}
+	response.Archive = archive.Bytes()
+
+	log.Infof("Entity: %x deleteEntity: deleted by %x", entityID, request.SignaturePublicKey)
+	return &response, nil
+}
+
+// deleteEntityData deletes the entity from the database along with the stored
+// trees of its censuses
+func (m *Manager) deleteEntityData(entityID []byte) error {
+	censuses, _, err := m.db.ListCensus(entityID, nil)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("cannot list censuses: %w", err)
+	}
+	if err := m.db.DeleteEntity(entityID); err != nil {
+		return err
+	}
+	for _, census := range censuses {
+		if err := m.censusTrees.Delete(census.ID); err != nil {
+			log.Errorf("cannot delete tree of census %x of entity %x: (%v)", census.ID, entityID, err)
+		}
+	}
+	return nil
+}
+
+// deletionToken returns the token confirming the deletion of the entity until
+// expiry, which is the expiry followed by the signature of the manager
+func (m *Manager) deletionToken(entityID []byte, expiry time.Time) (string, error) {
+	message := fmt.Sprintf("deleteEntity %x %d", entityID, expiry.Unix())
+	signature, err := m.signer.SignEthereum([]byte(message))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d.%x", expiry.Unix(), signature), nil
+}
+
+// checkDeletionToken checks that the token confirms the deletion of the
+// entity and has not expired
+func (m *Manager) checkDeletionToken(entityID []byte, token string) error {
+	parts := strings.SplitN(token, ".", 2)
+	unix, err := strconv.ParseInt(parts[0], 10, 64)
+	if len(parts) != 2 || err != nil {
+		return fmt.Errorf("invalid confirmation token")
+	}
+	expiry := time.Unix(unix, 0)
+	expected, err := m.deletionToken(entityID, expiry)
+	if err != nil {
+		log.Errorf("cannot create deletion token for entity %x: (%v)", entityID, err)
+		return fmt.Errorf("cannot check confirmation token")
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+		return fmt.Errorf("invalid confirmation token")
+	}
+	if time.Now().After(expiry) {
+		return fmt.Errorf("confirmation token expired")
+	}
+	return nil
+}
+
 func (m *Manager) listMembers(request *types.APIrequest) (*types.APIresponse, error) {
 	var entityID []byte
 	var err error
@@ -171,7 +424,7 @@ func (m *Manager) listMembers(request *types.APIrequest) (*types.APIresponse, er
 	}
 
 	// retrieve entity ID
-	if entityID, err = util.PubKeyToEntityID(request.SignaturePublicKey); err != nil {
+	if entityID, err = actingEntityID(request); err != nil {
 		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
 		return nil, fmt.Errorf("cannot recover entityID")
 	}
@@ -183,7 +436,7 @@ func (m *Manager) listMembers(request *types.APIrequest) (*types.APIresponse, er
 	}
 
 	// Query for members
-	if response.Members, err = m.db.ListMembers(entityID, request.ListOptions); err != nil {
+	if response.Members, response.NextCursor, err = m.db.ListMembers(entityID, request.ListOptions); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("no members found")
 		}
@@ -191,6 +444,12 @@ func (m *Manager) listMembers(request *types.APIrequest) (*types.APIresponse, er
 		return nil, fmt.Errorf("cannot retrieve members")
 	}
 
+	// the schema allows rendering the custom fields of the members
+	if response.CustomFieldsSchema, err = m.db.EntityCustomFieldsSchema(entityID); err != nil {
+		log.Errorf("cannot retrieve custom fields schema of %x: (%v)", request.SignaturePublicKey, err)
+		return nil, fmt.Errorf("cannot retrieve custom fields schema")
+	}
+
 	log.Debugf("Entity: %x listMembers %d members", request.SignaturePublicKey, len(response.Members))
 	return &response, nil
 }
@@ -212,7 +471,7 @@ func (m *Manager) getMember(request *types.APIrequest) (*types.APIresponse, erro
 	}
 
 	// retrieve entity ID
-	if entityID, err = util.PubKeyToEntityID(request.SignaturePublicKey); err != nil {
+	if entityID, err = actingEntityID(request); err != nil {
 		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
 		return nil, fmt.Errorf("cannot recover entityID")
 	}
@@ -243,6 +502,117 @@ func (m *Manager) getMember(request *types.APIrequest) (*types.APIresponse, erro
 	return &response, nil
 }
 
+// getMemberHistory returns the recorded changes of a member, which are kept
+// even if the member has been deleted
+func (m *Manager) getMemberHistory(request *types.APIrequest) (*types.APIresponse, error) {
+	var entityID []byte
+	var err error
+	var response types.APIresponse
+
+	if request.MemberID == nil {
+		log.Warnf("memberID is nil on getMemberHistory")
+		return nil, fmt.Errorf("invalid memberId")
+	}
+
+	// check public key length
+	if len(request.SignaturePublicKey) != ethereum.PubKeyLengthBytes {
+		log.Warnf("invalid public key: %x", request.SignaturePublicKey)
+		return nil, fmt.Errorf("invalid public key")
+	}
+
+	// retrieve entity ID
+	if entityID, err = actingEntityID(request); err != nil {
+		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
+		return nil, fmt.Errorf("cannot recover entityID")
+	}
+
+	if response.Events, err = m.db.MemberEvents(entityID, request.MemberID); err != nil {
+		log.Errorf("cannot retrieve history of member %q for entity %x: (%v)", request.MemberID.String(), request.SignaturePublicKey, err)
+		return nil, fmt.Errorf("cannot retrieve member history")
+	}
+	if len(response.Events) == 0 {
+		log.Warn("member history not found")
+		return nil, fmt.Errorf("member not found")
+	}
+
+	log.Debugf("Entity: %x getMemberHistory: %q %d events", entityID, request.MemberID.String(), len(response.Events))
+	return &response, nil
+}
+
+// exportMemberData returns, as a single document, everything stored about a
+// member in order to answer a data subject access request
+func (m *Manager) exportMemberData(request *types.APIrequest) (*types.APIresponse, error) {
+	var entityID []byte
+	var err error
+	var response types.APIresponse
+
+	if request.MemberID == nil {
+		log.Warnf("memberID is nil on exportMemberData")
+		return nil, fmt.Errorf("invalid memberId")
+	}
+
+	// check public key length
+	if len(request.SignaturePublicKey) != ethereum.PubKeyLengthBytes {
+		log.Warnf("invalid public key: %x", request.SignaturePublicKey)
+		return nil, fmt.Errorf("invalid public key")
+	}
+
+	// retrieve entity ID
+	if entityID, err = actingEntityID(request); err != nil {
+		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
+		return nil, fmt.Errorf("cannot recover entityID")
+	}
+
+	if response.MemberData, err = m.actingDB(request).ExportMemberData(entityID, request.MemberID); err != nil {
+		if err == sql.ErrNoRows {
+			log.Warn("member not found")
+			return nil, fmt.Errorf("member not found")
+		}
+		log.Errorf("cannot export data of member %q for entity %x: (%v)", request.MemberID.String(), entityID, err)
+		return nil, fmt.Errorf("cannot export member data")
+	}
+
+	log.Infof("Entity: %x exportMemberData: %q", entityID, request.MemberID.String())
+	return &response, nil
+}
+
+// eraseMember anonymizes a member in order to answer a data subject erasure
+// request, keeping the census rows it is part of
+func (m *Manager) eraseMember(request *types.APIrequest) (*types.APIresponse, error) {
+	var entityID []byte
+	var err error
+	var response types.APIresponse
+
+	if request.MemberID == nil {
+		log.Warnf("memberID is nil on eraseMember")
+		return nil, fmt.Errorf("invalid memberId")
+	}
+
+	// check public key length
+	if len(request.SignaturePublicKey) != ethereum.PubKeyLengthBytes {
+		log.Warnf("invalid public key: %x", request.SignaturePublicKey)
+		return nil, fmt.Errorf("invalid public key")
+	}
+
+	// retrieve entity ID
+	if entityID, err = actingEntityID(request); err != nil {
+		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
+		return nil, fmt.Errorf("cannot recover entityID")
+	}
+
+	if err = m.actingDB(request).EraseMember(entityID, request.MemberID); err != nil {
+		if err == sql.ErrNoRows {
+			log.Warn("member not found")
+			return nil, fmt.Errorf("member not found")
+		}
+		log.Errorf("cannot erase member %q for entity %x: (%v)", request.MemberID.String(), entityID, err)
+		return nil, fmt.Errorf("cannot erase member")
+	}
+
+	log.Infof("Entity: %x eraseMember: %q", entityID, request.MemberID.String())
+	return &response, nil
+}
+
 func (m *Manager) updateMember(request *types.APIrequest) (*types.APIresponse, error) {
 	var entityID []byte
 	var err error
@@ -258,13 +628,21 @@ func (m *Manager) updateMember(request *types.APIrequest) (*types.APIresponse, e
 	}
 
 	// retrieve entity ID
-	if entityID, err = util.PubKeyToEntityID(request.SignaturePublicKey); err != nil {
+	if entityID, err = actingEntityID(request); err != nil {
 		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
 		return nil, fmt.Errorf("cannot recover entityID")
 	}
 
+	// Custom fields are replaced as a whole, so they are validated as such
+	if len(request.Member.CustomFields) > 0 {
+		if err = m.checkCustomFields(entityID, request.Member.CustomFields); err != nil {
+			log.Debugf("invalid custom fields for member %q of %x: (%v)", request.Member.ID.String(), request.SignaturePublicKey, err)
+			return nil, err
+		}
+	}
+
 	// If a string Member property is sent as "" then it is not updated
-	if response.Count, err = m.db.UpdateMember(entityID, &request.Member.ID, &request.Member.MemberInfo); err != nil {
+	if response.Count, err = m.actingDB(request).UpdateMember(entityID, &request.Member.ID, &request.Member.MemberInfo); err != nil {
 		log.Errorf("cannot update member %q for entity %x: (%v)", request.Member.ID.String(), request.SignaturePublicKey, err)
 		return nil, fmt.Errorf("cannot update member")
 	}
@@ -288,12 +666,12 @@ func (m *Manager) deleteMembers(request *types.APIrequest) (*types.APIresponse,
 	}
 
 	// retrieve entity ID
-	if entityID, err = util.PubKeyToEntityID(request.SignaturePublicKey); err != nil {
+	if entityID, err = actingEntityID(request); err != nil {
 		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
 		return nil, fmt.Errorf("cannot recover entityID")
 	}
 
-	response.Count, response.InvalidIDs, err = m.db.DeleteMembers(entityID, request.MemberIDs)
+	response.Count, response.InvalidIDs, err = m.actingDB(request).DeleteMembers(entityID, request.MemberIDs)
 	if err != nil {
 		log.Errorf("error deleting members for entity %x: (%v)", entityID, err)
 		return nil, fmt.Errorf("error deleting members")
@@ -303,6 +681,179 @@ func (m *Manager) deleteMembers(request *types.APIrequest) (*types.APIresponse,
 	return &response, nil
 }
 
+// listDeletedMembers lists the deleted members that can still be restored
+func (m *Manager) listDeletedMembers(request *types.APIrequest) (*types.APIresponse, error) {
+	var entityID []byte
+	var err error
+	var response types.APIresponse
+
+	// check public key length
+	if len(request.SignaturePublicKey) != ethereum.PubKeyLengthBytes {
+		log.Warnf("invalid public key: %x", request.SignaturePublicKey)
+		return nil, fmt.Errorf("invalid public key")
+	}
+
+	// retrieve entity ID
+	if entityID, err = actingEntityID(request); err != nil {
+		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
+		return nil, fmt.Errorf("cannot recover entityID")
+	}
+
+	// check filter
+	if err = checkOptions(request.ListOptions, request.Method); err != nil {
+		log.Warnf("invalid filter options %x: (%v)", request.SignaturePublicKey, err)
+		return nil, fmt.Errorf("invalid filter options")
+	}
+
+	if response.Members, response.NextCursor, err = m.db.ListDeletedMembers(entityID, request.ListOptions); err != nil {
+		log.Errorf("cannot retrieve deleted members of %x: (%v)", request.SignaturePublicKey, err)
+		return nil, fmt.Errorf("cannot retrieve deleted members")
+	}
+
+	log.Debugf("Entity: %x listDeletedMembers %d members", request.SignaturePublicKey, len(response.Members))
+	return &response, nil
+}
+
+// restoreMembers undoes the deletion of members that have not been purged
+func (m *Manager) restoreMembers(request *types.APIrequest) (*types.APIresponse, error) {
+	var entityID []byte
+	var err error
+	var response types.APIresponse
+
+	if len(request.MemberIDs) == 0 {
+		return nil, fmt.Errorf("invalid member list")
+	}
+
+	// check public key length
+	if len(request.SignaturePublicKey) != ethereum.PubKeyLengthBytes {
+		return nil, fmt.Errorf("invalid public key")
+	}
+
+	// retrieve entity ID
+	if entityID, err = actingEntityID(request); err != nil {
+		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
+		return nil, fmt.Errorf("cannot recover entityID")
+	}
+
+	if err = m.checkQuota(entityID, quotaMembers, len(request.MemberIDs)); err != nil {
+		return nil, err
+	}
+
+	response.Count, response.InvalidIDs, err = m.actingDB(request).RestoreMembers(entityID, request.MemberIDs)
+	if err != nil {
+		log.Errorf("error restoring members for entity %x: (%v)", entityID, err)
+		return nil, fmt.Errorf("error restoring members")
+	}
+
+	log.Infof("restored %d members, found %d invalid tokens, for Entity with public Key %x", response.Count, len(response.InvalidIDs), entityID)
+	return &response, nil
+}
+
+// updateMembers applies a partial update to the given members, or to all the
+// members of a target or matching a filter, in a single transaction
+func (m *Manager) updateMembers(request *types.APIrequest) (*types.APIresponse, error) {
+	var entityID []byte
+	var err error
+	var response types.APIresponse
+
+	if request.MemberPatch == nil || request.MemberPatch.IsEmpty() {
+		return nil, fmt.Errorf("invalid member patch")
+	}
+	if !oneMemberSelection(request) {
+		return nil, fmt.Errorf("either a member list, a target or a filter is required")
+	}
+
+	// check public key length
+	if len(request.SignaturePublicKey) != ethereum.PubKeyLengthBytes {
+		return nil, fmt.Errorf("invalid public key")
+	}
+
+	// retrieve entity ID
+	if entityID, err = actingEntityID(request); err != nil {
+		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
+		return nil, fmt.Errorf("cannot recover entityID")
+	}
+
+	if len(request.MemberPatch.CustomFields) > 0 {
+		schema, err := m.db.EntityCustomFieldsSchema(entityID)
+		if err != nil {
+			log.Errorf("cannot retrieve custom fields schema of %x: (%v)", entityID, err)
+			return nil, fmt.Errorf("cannot retrieve custom fields schema")
+		}
+		if err := schema.ValidatePatch(request.MemberPatch.CustomFields); err != nil {
+			return nil, fmt.Errorf("invalid custom fields: %v", err)
+		}
+	}
+
+	if len(request.MemberIDs) > 0 {
+		response.Count, response.InvalidIDs, err = m.actingDB(request).UpdateMembers(entityID, request.MemberIDs, request.MemberPatch)
+		if err != nil {
+			log.Errorf("error updating members for entity %x: (%v)", entityID, err)
+			return nil, fmt.Errorf("error updating members")
+		}
+		log.Infof("updated %d members, found %d invalid tokens, for Entity with public Key %x", response.Count, len(response.InvalidIDs), entityID)
+		return &response, nil
+	}
+
+	filters, err := m.memberFilters(entityID, request)
+	if err != nil {
+		return nil, err
+	}
+	if response.Count, err = m.actingDB(request).UpdateMembersByFilters(entityID, filters, request.MemberPatch); err != nil {
+		log.Errorf("error updating members by filters for entity %x: (%v)", entityID, err)
+		return nil, fmt.Errorf("error updating members")
+	}
+
+	log.Infof("updated %d members by filters for Entity with public Key %x", response.Count, entityID)
+	return &response, nil
+}
+
+// oneMemberSelection returns true if the request selects the members either
+// by memberIds, targetId or filter, but not by more than one of them
+func oneMemberSelection(request *types.APIrequest) bool {
+	selections := 0
+	if len(request.MemberIDs) > 0 {
+		selections++
+	}
+	if request.TargetID != nil {
+		selections++
+	}
+	if request.Filter != nil {
+		selections++
+	}
+	return selections == 1
+}
+
+// memberFilters returns the filters of the target targetId of the request or,
+// if no target is given, its inline filter. The errors are meant for the caller.
+func (m *Manager) memberFilters(entityID []byte, request *types.APIrequest) (*types.TargetFilters, error) {
+	if request.TargetID == nil {
+		if request.Filter == nil || len(request.Filter.Filters) == 0 {
+			return nil, fmt.Errorf("invalid filter")
+		}
+		if err := m.checkTargetFilters(entityID, request.Filter.Filters); err != nil {
+			log.Debugf("invalid filter for %x: (%v)", entityID, err)
+			return nil, fmt.Errorf("invalid filter: %v", err)
+		}
+		return types.ParseTargetFilters(request.Filter.Filters)
+	}
+	target, err := m.db.Target(entityID, request.TargetID)
+	if err == sql.ErrNoRows || (err == nil && target == nil) {
+		log.Debugf("target %q not found for %x", request.TargetID.String(), entityID)
+		return nil, fmt.Errorf("target not found")
+	}
+	if err != nil {
+		log.Errorf("could not retrieve target for %x: (%v)", entityID, err)
+		return nil, fmt.Errorf("could not retrieve target")
+	}
+	filters, err := types.ParseTargetFilters(target.Filters)
+	if err != nil {
+		log.Errorf("invalid filters in target %q of %x: (%v)", request.TargetID.String(), entityID, err)
+		return nil, fmt.Errorf("invalid target filters")
+	}
+	return filters, nil
+}
+
 func (m *Manager) countMembers(request *types.APIrequest) (*types.APIresponse, error) {
 	var entityID []byte
 	var err error
@@ -314,13 +865,22 @@ func (m *Manager) countMembers(request *types.APIrequest) (*types.APIresponse, e
 	}
 
 	// retrieve entity ID
-	if entityID, err = util.PubKeyToEntityID(request.SignaturePublicKey); err != nil {
+	if entityID, err = actingEntityID(request); err != nil {
 		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
 		return nil, fmt.Errorf("cannot recover entityID")
 	}
 
+	var search string
+	if request.ListOptions != nil {
+		if err = checkOptions(request.ListOptions, request.Method); err != nil {
+			log.Warnf("invalid filter options %x: (%v)", request.SignaturePublicKey, err)
+			return nil, fmt.Errorf("invalid filter options")
+		}
+		search = request.ListOptions.Search
+	}
+
 	// Query for members
-	if response.Count, err = m.db.CountMembers(entityID); err != nil {
+	if response.Count, err = m.db.CountMembers(entityID, search); err != nil {
 		log.Errorf("cannot count members for %x: (%v)", request.SignaturePublicKey, err)
 		return nil, fmt.Errorf("cannot count members")
 	}
@@ -341,7 +901,7 @@ func (m *Manager) generateTokens(request *types.APIrequest) (*types.APIresponse,
 	}
 
 	// retrieve entity ID
-	if entityID, err = util.PubKeyToEntityID(request.SignaturePublicKey); err != nil {
+	if entityID, err = actingEntityID(request); err != nil {
 		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
 		return nil, fmt.Errorf("cannot recover entityID")
 	}
@@ -351,12 +911,16 @@ func (m *Manager) generateTokens(request *types.APIrequest) (*types.APIresponse,
 		return nil, fmt.Errorf("invalid token amount")
 	}
 
+	if err = m.checkQuota(entityID, quotaMembers, request.Amount); err != nil {
+		return nil, err
+	}
+
 	response.Tokens = make([]uuid.UUID, request.Amount)
 	for idx := range response.Tokens {
 		response.Tokens[idx] = uuid.New()
 	}
 	// TODO: Probably I need to initialize tokens
-	if err = m.db.CreateMembersWithTokens(entityID, response.Tokens); err != nil {
+	if err = m.actingDB(request).CreateMembersWithTokens(entityID, response.Tokens); err != nil {
 		log.Errorf("could not register generated tokens for %x: (%v)", request.SignaturePublicKey, err)
 		return nil, fmt.Errorf("could not register generated tokens")
 	}
@@ -378,7 +942,7 @@ func (m *Manager) exportTokens(request *types.APIrequest) (*types.APIresponse, e
 	}
 
 	// retrieve entity ID
-	if entityID, err = util.PubKeyToEntityID(request.SignaturePublicKey); err != nil {
+	if entityID, err = actingEntityID(request); err != nil {
 		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
 		return nil, fmt.Errorf("cannot recover entityID")
 	}
@@ -412,7 +976,7 @@ func (m *Manager) importMembers(request *types.APIrequest) (*types.APIresponse,
 	}
 
 	// retrieve entity ID
-	if entityID, err = util.PubKeyToEntityID(request.SignaturePublicKey); err != nil {
+	if entityID, err = actingEntityID(request); err != nil {
 		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
 		return nil, fmt.Errorf("cannot recover entityID")
 	}
@@ -422,12 +986,25 @@ func (m *Manager) importMembers(request *types.APIrequest) (*types.APIresponse,
 		return nil, fmt.Errorf("no member data provided")
 	}
 
+	schema, err := m.db.EntityCustomFieldsSchema(entityID)
+	if err != nil {
+		log.Errorf("cannot retrieve custom fields schema of %x: (%v)", request.SignaturePublicKey, err)
+		return nil, fmt.Errorf("could not import members")
+	}
 	for idx := range request.MembersInfo {
+		if err = schema.ValidateFields(request.MembersInfo[idx].CustomFields); err != nil {
+			log.Debugf("invalid custom fields for member %d imported by %x: (%v)", idx, request.SignaturePublicKey, err)
+			return nil, fmt.Errorf("invalid custom fields of member %d: %v", idx, err)
+		}
 		request.MembersInfo[idx].Origin = types.Token
 	}
 
+	if err = m.checkQuota(entityID, quotaMembers, len(request.MembersInfo)); err != nil {
+		return nil, err
+	}
+
 	// Add members
-	if err = m.db.ImportMembers(entityID, request.MembersInfo); err != nil {
+	if err = m.actingDB(request).ImportMembers(entityID, request.MembersInfo); err != nil {
 		log.Errorf("could not import members for %x: (%v)", request.SignaturePublicKey, err)
 		return nil, fmt.Errorf("could not import members")
 	}
@@ -436,6 +1013,149 @@ func (m *Manager) importMembers(request *types.APIrequest) (*types.APIresponse,
 	return &response, nil
 }
 
+func (m *Manager) importMembersCSV(request *types.APIrequest) (*types.APIresponse, error) {
+	var entityID []byte
+	var err error
+	var response types.APIresponse
+
+	// check public key length
+	if len(request.SignaturePublicKey) != ethereum.PubKeyLengthBytes {
+		log.Warnf("invalid public key: %x", request.SignaturePublicKey)
+		return nil, fmt.Errorf("invalid public key")
+	}
+
+	// retrieve entity ID
+	if entityID, err = actingEntityID(request); err != nil {
+		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
+		return nil, fmt.Errorf("cannot recover entityID")
+	}
+
+	if len(request.CSV) == 0 {
+		log.Warnf("no CSV content provided for import members by %x", request.SignaturePublicKey)
+		return nil, fmt.Errorf("no CSV content provided")
+	}
+
+	schema, err := m.db.EntityCustomFieldsSchema(entityID)
+	if err != nil {
+		log.Errorf("cannot retrieve custom fields schema of %x: (%v)", request.SignaturePublicKey, err)
+		return nil, fmt.Errorf("could not import members")
+	}
+
+	members, rejected, err := parseMembersCSV(request.CSV, request.ColumnMapping, schema)
+	if err != nil {
+		log.Debugf("invalid CSV provided by %x: (%v)", request.SignaturePublicKey, err)
+		return nil, fmt.Errorf("invalid CSV: %v", err)
+	}
+
+	// emails must be unique per entity
+	emails := make([]string, len(members))
+	for i, member := range members {
+		emails[i] = member.info.Email
+	}
+	existing, err := m.db.ExistingMemberEmails(entityID, emails)
+	if err != nil {
+		log.Errorf("cannot check existing emails for %x: (%v)", request.SignaturePublicKey, err)
+		return nil, fmt.Errorf("could not import members")
+	}
+	existingEmails := make(map[string]bool, len(existing))
+	for _, email := range existing {
+		existingEmails[email] = true
+	}
+	var membersInfo []types.MemberInfo
+	for _, member := range members {
+		if existingEmails[member.info.Email] {
+			rejected = append(rejected, types.RejectedRow{Line: member.line, Reason: "email already registered"})
+			continue
+		}
+		member.info.Origin = types.Token
+		membersInfo = append(membersInfo, member.info)
+	}
+	sort.Slice(rejected, func(i, j int) bool { return rejected[i].Line < rejected[j].Line })
+	response.RejectedRows = rejected
+
+	if len(membersInfo) > 0 {
+		if err = m.checkQuota(entityID, quotaMembers, len(membersInfo)); err != nil {
+			return nil, err
+		}
+		if err = m.actingDB(request).ImportMembers(entityID, membersInfo); err != nil {
+			log.Errorf("could not import members for %x: (%v)", request.SignaturePublicKey, err)
+			return nil, fmt.Errorf("could not import members")
+		}
+	}
+	response.Count = len(membersInfo)
+
+	log.Debugf("Entity: %x importMembersCSV: %d members, %d rejected rows", request.SignaturePublicKey, response.Count, len(rejected))
+	return &response, nil
+}
+
+func (m *Manager) exportMembers(request *types.APIrequest) (*types.APIresponse, error) {
+	var entityID []byte
+	var err error
+	var response types.APIresponse
+
+	// check public key length
+	if len(request.SignaturePublicKey) != ethereum.PubKeyLengthBytes {
+		log.Warnf("invalid public key: %x", request.SignaturePublicKey)
+		return nil, fmt.Errorf("invalid public key")
+	}
+
+	// retrieve entity ID
+	if entityID, err = actingEntityID(request); err != nil {
+		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
+		return nil, fmt.Errorf("cannot recover entityID")
+	}
+
+	format := request.Format
+	if len(format) == 0 {
+		format = ExportFormatCSV
+	}
+	if format != ExportFormatCSV && format != ExportFormatJSONL {
+		log.Debugf("invalid export format %q requested by %x", request.Format, request.SignaturePublicKey)
+		return nil, fmt.Errorf("invalid export format")
+	}
+
+	if request.TargetID != nil {
+		if _, err = m.db.Target(entityID, request.TargetID); err != nil {
+			if err == sql.ErrNoRows {
+				log.Debugf("target %q not found for %x", request.TargetID.String(), request.SignaturePublicKey)
+				return nil, fmt.Errorf("target not found")
+			}
+			log.Errorf("could not retrieve target for %x: (%v)", request.SignaturePublicKey, err)
+			return nil, fmt.Errorf("could not retrieve target")
+		}
+	}
+	if request.TagID != 0 {
+		if _, err = m.db.Tag(entityID, request.TagID); err != nil {
+			if err == sql.ErrNoRows {
+				log.Debugf("tag %d not found for %x", request.TagID, request.SignaturePublicKey)
+				return nil, fmt.Errorf("tag not found")
+			}
+			log.Errorf("could not retrieve tag for %x: (%v)", request.SignaturePublicKey, err)
+			return nil, fmt.Errorf("could not retrieve tag")
+		}
+	}
+
+	// the export is returned inline, so its size is limited
+	var export bytes.Buffer
+	writer := &limitedWriter{w: &export, limit: maxMembersExportSize}
+	if response.Count, err = ExportMembers(m.db, writer, entityID, &ExportOptions{
+		Format:   format,
+		TargetID: request.TargetID,
+		TagID:    request.TagID,
+	}); err != nil {
+		if writer.exceeded {
+			log.Warnf("cannot export members for %x: export larger than %d bytes", entityID, maxMembersExportSize)
+			return nil, fmt.Errorf("the export exceeds %d MB: restrict it to a target or tag, or use the exportMembers subcommand", maxMembersExportSize>>20)
+		}
+		log.Errorf("cannot export members for %x: (%v)", request.SignaturePublicKey, err)
+		return nil, fmt.Errorf("cannot export members")
+	}
+	response.Export = export.String()
+
+	log.Debugf("Entity: %x exportMembers: %d members as %s", request.SignaturePublicKey, response.Count, format)
+	return &response, nil
+}
+
 func (m *Manager) countTargets(request *types.APIrequest) (*types.APIresponse, error) {
 	var entityID []byte
 	var err error
@@ -447,7 +1167,7 @@ func (m *Manager) countTargets(request *types.APIrequest) (*types.APIresponse, e
 	}
 
 	// retrieve entity ID
-	if entityID, err = util.PubKeyToEntityID(request.SignaturePublicKey); err != nil {
+	if entityID, err = actingEntityID(request); err != nil {
 		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
 		return nil, fmt.Errorf("cannot recover entityID")
 	}
@@ -474,37 +1194,168 @@ func (m *Manager) listTargets(request *types.APIrequest) (*types.APIresponse, er
 	}
 
 	// retrieve entity ID
-	if entityID, err = util.PubKeyToEntityID(request.SignaturePublicKey); err != nil {
+	if entityID, err = actingEntityID(request); err != nil {
+		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
+		return nil, fmt.Errorf("cannot recover entityID")
+	}
+
+	// check filter
+	if err = checkOptions(request.ListOptions, request.Method); err != nil {
+		log.Warnf("invalid filter options %x: (%v)", request.SignaturePublicKey, err)
+		return nil, fmt.Errorf("invalid filter options: (%v)", err)
+	}
+
+	// Retrieve targets
+	// Implement filters in DB
+	response.Targets, err = m.db.ListTargets(entityID)
+	if err != nil || len(response.Targets) == 0 {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("no targets found")
+		}
+		log.Errorf("cannot query targets for %x: (%v)", request.SignaturePublicKey, err)
+		return nil, fmt.Errorf("cannot query targets")
+	}
+
+	log.Debugf("Entity: %x listTargets: %d targets", request.SignaturePublicKey, len(response.Targets))
+	return &response, nil
+}
+
+func (m *Manager) getTarget(request *types.APIrequest) (*types.APIresponse, error) {
+	var entityID []byte
+	var err error
+	var response types.APIresponse
+
+	// check public key length
+	if len(request.SignaturePublicKey) != ethereum.PubKeyLengthBytes {
+		log.Warnf("invalid public key: %x", request.SignaturePublicKey)
+		return nil, fmt.Errorf("invalid public key")
+	}
+
+	// retrieve entity ID
+	if entityID, err = actingEntityID(request); err != nil {
+		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
+		return nil, fmt.Errorf("cannot recover entityID")
+	}
+
+	if response.Target, err = m.db.Target(entityID, request.TargetID); err != nil {
+		if err == sql.ErrNoRows {
+			log.Debugf("target %q not found for %x", request.TargetID.String(), request.SignaturePublicKey)
+			return nil, fmt.Errorf("target not found")
+		}
+		log.Errorf("could not retrieve target for %x: %+v", request.SignaturePublicKey, err)
+		return nil, fmt.Errorf("could not retrieve target")
+	}
+
+	response.Members, err = m.db.TargetMembers(entityID, request.TargetID)
+	if err != nil {
+		log.Warn("members for requested target could not be retrieved")
+		return nil, fmt.Errorf("members for requested target could not be retrieved")
+	}
+
+	log.Debugf("Entity: %x getTarget: %s", request.SignaturePublicKey, request.TargetID.String())
+	return &response, nil
+}
+
+func (m *Manager) addTarget(request *types.APIrequest) (*types.APIresponse, error) {
+	var entityID []byte
+	var err error
+	var response types.APIresponse
+
+	if request.Target == nil || request.Target.Name == "" {
+		log.Debugf("invalid target for %x", request.SignaturePublicKey)
+		return nil, fmt.Errorf("invalid target")
+	}
+
+	// check public key length
+	if len(request.SignaturePublicKey) != ethereum.PubKeyLengthBytes {
+		log.Warnf("invalid public key: %x", request.SignaturePublicKey)
+		return nil, fmt.Errorf("invalid public key")
+	}
+
+	// retrieve entity ID
+	if entityID, err = actingEntityID(request); err != nil {
+		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
+		return nil, fmt.Errorf("cannot recover entityID")
+	}
+
+	if len(request.Target.Filters) == 0 {
+		request.Target.Filters = json.RawMessage([]byte("{}"))
+	}
+	if err = m.checkTargetFilters(entityID, request.Target.Filters); err != nil {
+		log.Debugf("invalid target filters for %x: (%v)", entityID, err)
+		return nil, fmt.Errorf("invalid target filters: %v", err)
+	}
+
+	target := &types.Target{EntityID: entityID, Name: request.Target.Name, Filters: request.Target.Filters}
+	if target.ID, err = m.db.AddTarget(entityID, target); err != nil {
+		log.Errorf("cannot add target %q for %x: (%v)", target.Name, entityID, err)
+		if strings.Contains(err.Error(), "targets_entity_id_name_unique") {
+			return nil, fmt.Errorf("duplicate target name")
+		}
+		return nil, fmt.Errorf("cannot add target")
+	}
+	response.Target = target
+
+	log.Debugf("Entity: %x addTarget: %s", entityID, target.ID.String())
+	return &response, nil
+}
+
+func (m *Manager) updateTarget(request *types.APIrequest) (*types.APIresponse, error) {
+	var entityID []byte
+	var err error
+	var response types.APIresponse
+
+	if request.TargetID == nil || *request.TargetID == uuid.Nil {
+		log.Debugf("invalid target id for %x", request.SignaturePublicKey)
+		return nil, fmt.Errorf("invalid target id")
+	}
+	if request.Target == nil {
+		log.Debugf("invalid target for %x", request.SignaturePublicKey)
+		return nil, fmt.Errorf("invalid target")
+	}
+
+	// check public key length
+	if len(request.SignaturePublicKey) != ethereum.PubKeyLengthBytes {
+		log.Warnf("invalid public key: %x", request.SignaturePublicKey)
+		return nil, fmt.Errorf("invalid public key")
+	}
+
+	// retrieve entity ID
+	if entityID, err = actingEntityID(request); err != nil {
 		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
 		return nil, fmt.Errorf("cannot recover entityID")
 	}
 
-	// check filter
-	if err = checkOptions(request.ListOptions, request.Method); err != nil {
-		log.Warnf("invalid filter options %x: (%v)", request.SignaturePublicKey, err)
-		return nil, fmt.Errorf("invalid filter options: (%v)", err)
+	// empty filters are not updated
+	if len(request.Target.Filters) > 0 {
+		if err = m.checkTargetFilters(entityID, request.Target.Filters); err != nil {
+			log.Debugf("invalid target filters for %x: (%v)", entityID, err)
+			return nil, fmt.Errorf("invalid target filters: %v", err)
+		}
 	}
 
-	// Retrieve targets
-	// Implement filters in DB
-	response.Targets, err = m.db.ListTargets(entityID)
-	if err != nil || len(response.Targets) == 0 {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("no targets found")
+	if response.Count, err = m.db.UpdateTarget(entityID, request.TargetID, request.Target); err != nil {
+		log.Errorf("cannot update target %q for %x: (%v)", request.TargetID.String(), entityID, err)
+		if strings.Contains(err.Error(), "duplicate name") {
+			return nil, fmt.Errorf("duplicate target name")
 		}
-		log.Errorf("cannot query targets for %x: (%v)", request.SignaturePublicKey, err)
-		return nil, fmt.Errorf("cannot query targets")
+		return nil, fmt.Errorf("cannot update target")
 	}
 
-	log.Debugf("Entity: %x listTargets: %d targets", request.SignaturePublicKey, len(response.Targets))
+	log.Debugf("Entity: %x updateTarget: %s", entityID, request.TargetID.String())
 	return &response, nil
 }
 
-func (m *Manager) getTarget(request *types.APIrequest) (*types.APIresponse, error) {
+func (m *Manager) deleteTarget(request *types.APIrequest) (*types.APIresponse, error) {
 	var entityID []byte
 	var err error
 	var response types.APIresponse
 
+	if request.TargetID == nil || *request.TargetID == uuid.Nil {
+		log.Debugf("invalid target id for %x", request.SignaturePublicKey)
+		return nil, fmt.Errorf("invalid target id")
+	}
+
 	// check public key length
 	if len(request.SignaturePublicKey) != ethereum.PubKeyLengthBytes {
 		log.Warnf("invalid public key: %x", request.SignaturePublicKey)
@@ -512,32 +1363,28 @@ func (m *Manager) getTarget(request *types.APIrequest) (*types.APIresponse, erro
 	}
 
 	// retrieve entity ID
-	if entityID, err = util.PubKeyToEntityID(request.SignaturePublicKey); err != nil {
+	if entityID, err = actingEntityID(request); err != nil {
 		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
 		return nil, fmt.Errorf("cannot recover entityID")
 	}
 
-	if response.Target, err = m.db.Target(entityID, request.TargetID); err != nil {
+	if err = m.db.DeleteTarget(entityID, request.TargetID); err != nil {
 		if err == sql.ErrNoRows {
-			log.Debugf("target %q not found for %x", request.TargetID.String(), request.SignaturePublicKey)
+			log.Debugf("target %q not found for %x", request.TargetID.String(), entityID)
 			return nil, fmt.Errorf("target not found")
 		}
-		log.Errorf("could not retrieve target for %x: %+v", request.SignaturePublicKey, err)
-		return nil, fmt.Errorf("could not retrieve target")
-	}
-
-	response.Members, err = m.db.TargetMembers(entityID, request.TargetID)
-	if err != nil {
-		log.Warn("members for requested target could not be retrieved")
-		return nil, fmt.Errorf("members for requested target could not be retrieved")
+		log.Errorf("cannot delete target %q for %x: (%v)", request.TargetID.String(), entityID, err)
+		if strings.Contains(err.Error(), "referenced by existing censuses") {
+			return nil, fmt.Errorf("target is used by existing censuses")
+		}
+		return nil, fmt.Errorf("cannot delete target")
 	}
 
-	log.Debugf("Entity: %x getTarget: %s", request.SignaturePublicKey, request.TargetID.String())
+	log.Debugf("Entity: %x deleteTarget: %s", entityID, request.TargetID.String())
 	return &response, nil
 }
 
 func (m *Manager) dumpTarget(request *types.APIrequest) (*types.APIresponse, error) {
-	var target *types.Target
 	var entityID []byte
 	var err error
 	var response types.APIresponse
@@ -549,12 +1396,12 @@ func (m *Manager) dumpTarget(request *types.APIrequest) (*types.APIresponse, err
 	}
 
 	// retrieve entity ID
-	if entityID, err = util.PubKeyToEntityID(request.SignaturePublicKey); err != nil {
+	if entityID, err = actingEntityID(request); err != nil {
 		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
 		return nil, fmt.Errorf("cannot recover entityID")
 	}
 
-	if target, err = m.db.Target(entityID, request.TargetID); err != nil || target.Name != "all" {
+	if _, err = m.db.Target(entityID, request.TargetID); err != nil {
 		if err == sql.ErrNoRows {
 			log.Debugf("target %q not found for %x", request.TargetID.String(), request.SignaturePublicKey)
 			return nil, fmt.Errorf("target not found")
@@ -563,8 +1410,7 @@ func (m *Manager) dumpTarget(request *types.APIrequest) (*types.APIresponse, err
 		return nil, fmt.Errorf("could not retrieve target")
 	}
 
-	// TODO: Implement DumpTargetClaims filtered directly by target filters
-	if response.Claims, err = m.db.DumpClaims(entityID); err != nil {
+	if response.Claims, response.Skipped, err = m.db.DumpTargetClaims(entityID, request.TargetID); err != nil {
 		if err == sql.ErrNoRows {
 			log.Debugf("no claims found for %x", request.SignaturePublicKey)
 			return nil, fmt.Errorf("no claims found")
@@ -573,7 +1419,7 @@ func (m *Manager) dumpTarget(request *types.APIrequest) (*types.APIresponse, err
 		return nil, fmt.Errorf("cannot dump claims")
 	}
 
-	log.Debugf("Entity: %x dumpTarget: %d claims", request.SignaturePublicKey, len(response.Claims))
+	log.Debugf("Entity: %x dumpTarget: %d claims, %d skipped", request.SignaturePublicKey, len(response.Claims), response.Skipped)
 	return &response, nil
 }
 
@@ -588,18 +1434,31 @@ func (m *Manager) dumpCensus(request *types.APIrequest) (*types.APIresponse, err
 	}
 
 	// retrieve entity ID
-	entityID, err := util.PubKeyToEntityID(request.SignaturePublicKey)
+	entityID, err := actingEntityID(request)
 	if err != nil {
 		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
 		return nil, fmt.Errorf("cannot recover entityID")
 	}
 
-	censusID, err := util.DecodeCensusID(request.CensusID, request.SignaturePublicKey)
+	censusID, err := util.DecodeCensusID(request.CensusID, entityID)
 	if err != nil {
 		log.Errorf("cannot decode census id %s for %x", request.CensusID, entityID)
 		return nil, fmt.Errorf("cannot decode census id")
 	}
 
+	// the members of a census are expanded only once
+	census, err := m.db.Census(entityID, censusID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("census not found")
+		}
+		log.Errorf("cannot retrieve census %q for %x: (%v)", request.CensusID, entityID, err)
+		return nil, fmt.Errorf("cannot retrieve census")
+	}
+	if census.State != types.CensusDraft {
+		return nil, fmt.Errorf("census is %s and cannot be expanded", census.State)
+	}
+
 	// TODO: Implement DumpTargetClaims filtered directly by target filters
 	censusMembers, err := m.db.ExpandCensusMembers(entityID, censusID)
 	if err != nil {
@@ -607,13 +1466,21 @@ func (m *Manager) dumpCensus(request *types.APIrequest) (*types.APIresponse, err
 		return nil, fmt.Errorf("cannot dump claims")
 	}
 	shuffledClaims := make([][]byte, len(censusMembers))
+	shuffledWeights := make([]uint64, len(censusMembers))
 	shuffledIndexes := rand.Perm(len(censusMembers))
 	for i, v := range shuffledIndexes {
 		shuffledClaims[v] = censusMembers[i].DigestedPubKey
+		shuffledWeights[v] = censusMembers[i].Weight
 	}
 	response.Claims = shuffledClaims
+	response.Weights = shuffledWeights
+
+	if response.Root, err = m.censusTrees.BuildCensus(m.db, entityID, censusID); err != nil {
+		log.Errorf("cannot build census tree %x for %x: (%v)", censusID, entityID, err)
+		return nil, fmt.Errorf("cannot build census tree")
+	}
 
-	log.Debugf("Entity: %x dumpCensus: %d claims", entityID, len(response.Claims))
+	log.Debugf("Entity: %x dumpCensus: %d claims, root %x", entityID, len(response.Claims), response.Root)
 	return &response, nil
 }
 
@@ -630,7 +1497,7 @@ func (m *Manager) sendVotingLinks(request *types.APIrequest) (*types.APIresponse
 	}
 
 	// retrieve entity ID
-	entityID, err := util.PubKeyToEntityID(request.SignaturePublicKey)
+	entityID, err := actingEntityID(request)
 	if err != nil {
 		log.Errorf("cannot recover %x entityID from public key: (%v)", request.SignaturePublicKey, err)
 		return nil, fmt.Errorf("cannot recover entityID from public key")
@@ -642,7 +1509,7 @@ func (m *Manager) sendVotingLinks(request *types.APIrequest) (*types.APIresponse
 		return nil, fmt.Errorf("cannot recover entity from public key")
 	}
 
-	censusID, err := util.DecodeCensusID(request.CensusID, request.SignaturePublicKey)
+	censusID, err := util.DecodeCensusID(request.CensusID, entityID)
 	if err != nil {
 		log.Errorf("cannot decode census id %s for %x", request.CensusID, entityID)
 		return nil, fmt.Errorf("cannot decode census id")
@@ -655,10 +1522,18 @@ func (m *Manager) sendVotingLinks(request *types.APIrequest) (*types.APIresponse
 			log.Errorf("cannot retrieve ephemeral member %s of  census %x for enity %x: (%v)", request.Email, censusID, entityID, err)
 			return nil, fmt.Errorf("cannot retrieve ephemeral census member by email")
 		}
+		day, err := m.reserveEmails(entityID, 1)
+		if err != nil {
+			return nil, err
+		}
 		if err := m.smtp.SendVotingLink(censusMember, entity, request.ProcessID); err != nil {
 			log.Errorf("could not send voting link for member %q entity: (%v)", censusMember.ID, err)
+			m.releaseEmails(entityID, day, 1)
 			return nil, fmt.Errorf("could not send voting link")
 		}
+		if err := m.db.AddMemberEmails(entityID, []uuid.UUID{censusMember.ID}, types.MemberEmailVotingLink, censusID, request.ProcessID); err != nil {
+			log.Errorf("cannot record voting link sent to member %q: (%v)", censusMember.ID, err)
+		}
 		log.Infof("send validation links to 1 members for Entity %x", entityID)
 		var response types.APIresponse
 		response.Count = 1
@@ -676,6 +1551,10 @@ func (m *Manager) sendVotingLinks(request *types.APIrequest) (*types.APIresponse
 		response.Count = 0
 		return &response, nil
 	}
+	day, err := m.reserveEmails(entityID, len(censusMembers))
+	if err != nil {
+		return nil, err
+	}
 	// send concurrently emails
 	processID := request.ProcessID
 	var wg sync.WaitGroup
@@ -688,6 +1567,7 @@ func (m *Manager) sendVotingLinks(request *types.APIrequest) (*types.APIresponse
 				log.Errorf("could not send voting link for member %q entity: (%v)", member.ID, err)
 				ec <- fmt.Errorf("member %s error  %v", member.ID, err)
 				wg.Done()
+				return
 			}
 			sc <- member.ID
 			wg.Done()
@@ -706,6 +1586,7 @@ func (m *Manager) sendVotingLinks(request *types.APIrequest) (*types.APIresponse
 	for err := range ec {
 		errors = append(errors, err)
 	}
+	m.releaseEmails(entityID, day, len(censusMembers)-response.Count)
 	if len(errors)+response.Count != len(censusMembers) {
 		log.Errorf("inconsistency in number of sent emails and errors")
 		return nil, fmt.Errorf("inconsistency in number of sent emails and errors")
@@ -718,6 +1599,10 @@ func (m *Manager) sendVotingLinks(request *types.APIrequest) (*types.APIresponse
 		response.Message = fmt.Sprintf("%d where found:\n%v", len(errors), errors)
 	}
 
+	if err := m.db.AddMemberEmails(entityID, successUUIDs, types.MemberEmailVotingLink, censusID, processID); err != nil {
+		log.Errorf("cannot record voting links sent to members of %x: (%v)", entityID, err)
+	}
+
 	// add tag PendingValidation to sucessful members
 	tagName := "VoteEmailSent"
 	tag, err := m.db.TagByName(entityID, tagName)
@@ -736,7 +1621,7 @@ func (m *Manager) sendVotingLinks(request *types.APIrequest) (*types.APIresponse
 			return nil, fmt.Errorf("sent emails but could not assign tag")
 		}
 	}
-	_, _, err = m.db.AddTagToMembers(entityID, successUUIDs, tag.ID)
+	_, _, err = m.actingDB(request).AddTagToMembers(entityID, successUUIDs, tag.ID)
 	if err != nil {
 		log.Infof("send validation links to %d members, skipped %d invalid IDs and %d errors , for Entity %x\nErrors: %v", response.Count, len(response.InvalidIDs), len(errors), entityID, errors)
 		log.Errorf("error assinging Pending tag:  %v", err)
@@ -769,17 +1654,27 @@ func (m *Manager) addCensus(request *types.APIrequest) (*types.APIresponse, erro
 	}
 
 	// retrieve entity ID
-	entityID, err = util.PubKeyToEntityID(request.SignaturePublicKey)
+	entityID, err = actingEntityID(request)
 	if err != nil {
 		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
 		return nil, fmt.Errorf("cannot recover entityID")
 	}
 
 	var censusID []byte
-	if censusID, err = util.DecodeCensusID(request.CensusID, request.SignaturePublicKey); err != nil {
+	if censusID, err = util.DecodeCensusID(request.CensusID, entityID); err != nil {
 		return nil, fmt.Errorf(err.Error())
 	}
 
+	if request.Census != nil && request.Census.Weight != nil {
+		if err := m.checkCensusWeight(entityID, request.Census.Weight); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = m.checkQuota(entityID, quotaCensuses, 1); err != nil {
+		return nil, err
+	}
+
 	// size, err := m.db.AddCensusWithMembers(entityID, censusID, request.TargetID, request.Census)
 	if err := m.db.AddCensus(entityID, censusID, request.TargetID, request.Census); err != nil {
 		log.Errorf("cannot add census %q  for: %q: (%v)", request.CensusID, entityID, err)
@@ -791,7 +1686,6 @@ func (m *Manager) addCensus(request *types.APIrequest) (*types.APIresponse, erro
 }
 
 func (m *Manager) updateCensus(request *types.APIrequest) (*types.APIresponse, error) {
-	// TODO Handle invalid claims
 	var entityID []byte
 	var err error
 	var response types.APIresponse
@@ -813,19 +1707,42 @@ func (m *Manager) updateCensus(request *types.APIrequest) (*types.APIresponse, e
 	}
 
 	// retrieve entity ID
-	entityID, err = util.PubKeyToEntityID(request.SignaturePublicKey)
+	entityID, err = actingEntityID(request)
 	if err != nil {
 		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
 		return nil, fmt.Errorf("cannot recover entityID")
 	}
 
 	var censusID []byte
-	if censusID, err = util.DecodeCensusID(request.CensusID, request.SignaturePublicKey); err != nil {
+	if censusID, err = util.DecodeCensusID(request.CensusID, entityID); err != nil {
 		return nil, fmt.Errorf(err.Error())
 	}
 
-	if request.InvalidClaims != nil && len(request.InvalidClaims) > 0 {
-		log.Warnf("invalid claims: %v", request.InvalidClaims)
+	// published censuses are immutable
+	census, err := m.db.Census(entityID, censusID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("census not found")
+		}
+		log.Errorf("cannot retrieve census %q for %x: (%v)", request.CensusID, entityID, err)
+		return nil, fmt.Errorf("cannot retrieve census")
+	}
+	if !census.State.Mutable() {
+		return nil, fmt.Errorf("census is %s and cannot be updated", census.State)
+	}
+
+	// the root published by the client must be the one of the census tree
+	// computed from the census members
+	if len(request.Census.MerkleRoot) > 0 {
+		root, err := m.censusTrees.CensusRoot(m.db, entityID, censusID)
+		if err != nil {
+			log.Errorf("cannot compute root of census %q for %x: (%v)", request.CensusID, entityID, err)
+			return nil, fmt.Errorf("cannot compute census root")
+		}
+		if !bytes.Equal(root, request.Census.MerkleRoot) {
+			log.Warnf("census %q for %x: merkle root %x does not match %x", request.CensusID, entityID, request.Census.MerkleRoot, root)
+			return nil, fmt.Errorf("merkle root does not match the census root %x", root)
+		}
 	}
 
 	if response.Count, err = m.db.UpdateCensus(entityID, censusID, request.Census); err != nil {
@@ -833,10 +1750,69 @@ func (m *Manager) updateCensus(request *types.APIrequest) (*types.APIresponse, e
 		return nil, fmt.Errorf("cannot update census")
 	}
 
+	// flag the members of the claims reported as invalid, so that they can
+	// be contacted to fix their keys. Only censuses that were updated are
+	// flagged.
+	if len(request.InvalidClaims) > 0 && response.Count > 0 {
+		if response.InvalidClaims, err = m.flagInvalidClaims(entityID, censusID, request.InvalidClaims); err != nil {
+			log.Errorf("cannot flag invalid claims of census %q for %x: (%v)", request.CensusID, entityID, err)
+			return nil, fmt.Errorf("cannot flag invalid claims")
+		}
+		log.Warnf("census %q for %x: %d invalid claims", request.CensusID, entityID, len(response.InvalidClaims))
+	}
+
 	log.Debugf("Entity: %x updateCensus: %s \n %v", entityID, request.CensusID, request.Census)
 	return &response, nil
 }
 
+// flagInvalidClaims flags the census members of the claims reported as
+// invalid with the likely reason of their rejection. It returns them along
+// with the claims that do not belong to any census member.
+func (m *Manager) flagInvalidClaims(entityID, censusID []byte, claims [][]byte) ([]types.InvalidClaim, error) {
+	censusClaims, err := m.db.DumpCensusClaims(entityID, censusID)
+	if err != nil {
+		return nil, fmt.Errorf("cannot dump census claims: %w", err)
+	}
+	counts := make(map[string]int, len(censusClaims))
+	for _, claim := range censusClaims {
+		counts[string(claim)]++
+	}
+
+	var uniqueClaims [][]byte
+	var reasons []string
+	seen := make(map[string]bool, len(claims))
+	for _, claim := range claims {
+		if seen[string(claim)] {
+			continue
+		}
+		seen[string(claim)] = true
+		uniqueClaims = append(uniqueClaims, claim)
+		switch {
+		case !util.ValidPubKey(claim):
+			reasons = append(reasons, types.InvalidClaimPublicKey)
+		case counts[string(claim)] > 1:
+			reasons = append(reasons, types.InvalidClaimDuplicated)
+		default:
+			reasons = append(reasons, types.InvalidClaimRejected)
+		}
+	}
+
+	invalidClaims, err := m.db.FlagInvalidClaims(entityID, censusID, uniqueClaims, reasons)
+	if err != nil {
+		return nil, err
+	}
+	flagged := make(map[string]bool, len(invalidClaims))
+	for _, invalidClaim := range invalidClaims {
+		flagged[string(invalidClaim.Claim)] = true
+	}
+	for _, claim := range uniqueClaims {
+		if !flagged[string(claim)] {
+			invalidClaims = append(invalidClaims, types.InvalidClaim{Claim: claim, Reason: types.InvalidClaimNotInCensus})
+		}
+	}
+	return invalidClaims, nil
+}
+
 func (m *Manager) getCensus(request *types.APIrequest) (*types.APIresponse, error) {
 	var entityID []byte
 	var err error
@@ -854,14 +1830,14 @@ func (m *Manager) getCensus(request *types.APIrequest) (*types.APIresponse, erro
 	}
 
 	// retrieve entity ID
-	entityID, err = util.PubKeyToEntityID(request.SignaturePublicKey)
+	entityID, err = actingEntityID(request)
 	if err != nil {
 		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
 		return nil, fmt.Errorf("cannot recover entityID")
 	}
 
 	var censusID []byte
-	if censusID, err = util.DecodeCensusID(request.CensusID, request.SignaturePublicKey); err != nil {
+	if censusID, err = util.DecodeCensusID(request.CensusID, entityID); err != nil {
 		log.Errorf("cannot decode census id %s for %x", request.CensusID, request.SignaturePublicKey)
 		return nil, fmt.Errorf("cannot decode census id")
 	}
@@ -882,10 +1858,75 @@ func (m *Manager) getCensus(request *types.APIrequest) (*types.APIresponse, erro
 		return nil, fmt.Errorf("census target not found")
 	}
 
+	if response.InvalidClaims, err = m.db.CensusInvalidClaims(entityID, censusID); err != nil {
+		log.Errorf("cannot retrieve invalid claims of census %q for %x: (%v)", request.CensusID, entityID, err)
+		return nil, fmt.Errorf("cannot retrieve census invalid claims")
+	}
+
 	log.Debugf("Entity: %x getCensus:%s", request.SignaturePublicKey, request.CensusID)
 	return &response, nil
 }
 
+// updateCensusState moves a census to the next state of its lifecycle. Only a
+// census bound to a process and with a Merkle root can be published, and
+// closing a census removes the ephemeral private keys of its members.
+func (m *Manager) updateCensusState(request *types.APIrequest) (*types.APIresponse, error) {
+	var response types.APIresponse
+
+	if len(request.CensusID) == 0 {
+		log.Debugf("invalid census id %q for %x", request.CensusID, request.SignaturePublicKey)
+		return nil, fmt.Errorf("invalid census id")
+	}
+	if !request.State.Valid() {
+		return nil, fmt.Errorf("invalid census state %q", request.State)
+	}
+	if request.State == types.CensusExpanded {
+		return nil, fmt.Errorf("censuses are expanded by dumpCensus")
+	}
+
+	// check public key length
+	if len(request.SignaturePublicKey) != ethereum.PubKeyLengthBytes {
+		log.Warnf("invalid public key: %x", request.SignaturePublicKey)
+		return nil, fmt.Errorf("invalid public key")
+	}
+
+	// retrieve entity ID
+	entityID, err := actingEntityID(request)
+	if err != nil {
+		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
+		return nil, fmt.Errorf("cannot recover entityID")
+	}
+
+	censusID, err := util.DecodeCensusID(request.CensusID, entityID)
+	if err != nil {
+		log.Errorf("cannot decode census id %s for %x", request.CensusID, entityID)
+		return nil, fmt.Errorf("cannot decode census id")
+	}
+
+	census, err := m.db.Census(entityID, censusID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("census not found")
+		}
+		log.Errorf("cannot retrieve census %q for %x: (%v)", request.CensusID, entityID, err)
+		return nil, fmt.Errorf("cannot retrieve census")
+	}
+	if !census.State.CanTransition(request.State) {
+		return nil, fmt.Errorf("census cannot go from %s to %s", census.State, request.State)
+	}
+	if request.State == types.CensusPublished && (len(census.ProcessID) == 0 || len(census.MerkleRoot) == 0) {
+		return nil, fmt.Errorf("census needs a process id and a merkle root to be published")
+	}
+
+	if err := m.db.SetCensusState(entityID, censusID, census.State, request.State); err != nil {
+		log.Errorf("cannot set state %s of census %q for %x: (%v)", request.State, request.CensusID, entityID, err)
+		return nil, fmt.Errorf("cannot update census state")
+	}
+
+	log.Debugf("Entity: %x updateCensusState: %s %s -> %s", entityID, request.CensusID, census.State, request.State)
+	return &response, nil
+}
+
 func (m *Manager) countCensus(request *types.APIrequest) (*types.APIresponse, error) {
 	var entityID []byte
 	var err error
@@ -897,7 +1938,7 @@ func (m *Manager) countCensus(request *types.APIrequest) (*types.APIresponse, er
 	}
 
 	// retrieve entity ID
-	if entityID, err = util.PubKeyToEntityID(request.SignaturePublicKey); err != nil {
+	if entityID, err = actingEntityID(request); err != nil {
 		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
 		return nil, fmt.Errorf("cannot recover entityID")
 	}
@@ -924,7 +1965,7 @@ func (m *Manager) listCensus(request *types.APIrequest) (*types.APIresponse, err
 	}
 
 	// retrieve entity ID
-	entityID, err = util.PubKeyToEntityID(request.SignaturePublicKey)
+	entityID, err = actingEntityID(request)
 	if err != nil {
 		log.Warnf("invalid public key: %x", request.SignaturePublicKey)
 		return nil, fmt.Errorf("invalid public key")
@@ -938,7 +1979,7 @@ func (m *Manager) listCensus(request *types.APIrequest) (*types.APIresponse, err
 
 	// Query for members
 	// TODO Implement listCensus in Db that supports filters
-	response.Censuses, err = m.db.ListCensus(entityID, request.ListOptions)
+	response.Censuses, response.NextCursor, err = m.db.ListCensus(entityID, request.ListOptions)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("no censuses found")
@@ -967,23 +2008,41 @@ func (m *Manager) deleteCensus(request *types.APIrequest) (*types.APIresponse, e
 	}
 
 	// retrieve entity ID
-	entityID, err = util.PubKeyToEntityID(request.SignaturePublicKey)
+	entityID, err = actingEntityID(request)
 	if err != nil {
 		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
 		return nil, fmt.Errorf("cannot recover entityID")
 	}
 
 	var censusID []byte
-	if censusID, err = util.DecodeCensusID(request.CensusID, request.SignaturePublicKey); err != nil {
+	if censusID, err = util.DecodeCensusID(request.CensusID, entityID); err != nil {
 		log.Errorf("cannot decode census id %x for %s", request.CensusID, request.SignaturePublicKey)
 		return nil, fmt.Errorf("cannot decode census id")
 	}
 
+	// published censuses are immutable
+	census, err := m.db.Census(entityID, censusID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return &response, nil
+		}
+		log.Errorf("cannot retrieve census %q for %x: (%v)", request.CensusID, entityID, err)
+		return nil, fmt.Errorf("cannot retrieve census")
+	}
+	if !census.State.Mutable() {
+		return nil, fmt.Errorf("census is %s and cannot be deleted", census.State)
+	}
+
 	err = m.db.DeleteCensus(entityID, censusID)
 	if err != nil && err != sql.ErrNoRows {
 		log.Errorf("error deleting census %s for entity %x: (%v)", request.CensusID, entityID, err)
 		return nil, fmt.Errorf("cannot delete census")
 	}
+	if err == nil {
+		if err := m.censusTrees.Delete(censusID); err != nil {
+			log.Errorf("cannot delete tree of census %s for entity %x: (%v)", request.CensusID, entityID, err)
+		}
+	}
 
 	log.Debugf("Entity: %x deleteCensus:%s", entityID, request.CensusID)
 	return &response, nil
@@ -1002,7 +2061,7 @@ func (m *Manager) sendValidationLinks(request *types.APIrequest) (*types.APIresp
 	}
 
 	// retrieve entity ID
-	entityID, err := util.PubKeyToEntityID(request.SignaturePublicKey)
+	entityID, err := actingEntityID(request)
 	if err != nil {
 		log.Errorf("cannot recover %x entityID from public key: (%v)", request.SignaturePublicKey, err)
 		return nil, fmt.Errorf("cannot recover entityID from public key")
@@ -1022,10 +2081,45 @@ func (m *Manager) sendValidationLinks(request *types.APIrequest) (*types.APIresp
 		return nil, fmt.Errorf("cannot retrieve member")
 	}
 
+	// if a target is provided only its members are considered
+	if request.TargetID != nil {
+		targetMembers, err := m.db.TargetMembers(entityID, request.TargetID)
+		if err != nil {
+			log.Errorf("cannot retrieve target %q members for entity %x: (%v)", request.TargetID.String(), entityID, err)
+			return nil, fmt.Errorf("cannot retrieve target members")
+		}
+		if len(request.MemberIDs) == 0 {
+			// send to all the target members that are not yet validated
+			for _, member := range targetMembers {
+				if member.PubKey == nil {
+					members = append(members, member)
+				}
+			}
+		} else {
+			inTarget := make(map[uuid.UUID]bool, len(targetMembers))
+			for _, member := range targetMembers {
+				inTarget[member.ID] = true
+			}
+			var targeted []types.Member
+			for _, member := range members {
+				if inTarget[member.ID] {
+					targeted = append(targeted, member)
+				} else {
+					response.InvalidIDs = append(response.InvalidIDs, member.ID)
+				}
+			}
+			members = targeted
+		}
+	}
+
 	if len(members) == 0 {
 		response.Count = 0
 		return &response, nil
 	}
+	day, err := m.reserveEmails(entityID, len(members))
+	if err != nil {
+		return nil, err
+	}
 	// send concurrently emails
 	var wg sync.WaitGroup
 	wg.Add(len(members))
@@ -1037,11 +2131,13 @@ func (m *Manager) sendValidationLinks(request *types.APIrequest) (*types.APIresp
 				log.Errorf("member %s is already validated at  %s", member.ID.String(), member.Verified)
 				ec <- fmt.Errorf("member %s is already validated at  %s", member.ID.String(), member.Verified)
 				wg.Done()
+				return
 			}
 			if err := m.smtp.SendValidationLink(&member, entity); err != nil {
 				log.Errorf("could not send validation link for member %q entity: (%v)", member.ID, err)
 				ec <- fmt.Errorf("member %s error  %v", member.ID, err)
 				wg.Done()
+				return
 			}
 			sc <- member.ID
 			wg.Done()
@@ -1060,6 +2156,7 @@ func (m *Manager) sendValidationLinks(request *types.APIrequest) (*types.APIresp
 	for err := range ec {
 		errors = append(errors, err)
 	}
+	m.releaseEmails(entityID, day, len(members)-response.Count)
 	if len(errors)+response.Count != len(members) {
 		log.Errorf("inconsistency in number of sent emails and errors")
 		return nil, fmt.Errorf("inconsistency in number of sent emails and errors")
@@ -1071,7 +2168,14 @@ func (m *Manager) sendValidationLinks(request *types.APIrequest) (*types.APIresp
 	if len(errors) > 0 {
 		response.Message = fmt.Sprintf("%d where found:\n%v", len(errors), errors)
 	}
-	duplicates := len(request.MemberIDs) - len(members) - len(response.InvalidIDs)
+	duplicates := 0
+	if len(request.MemberIDs) > 0 {
+		duplicates = len(request.MemberIDs) - len(members) - len(response.InvalidIDs)
+	}
+
+	if err := m.db.AddMemberEmails(entityID, successUUIDs, types.MemberEmailValidation, nil, nil); err != nil {
+		log.Errorf("cannot record validation links sent to members of %x: (%v)", entityID, err)
+	}
 
 	// add tag PendingValidation to sucessful members
 	tagName := "PendingValidation"
@@ -1091,7 +2195,7 @@ func (m *Manager) sendValidationLinks(request *types.APIrequest) (*types.APIresp
 			return nil, fmt.Errorf("sent emails but could not assign tag")
 		}
 	}
-	_, _, err = m.db.AddTagToMembers(entityID, successUUIDs, tag.ID)
+	_, _, err = m.actingDB(request).AddTagToMembers(entityID, successUUIDs, tag.ID)
 	if err != nil {
 		log.Infof("send validation links to %d members, skipped %d invalid IDs, %d duplicates and %d errors , for Entity %x\nErrors: %v", response.Count, len(response.InvalidIDs), duplicates, len(errors), entityID, errors)
 		log.Errorf("error assinging Pending tag:  %v", err)
@@ -1119,7 +2223,7 @@ func (m *Manager) createTag(request *types.APIrequest) (*types.APIresponse, erro
 	}
 
 	// retrieve entity ID
-	if entityID, err = util.PubKeyToEntityID(request.SignaturePublicKey); err != nil {
+	if entityID, err = actingEntityID(request); err != nil {
 		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
 		return nil, fmt.Errorf("cannot recover entityID")
 	}
@@ -1137,6 +2241,49 @@ func (m *Manager) createTag(request *types.APIrequest) (*types.APIresponse, erro
 	return &response, nil
 }
 
+// updateTag renames a tag and updates its description and color
+func (m *Manager) updateTag(request *types.APIrequest) (*types.APIresponse, error) {
+	var entityID []byte
+	var err error
+	var response types.APIresponse
+
+	if request.TagID == 0 {
+		log.Debugf("invalid tag id for %x", request.SignaturePublicKey)
+		return nil, fmt.Errorf("invalid tag id")
+	}
+	if request.Tag == nil {
+		log.Debugf("invalid tag for %x", request.SignaturePublicKey)
+		return nil, fmt.Errorf("invalid tag")
+	}
+	if len(request.Tag.Color) > 0 && !tagColorRegexp.MatchString(request.Tag.Color) {
+		log.Debugf("invalid tag color %q for %x", request.Tag.Color, request.SignaturePublicKey)
+		return nil, fmt.Errorf("invalid tag color")
+	}
+
+	// check public key length
+	if len(request.SignaturePublicKey) != ethereum.PubKeyLengthBytes {
+		log.Warnf("invalid public key: %x", request.SignaturePublicKey)
+		return nil, fmt.Errorf("invalid public key")
+	}
+
+	// retrieve entity ID
+	if entityID, err = actingEntityID(request); err != nil {
+		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
+		return nil, fmt.Errorf("cannot recover entityID")
+	}
+
+	if response.Count, err = m.db.UpdateTag(entityID, request.TagID, request.Tag); err != nil {
+		log.Errorf("cannot update tag %d for %x: (%v)", request.TagID, entityID, err)
+		if strings.Contains(err.Error(), "duplicate name") {
+			return nil, fmt.Errorf("duplicate tag name")
+		}
+		return nil, fmt.Errorf("cannot update tag")
+	}
+
+	log.Debugf("Entity: %x updateTag: %d", entityID, request.TagID)
+	return &response, nil
+}
+
 func (m *Manager) listTags(request *types.APIrequest) (*types.APIresponse, error) {
 	var entityID []byte
 	var err error
@@ -1149,7 +2296,7 @@ func (m *Manager) listTags(request *types.APIrequest) (*types.APIresponse, error
 	}
 
 	// retrieve entity ID
-	if entityID, err = util.PubKeyToEntityID(request.SignaturePublicKey); err != nil {
+	if entityID, err = actingEntityID(request); err != nil {
 		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
 		return nil, fmt.Errorf("cannot recover entityID")
 	}
@@ -1184,12 +2331,12 @@ func (m *Manager) deleteTag(request *types.APIrequest) (*types.APIresponse, erro
 	}
 
 	// retrieve entity ID
-	if entityID, err = util.PubKeyToEntityID(request.SignaturePublicKey); err != nil {
+	if entityID, err = actingEntityID(request); err != nil {
 		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
 		return nil, fmt.Errorf("cannot recover entityID")
 	}
 
-	if err = m.db.DeleteTag(entityID, request.TagID); err != nil {
+	if err = m.actingDB(request).DeleteTag(entityID, request.TagID); err != nil {
 		log.Errorf("cannot delete tag %d for entity %x: (%v)", request.TagID, entityID, err)
 		return nil, fmt.Errorf("cannot delete tag ")
 	}
@@ -1203,7 +2350,7 @@ func (m *Manager) addTag(request *types.APIrequest) (*types.APIresponse, error)
 	var err error
 	var response types.APIresponse
 
-	if request.TagID == 0 || len(request.MemberIDs) == 0 {
+	if request.TagID == 0 || !oneMemberSelection(request) {
 		log.Debug("addTag invalid arguments")
 		return nil, fmt.Errorf("invalid arguments")
 	}
@@ -1215,12 +2362,25 @@ func (m *Manager) addTag(request *types.APIrequest) (*types.APIresponse, error)
 	}
 
 	// retrieve entity ID
-	if entityID, err = util.PubKeyToEntityID(request.SignaturePublicKey); err != nil {
+	if entityID, err = actingEntityID(request); err != nil {
 		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
 		return nil, fmt.Errorf("cannot recover entityID")
 	}
 
-	response.Count, response.InvalidIDs, err = m.db.AddTagToMembers(entityID, request.MemberIDs, request.TagID)
+	if len(request.MemberIDs) == 0 {
+		filters, err := m.memberFilters(entityID, request)
+		if err != nil {
+			return nil, err
+		}
+		if response.Count, err = m.db.AddTagToMembersByFilters(entityID, filters, request.TagID); err != nil {
+			log.Errorf("cannot add tag %d to members by filters for entity %x: (%v)", request.TagID, entityID, err)
+			return nil, fmt.Errorf("cannot add tag ")
+		}
+		log.Infof("added tag with id %d to %d members by filters of Entity %x", request.TagID, response.Count, entityID)
+		return &response, nil
+	}
+
+	response.Count, response.InvalidIDs, err = m.actingDB(request).AddTagToMembers(entityID, request.MemberIDs, request.TagID)
 	if err != nil {
 		log.Errorf("cannot add tag %d to members for entity %x: (%v)", request.TagID, entityID, err)
 		return nil, fmt.Errorf("cannot add tag ")
@@ -1235,7 +2395,7 @@ func (m *Manager) removeTag(request *types.APIrequest) (*types.APIresponse, erro
 	var err error
 	var response types.APIresponse
 
-	if request.TagID == 0 || len(request.MemberIDs) == 0 {
+	if request.TagID == 0 || !oneMemberSelection(request) {
 		log.Debug("removeTag invalid arguments")
 		return nil, fmt.Errorf("invalid arguments")
 	}
@@ -1247,12 +2407,25 @@ func (m *Manager) removeTag(request *types.APIrequest) (*types.APIresponse, erro
 	}
 
 	// retrieve entity ID
-	if entityID, err = util.PubKeyToEntityID(request.SignaturePublicKey); err != nil {
+	if entityID, err = actingEntityID(request); err != nil {
 		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
 		return nil, fmt.Errorf("cannot recover entityID")
 	}
 
-	response.Count, response.InvalidIDs, err = m.db.RemoveTagFromMembers(entityID, request.MemberIDs, request.TagID)
+	if len(request.MemberIDs) == 0 {
+		filters, err := m.memberFilters(entityID, request)
+		if err != nil {
+			return nil, err
+		}
+		if response.Count, err = m.db.RemoveTagFromMembersByFilters(entityID, filters, request.TagID); err != nil {
+			log.Errorf("cannot remove tag %d from members by filters for entity %x: (%v)", request.TagID, entityID, err)
+			return nil, fmt.Errorf("cannot remove tag ")
+		}
+		log.Infof("removed tag with id %d from %d members by filters of Entity %x", request.TagID, response.Count, entityID)
+		return &response, nil
+	}
+
+	response.Count, response.InvalidIDs, err = m.actingDB(request).RemoveTagFromMembers(entityID, request.MemberIDs, request.TagID)
 	if err != nil {
 		log.Errorf("cannot remove tag %d from members for entity %x: (%v)", request.TagID, entityID, err)
 		return nil, fmt.Errorf("cannot remove tag ")
@@ -1263,27 +2436,9 @@ func (m *Manager) removeTag(request *types.APIrequest) (*types.APIresponse, erro
 }
 
 func (m *Manager) adminEntityList(request *types.APIrequest) (*types.APIresponse, error) {
-	var entityID []byte
 	var err error
 	var response types.APIresponse
 
-	// check public key length
-	if len(request.SignaturePublicKey) != ethereum.PubKeyLengthBytes {
-		log.Warnf("invalid public key: %x", request.SignaturePublicKey)
-		return nil, fmt.Errorf("invalid public key")
-	}
-
-	// retrieve entity ID
-	if entityID, err = util.PubKeyToEntityID(request.SignaturePublicKey); err != nil {
-		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
-		return nil, fmt.Errorf("cannot recover entityID")
-	}
-	log.Debugf("%s", ethcommon.BytesToAddress((entityID)).String())
-	if ethcommon.BytesToAddress((entityID)).String() != "0xCc41C6545234ac63F11c47bC282f89Ca77aB9945" {
-		log.Warnf("invalid auth: (%v)", request.SignaturePublicKey)
-		return nil, fmt.Errorf("invalid auth")
-	}
-
 	// Query for members
 	if response.Entities, err = m.db.AdminEntityList(); err != nil {
 		if err == sql.ErrNoRows {
@@ -1314,7 +2469,7 @@ func (m *Manager) requestGas(request *types.APIrequest) (*types.APIresponse, err
 	}
 
 	// retrieve entity ID
-	if entityID, err = util.PubKeyToEntityID(request.SignaturePublicKey); err != nil {
+	if entityID, err = actingEntityID(request); err != nil {
 		log.Errorf("cannot recover %q entityID: (%v)", request.SignaturePublicKey, err)
 		return nil, fmt.Errorf("cannot recover entityID")
 	}
@@ -1331,16 +2486,104 @@ func (m *Manager) requestGas(request *types.APIrequest) (*types.APIresponse, err
 		return nil, fmt.Errorf("cannot retrieve entity")
 	}
 
+	if err = m.checkQuota(entityID, quotaFaucetRequests, 1); err != nil {
+		return nil, err
+	}
+
 	sent, err := m.eth.SendTokens(context.Background(), entityAddress, 0, 0)
 	if err != nil {
 		log.Errorf("error sending tokens to entity %s : %v", entityAddress.String(), err)
 		return nil, fmt.Errorf("error sending tokens")
 	}
+	if err = m.db.AddFaucetRequest(entityID); err != nil {
+		log.Errorf("cannot record faucet request of entity %s: (%v)", entityAddress.String(), err)
+	}
 
 	response.Count = int(sent.Int64())
 	return &response, nil
 }
 
+// checkCustomFields validates the custom fields of a member against the
+// custom fields schema of the entity
+func (m *Manager) checkCustomFields(entityID []byte, customFields json.RawMessage) error {
+	schema, err := m.db.EntityCustomFieldsSchema(entityID)
+	if err != nil {
+		log.Errorf("cannot retrieve custom fields schema of %x: (%v)", entityID, err)
+		return fmt.Errorf("cannot retrieve custom fields schema")
+	}
+	if err := schema.ValidateFields(customFields); err != nil {
+		return fmt.Errorf("invalid custom fields: %v", err)
+	}
+	return nil
+}
+
+// checkTargetFilters verifies that the target filters are well formed
+// and that the tags they refer to exist for the entity
+func (m *Manager) checkTargetFilters(entityID []byte, rawFilters json.RawMessage) error {
+	filters, err := types.ParseTargetFilters(rawFilters)
+	if err != nil {
+		return err
+	}
+	if len(filters.CustomFields) > 0 {
+		schema, err := m.db.EntityCustomFieldsSchema(entityID)
+		if err != nil {
+			return fmt.Errorf("cannot retrieve custom fields schema")
+		}
+		if err := schema.CheckFilters(filters.CustomFields); err != nil {
+			return err
+		}
+	}
+	if filters.Tags == nil {
+		return nil
+	}
+	tags, err := m.db.ListTags(entityID)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("cannot retrieve entity tags")
+	}
+	entityTags := make(map[int32]bool, len(tags))
+	for _, tag := range tags {
+		entityTags[tag.ID] = true
+	}
+	for _, ids := range [][]int32{filters.Tags.Any, filters.Tags.All, filters.Tags.None} {
+		for _, id := range ids {
+			if !entityTags[id] {
+				return fmt.Errorf("unknown tag %d", id)
+			}
+		}
+	}
+	return nil
+}
+
+// checkCensusWeight verifies that the census weight source is well formed
+// and that the custom field and tags it refers to exist for the entity
+func (m *Manager) checkCensusWeight(entityID []byte, weight *types.CensusWeight) error {
+	schema, err := m.db.EntityCustomFieldsSchema(entityID)
+	if err != nil {
+		log.Errorf("cannot retrieve custom fields schema of %x: (%v)", entityID, err)
+		return fmt.Errorf("cannot retrieve custom fields schema")
+	}
+	if err := weight.Validate(schema); err != nil {
+		return fmt.Errorf("invalid census weight: %v", err)
+	}
+	if len(weight.Tags) == 0 {
+		return nil
+	}
+	tags, err := m.db.ListTags(entityID)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("cannot retrieve entity tags")
+	}
+	entityTags := make(map[int32]bool, len(tags))
+	for _, tag := range tags {
+		entityTags[tag.ID] = true
+	}
+	for id := range weight.Tags {
+		if !entityTags[id] {
+			return fmt.Errorf("unknown tag %d", id)
+		}
+	}
+	return nil
+}
+
 func checkOptions(filter *types.ListOptions, method string) error {
 	if filter == nil {
 		return nil
@@ -1349,13 +2592,43 @@ func checkOptions(filter *types.ListOptions, method string) error {
 	if filter.Skip < 0 || filter.Count < 0 {
 		return fmt.Errorf("invalid skip/count")
 	}
+	// Check cursor, which replaces skip
+	if len(filter.Cursor) > 0 {
+		if filter.Skip > 0 {
+			return fmt.Errorf("cursor and skip cannot be combined")
+		}
+		if err := pgsql.ValidateCursor(filter); err != nil {
+			return err
+		}
+	}
+	// Check search, only available for members and entities
+	if len(filter.Search) > 0 {
+		if method != "listMembers" && method != "countMembers" && method != "listDeletedMembers" &&
+			method != "listEntities" && method != "countEntities" {
+			return fmt.Errorf("search not supported")
+		}
+		if len(filter.Search) > maxSearchLength {
+			return fmt.Errorf("search too long")
+		}
+	}
+	// Check state, only available for censuses
+	if len(filter.State) > 0 {
+		if method != "listCensus" {
+			return fmt.Errorf("state not supported")
+		}
+		if !filter.State.Valid() {
+			return fmt.Errorf("invalid census state")
+		}
+	}
 	var t reflect.Type
 	// check method
 	switch method {
-	case "listMembers":
+	case "listMembers", "countMembers", "listDeletedMembers":
 		t = reflect.TypeOf(types.MemberInfo{})
 	case "listCensus":
 		t = reflect.TypeOf(types.CensusInfo{})
+	case "listEntities", "countEntities":
+		t = reflect.TypeOf(types.Entity{})
 	default:
 		return fmt.Errorf("invalid method")
 	}
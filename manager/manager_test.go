@@ -1,9 +1,14 @@
 package manager_test
 
 import (
+	"archive/zip"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"math/rand"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/google/uuid"
@@ -120,6 +125,415 @@ func TestGetEntity(t *testing.T) {
 	}
 }
 
+func TestOperators(t *testing.T) {
+	wsc, err := testcommon.NewApiConnection(fmt.Sprintf("http://127.0.0.1:%d/api/manager", api.Port), t)
+	// check connected successfully
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Signers[2] is an editor and Signers[3] a viewer of the entity of Signers[1]
+	owner := ethereum.NewSignKeys()
+	owner.AddHexKey(testdb.Signers[1].Priv)
+	entityID := owner.Address().Bytes()
+	editor := ethereum.NewSignKeys()
+	editor.AddHexKey(testdb.Signers[2].Priv)
+	viewer := ethereum.NewSignKeys()
+	viewer.AddHexKey(testdb.Signers[3].Priv)
+	stranger := ethereum.NewSignKeys()
+	stranger.AddHexKey(testdb.Signers[0].Priv)
+
+	for i, tc := range []struct {
+		signer *ethereum.SignKeys
+		method string
+		ok     bool
+	}{
+		{editor, "getEntity", true},
+		{editor, "createTag", true},
+		{editor, "updateEntity", false},
+		{editor, "listOperators", true},
+		{viewer, "getEntity", true},
+		{viewer, "createTag", false},
+		{stranger, "getEntity", false},
+		{owner, "updateEntity", true},
+	} {
+		var req types.APIrequest
+		req.Method = tc.method
+		req.EntityID = entityID
+		req.TagName = "operators"
+		req.Entity = &types.EntityInfo{Name: "operators"}
+		// make request
+		resp := wsc.Request(req, tc.signer)
+		if resp.Ok != tc.ok {
+			t.Fatalf("%d: expected %s ok %v but got %q", i, tc.method, tc.ok, resp.Message)
+		}
+		if tc.method == "getEntity" && resp.Ok && string(resp.Entity.ID) != string(entityID) {
+			t.Fatalf("%d: expected the entity %x but got %x", i, entityID, resp.Entity.ID)
+		}
+	}
+
+	// should fail to set an operator with an invalid address or role
+	var req types.APIrequest
+	req.Method = "setOperator"
+	req.Operator = &types.Operator{Address: []byte{1, 2, 3}, Role: types.OperatorEditor}
+	// make request
+	resp := wsc.Request(req, owner)
+	if resp.Ok {
+		t.Fatal("should fail if the operator address is invalid")
+	}
+	req.Operator = &types.Operator{Address: stranger.Address().Bytes(), Role: "owner"}
+	resp = wsc.Request(req, owner)
+	if resp.Ok {
+		t.Fatal("should fail if the operator role is invalid")
+	}
+	req.Operator.Role = types.OperatorViewer
+	resp = wsc.Request(req, owner)
+	if !resp.Ok {
+		t.Fatalf("should set a valid operator: %s", resp.Message)
+	}
+	// only admins can manage operators
+	req.EntityID = entityID
+	resp = wsc.Request(req, editor)
+	if resp.Ok {
+		t.Fatal("editors should not be able to set operators")
+	}
+
+	var req2 types.APIrequest
+	req2.Method = "removeOperator"
+	req2.Operator = &types.Operator{Address: stranger.Address().Bytes()}
+	// make request
+	resp2 := wsc.Request(req2, owner)
+	if !resp2.Ok || resp2.Count != 1 {
+		t.Fatalf("should remove the operator: %s", resp2.Message)
+	}
+}
+
+func TestAdmin(t *testing.T) {
+	wsc, err := testcommon.NewApiConnection(fmt.Sprintf("http://127.0.0.1:%d/api/admin", api.Port), t)
+	// check connected successfully
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := ethereum.NewSignKeys()
+	s.AddHexKey(testdb.Signers[1].Priv)
+
+	// should fail if not called by an admin
+	var req types.APIrequest
+	req.Method = "listEntities"
+	// make request
+	resp := wsc.Request(req, s)
+	if resp.Ok {
+		t.Fatal("should fail if not called by an admin")
+	}
+
+	// should list the entities with their counts
+	resp = wsc.Request(req, api.Admin)
+	if !resp.Ok {
+		t.Fatalf("should list the entities: %s", resp.Message)
+	}
+	if len(resp.Entities) != 1 || resp.Entities[0].MembersCount == nil || *resp.Entities[0].MembersCount != 10 {
+		t.Fatalf("expected one entity with its counts but got %+v", resp.Entities)
+	}
+	req.ListOptions = &types.ListOptions{Search: "vocdoni", SortBy: "createdAt", Order: "descend", Count: 10}
+	resp = wsc.Request(req, api.Admin)
+	if !resp.Ok {
+		t.Fatalf("should search the entities: %s", resp.Message)
+	}
+	req.ListOptions = &types.ListOptions{SortBy: "merkleRoot"}
+	resp = wsc.Request(req, api.Admin)
+	if resp.Ok {
+		t.Fatal("should fail if the sort field is invalid")
+	}
+
+	var req2 types.APIrequest
+	req2.Method = "countEntities"
+	// make request
+	resp2 := wsc.Request(req2, api.Admin)
+	if !resp2.Ok || resp2.Count != 1 {
+		t.Fatalf("should count the entities: %s", resp2.Message)
+	}
+
+	for _, method := range []string{"authorizeEntity", "deleteEntity"} {
+		var req3 types.APIrequest
+		req3.Method = method
+		// make request
+		resp3 := wsc.Request(req3, api.Admin)
+		if resp3.Ok {
+			t.Fatalf("%s should fail without entity", method)
+		}
+		// the entity of Signers[0] cannot be retrieved
+		signer := ethereum.NewSignKeys()
+		signer.AddHexKey(testdb.Signers[0].Priv)
+		req3.EntityID = signer.Address().Bytes()
+		resp3 = wsc.Request(req3, api.Admin)
+		if resp3.Ok {
+			t.Fatalf("%s should fail if the entity cannot be retrieved", method)
+		}
+		// the entity of Signers[4] is pending approval
+		pending := ethereum.NewSignKeys()
+		pending.AddHexKey(testdb.Signers[4].Priv)
+		req3.EntityID = pending.Address().Bytes()
+		resp3 = wsc.Request(req3, s)
+		if resp3.Ok {
+			t.Fatalf("%s should fail if not called by an admin", method)
+		}
+		resp3 = wsc.Request(req3, api.Admin)
+		if !resp3.Ok {
+			t.Fatalf("%s should success: %s", method, resp3.Message)
+		}
+	}
+
+	// should fail if the entity is already authorized
+	var req5 types.APIrequest
+	req5.Method = "authorizeEntity"
+	req5.EntityID = s.Address().Bytes()
+	// make request
+	resp5 := wsc.Request(req5, api.Admin)
+	if resp5.Ok {
+		t.Fatal("authorizeEntity should fail if the entity is already authorized")
+	}
+
+	// adminEntityList is also restricted to the admins
+	wsc2, err := testcommon.NewApiConnection(fmt.Sprintf("http://127.0.0.1:%d/api/manager", api.Port), t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req4 types.APIrequest
+	req4.Method = "adminEntityList"
+	// make request
+	resp4 := wsc2.Request(req4, s)
+	if resp4.Ok {
+		t.Fatal("adminEntityList should fail if not called by an admin")
+	}
+	resp4 = wsc2.Request(req4, api.Admin)
+	if !resp4.Ok {
+		t.Fatalf("adminEntityList should success: %s", resp4.Message)
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	wsc, err := testcommon.NewApiConnection(fmt.Sprintf("http://127.0.0.1:%d/api/manager", api.Port), t)
+	// check connected successfully
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the entity of Signers[4] is pending approval
+	s := ethereum.NewSignKeys()
+	s.AddHexKey(testdb.Signers[4].Priv)
+	s2 := ethereum.NewSignKeys()
+	s2.AddHexKey(testdb.Signers[2].Priv)
+
+	// should import members up to the sandbox limit
+	var req types.APIrequest
+	req.Method = "importMembers"
+	req.MembersInfo = make([]types.MemberInfo, 2)
+	// make request
+	resp := wsc.Request(req, s)
+	if !resp.Ok {
+		t.Fatalf("should import members while pending approval: %s", resp.Message)
+	}
+
+	// should fail over the sandbox limit of 100 members
+	req.MembersInfo = make([]types.MemberInfo, 101)
+	resp = wsc.Request(req, s)
+	if resp.Ok || !strings.Contains(resp.Message, "pending approval") {
+		t.Fatalf("should fail over the sandbox members limit: %s", resp.Message)
+	}
+	var req2 types.APIrequest
+	req2.Method = "generateTokens"
+	req2.Amount = 101
+	resp2 := wsc.Request(req2, s)
+	if resp2.Ok || !strings.Contains(resp2.Message, "pending approval") {
+		t.Fatalf("generateTokens should fail over the sandbox members limit: %s", resp2.Message)
+	}
+
+	// authorized entities have no limit
+	resp = wsc.Request(req, s2)
+	if !resp.Ok {
+		t.Fatalf("authorized entities should import over the sandbox limit: %s", resp.Message)
+	}
+
+	// should not send emails
+	for _, method := range []string{"sendValidationLinks", "sendVotingLinks"} {
+		var req3 types.APIrequest
+		req3.Method = method
+		// make request
+		resp3 := wsc.Request(req3, s)
+		if resp3.Ok || !strings.Contains(resp3.Message, "pending approval") {
+			t.Fatalf("%s should fail while pending approval: %s", method, resp3.Message)
+		}
+	}
+}
+
+func TestDeleteEntity(t *testing.T) {
+	wsc, err := testcommon.NewApiConnection(fmt.Sprintf("http://127.0.0.1:%d/api/manager", api.Port), t)
+	// check connected successfully
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// should fail if the entity has censuses of active processes
+	s := ethereum.NewSignKeys()
+	s.AddHexKey(testdb.Signers[3].Priv)
+	var req types.APIrequest
+	req.Method = "deleteEntity"
+	// make request
+	resp := wsc.Request(req, s)
+	if resp.Ok {
+		t.Fatal("should fail if the entity has censuses of active processes")
+	}
+
+	// should fail for viewer operators
+	owner := ethereum.NewSignKeys()
+	owner.AddHexKey(testdb.Signers[1].Priv)
+	req.EntityID = owner.Address().Bytes()
+	resp = wsc.Request(req, s)
+	if resp.Ok {
+		t.Fatal("should fail for viewer operators")
+	}
+
+	// should fail for admin operators too, only the entity can delete itself
+	admin := ethereum.NewSignKeys()
+	admin.AddHexKey(testdb.Signers[4].Priv)
+	resp = wsc.Request(req, admin)
+	if resp.Ok {
+		t.Fatal("should fail for admin operators")
+	}
+
+	// should return a confirmation token
+	s2 := ethereum.NewSignKeys()
+	s2.AddHexKey(testdb.Signers[2].Priv)
+	var req2 types.APIrequest
+	req2.Method = "deleteEntity"
+	// make request
+	resp2 := wsc.Request(req2, s2)
+	if !resp2.Ok || len(resp2.ConfirmationToken) == 0 || resp2.Archive != nil {
+		t.Fatalf("should return a confirmation token: %s", resp2.Message)
+	}
+	token := resp2.ConfirmationToken
+
+	// should fail with an invalid token or the token of another entity
+	s3 := ethereum.NewSignKeys()
+	s3.AddHexKey(testdb.Signers[4].Priv)
+	var req3 types.APIrequest
+	req3.Method = "deleteEntity"
+	resp3 := wsc.Request(req3, s3)
+	if !resp3.Ok {
+		t.Fatalf("should return a confirmation token: %s", resp3.Message)
+	}
+	// a token with a tampered expiry is not valid either
+	for _, invalid := range []string{"invalid", "1" + token, resp3.ConfirmationToken} {
+		req2.ConfirmationToken = invalid
+		resp2 = wsc.Request(req2, s2)
+		if resp2.Ok {
+			t.Fatalf("should fail with the invalid token %q", invalid)
+		}
+	}
+
+	// should return the export archive
+	req2.ConfirmationToken = token
+	resp2 = wsc.Request(req2, s2)
+	if !resp2.Ok {
+		t.Fatalf("should delete the entity: %s", resp2.Message)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(resp2.Archive), int64(len(resp2.Archive)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]bool)
+	for _, file := range archive.File {
+		files[file.Name] = true
+	}
+	for _, name := range []string{"entity.json", "members.jsonl", "tags.json", "targets.json", "censuses.json"} {
+		if !files[name] {
+			t.Fatalf("expected %s in the archive but got %v", name, files)
+		}
+	}
+}
+
+func TestQuotas(t *testing.T) {
+	wsc, err := testcommon.NewApiConnection(fmt.Sprintf("http://127.0.0.1:%d/api/manager", api.Port), t)
+	// check connected successfully
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the entity of Signers[3] has a quota of 1 census and 5 emails per day,
+	// and has used them all
+	s := ethereum.NewSignKeys()
+	s.AddHexKey(testdb.Signers[3].Priv)
+
+	// should report the usage against the quotas
+	var req types.APIrequest
+	req.Method = "getUsage"
+	// make request
+	resp := wsc.Request(req, s)
+	if !resp.Ok {
+		t.Fatalf("should return the usage: %s", resp.Message)
+	}
+	expected := types.Quotas{Members: 0, Censuses: 1, EmailsPerDay: 5, FaucetRequestsPerMonth: 10}
+	if resp.Quotas == nil || *resp.Quotas != expected {
+		t.Fatalf("expected quotas %+v but got %+v", expected, resp.Quotas)
+	}
+	if resp.Usage == nil || resp.Usage.Members != 10 || resp.Usage.Censuses != 1 || resp.Usage.EmailsToday != 5 {
+		t.Fatalf("unexpected usage %+v", resp.Usage)
+	}
+	// should fail if the usage cannot be retrieved
+	s2 := ethereum.NewSignKeys()
+	s2.AddHexKey(testdb.Signers[0].Priv)
+	resp = wsc.Request(req, s2)
+	if resp.Ok {
+		t.Fatal("should fail if the usage cannot be retrieved")
+	}
+
+	// should fail over the censuses quota
+	var req2 types.APIrequest
+	req2.Method = "addCensus"
+	req2.TargetID = new(uuid.UUID)
+	*req2.TargetID = uuid.New()
+	req2.CensusID = "d67fb28849af7543f2b0b6bf01bde17613bf7ada"
+	// make request
+	resp2 := wsc.Request(req2, s)
+	if resp2.Ok || !strings.Contains(resp2.Message, "quota") {
+		t.Fatalf("should fail over the censuses quota: %s", resp2.Message)
+	}
+
+	// should fail over the emails quota
+	var req3 types.APIrequest
+	req3.Method = "sendVotingLinks"
+	req3.MemberID = new(uuid.UUID)
+	req3.ProcessID = []byte{1, 2, 3}
+	req3.CensusID = "d67fb28849af7543f2b0b6bf01bde17613bf7ada"
+	req3.Email = "john@vocdoni.io"
+	// make request
+	resp3 := wsc.Request(req3, s)
+	if resp3.Ok || !strings.Contains(resp3.Message, "quota") {
+		t.Fatalf("should fail over the emails quota: %s", resp3.Message)
+	}
+
+	// admins can set the quotas
+	wsc2, err := testcommon.NewApiConnection(fmt.Sprintf("http://127.0.0.1:%d/api/admin", api.Port), t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req4 types.APIrequest
+	req4.Method = "setQuotas"
+	req4.EntityID = s.Address().Bytes()
+	req4.Quotas = &types.Quotas{Censuses: 10, FaucetRequestsPerMonth: -1}
+	// make request
+	resp4 := wsc2.Request(req4, api.Admin)
+	if resp4.Ok {
+		t.Fatal("should fail if the quotas are negative")
+	}
+	req4.Quotas.FaucetRequestsPerMonth = 0
+	resp4 = wsc2.Request(req4, s)
+	if resp4.Ok {
+		t.Fatal("should fail if not called by an admin")
+	}
+	resp4 = wsc2.Request(req4, api.Admin)
+	if !resp4.Ok {
+		t.Fatalf("should set the quotas: %s", resp4.Message)
+	}
+}
+
 func TestListMembers(t *testing.T) {
 	// connect to endpoint
 	wsc, err := testcommon.NewApiConnection(fmt.Sprintf("http://127.0.0.1:%d/api/manager", api.Port), t)
@@ -197,6 +611,19 @@ func TestListMembers(t *testing.T) {
 	if !resp4.Ok {
 		t.Fatal("should success if all correct")
 	}
+
+	// should fail if the cursor is malformed
+	var req5 types.APIrequest
+	req5.Method = "listMembers"
+	req5.ListOptions = &types.ListOptions{
+		Count:  10,
+		Cursor: "0x",
+	}
+	// make request
+	resp5 := wsc.Request(req5, s3)
+	if resp5.Ok {
+		t.Fatal("should fail if invalid cursor")
+	}
 }
 
 func TestGetMember(t *testing.T) {
@@ -280,7 +707,7 @@ func TestGetMember(t *testing.T) {
 	}
 }
 
-func TestUpdateMember(t *testing.T) {
+func TestGetMemberHistory(t *testing.T) {
 	// connect to endpoint
 	wsc, err := testcommon.NewApiConnection(fmt.Sprintf("http://127.0.0.1:%d/api/manager", api.Port), t)
 	// check connected successfully
@@ -288,67 +715,441 @@ func TestUpdateMember(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	// should fail if no member found
+	// should fail if no member ID is provided
 	s := ethereum.NewSignKeys()
-	s.AddHexKey(testdb.Signers[0].Priv)
+	s.AddHexKey(testdb.Signers[1].Priv)
 	var req types.APIrequest
-	req.Method = "updateMember"
-	req.Member = &types.Member{}
-	// make request
+	req.Method = "getMemberHistory"
 	resp := wsc.Request(req, s)
-	// check register went successful
 	if resp.Ok {
-		t.Fatal("should fail if update fails on db")
+		t.Fatal("should fail if no member ID is provided")
 	}
 
-	// should update member
+	// should fail if cannot get member events from db
 	s2 := ethereum.NewSignKeys()
-	s2.AddHexKey(testdb.Signers[1].Priv)
+	s2.AddHexKey(testdb.Signers[0].Priv)
 	var req2 types.APIrequest
-	req2.Method = "updateMember"
-	req2.Member = &types.Member{}
-	// make request
+	req2.Method = "getMemberHistory"
+	u := uuid.New()
+	req2.MemberID = &u
 	resp2 := wsc.Request(req2, s2)
-	// check register went successful
-	if !resp2.Ok {
-		t.Fatal("should update member")
+	if resp2.Ok {
+		t.Fatal("should fail if cannot get member events from db")
+	}
+
+	// should return the member events
+	var req3 types.APIrequest
+	req3.Method = "getMemberHistory"
+	req3.MemberID = &u
+	resp3 := wsc.Request(req3, s)
+	if !resp3.Ok {
+		t.Fatalf("should return the member history: %s", resp3.Message)
+	}
+	if len(resp3.Events) != 2 {
+		t.Fatalf("expected 2 events but got %d", len(resp3.Events))
+	}
+	if resp3.Events[1].Operation != types.MemberEventUpdate || resp3.Events[1].MemberID != u {
+		t.Fatalf("unexpected event %+v", resp3.Events[1])
 	}
 }
 
-func TestDeleteMembers(t *testing.T) {
+func TestMemberData(t *testing.T) {
 	wsc, err := testcommon.NewApiConnection(fmt.Sprintf("http://127.0.0.1:%d/api/manager", api.Port), t)
 	// check connected successfully
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	// should fail if db delete member fails
 	s := ethereum.NewSignKeys()
 	s.AddHexKey(testdb.Signers[0].Priv)
-	var req types.APIrequest
-	req.Method = "deleteMembers"
-	req.MemberIDs = []uuid.UUID{uuid.New()}
-	// *req.MemberID = uuid.New()
-	// make request
-	resp := wsc.Request(req, s)
-	// check register went successful
-	if resp.Ok {
-		t.Fatal("should fail if update fails on db")
-	}
-
-	// otherwise should success
 	s2 := ethereum.NewSignKeys()
 	s2.AddHexKey(testdb.Signers[1].Priv)
-	var req2 types.APIrequest
-	req2.Method = "deleteMembers"
-	req2.MemberIDs = []uuid.UUID{uuid.New()}
-	// make request
-	resp2 := wsc.Request(req2, s2)
-	// check register went successful
-	if !resp2.Ok {
-		t.Fatal("should succeed")
-	}
-}
+	s3 := ethereum.NewSignKeys()
+	s3.AddHexKey(testdb.Signers[2].Priv)
+
+	for _, method := range []string{"exportMemberData", "eraseMember"} {
+		// should fail without a member ID
+		var req types.APIrequest
+		req.Method = method
+		resp := wsc.Request(req, s3)
+		if resp.Ok {
+			t.Fatalf("%s should fail without member ID", method)
+		}
+
+		// should fail if db fails
+		memberID := uuid.New()
+		req.MemberID = &memberID
+		resp = wsc.Request(req, s)
+		if resp.Ok {
+			t.Fatalf("%s should fail if db fails", method)
+		}
+
+		// should fail if the member does not exist
+		resp = wsc.Request(req, s2)
+		if resp.Ok || resp.Message != "member not found" {
+			t.Fatalf("%s should fail if the member is not found: %s", method, resp.Message)
+		}
+
+		// otherwise should success
+		resp = wsc.Request(req, s3)
+		if !resp.Ok {
+			t.Fatalf("%s should success: %s", method, resp.Message)
+		}
+		if method == "exportMemberData" {
+			if resp.MemberData == nil || resp.MemberData.Member == nil || resp.MemberData.Member.ID != memberID {
+				t.Fatalf("unexpected member data %+v", resp.MemberData)
+			}
+			if len(resp.MemberData.Tags) != 1 || len(resp.MemberData.Emails) != 1 || len(resp.MemberData.Events) != 1 {
+				t.Fatalf("unexpected member data %+v", resp.MemberData)
+			}
+		}
+	}
+}
+
+func TestUpdateMember(t *testing.T) {
+	// connect to endpoint
+	wsc, err := testcommon.NewApiConnection(fmt.Sprintf("http://127.0.0.1:%d/api/manager", api.Port), t)
+	// check connected successfully
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// should fail if no member found
+	s := ethereum.NewSignKeys()
+	s.AddHexKey(testdb.Signers[0].Priv)
+	var req types.APIrequest
+	req.Method = "updateMember"
+	req.Member = &types.Member{}
+	// make request
+	resp := wsc.Request(req, s)
+	// check register went successful
+	if resp.Ok {
+		t.Fatal("should fail if update fails on db")
+	}
+
+	// should update member
+	s2 := ethereum.NewSignKeys()
+	s2.AddHexKey(testdb.Signers[1].Priv)
+	var req2 types.APIrequest
+	req2.Method = "updateMember"
+	req2.Member = &types.Member{}
+	// make request
+	resp2 := wsc.Request(req2, s2)
+	// check register went successful
+	if !resp2.Ok {
+		t.Fatal("should update member")
+	}
+}
+
+func TestCustomFieldsSchema(t *testing.T) {
+	wsc, err := testcommon.NewApiConnection(fmt.Sprintf("http://127.0.0.1:%d/api/manager", api.Port), t)
+	// check connected successfully
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the entity of Signers[3] has a schema with a required number "shares"
+	// and an optional enum "branch"
+	s := ethereum.NewSignKeys()
+	s.AddHexKey(testdb.Signers[3].Priv)
+
+	// should fail if the schema is invalid
+	var req types.APIrequest
+	req.Method = "updateEntity"
+	req.Entity = &types.EntityInfo{CustomFieldsSchema: types.CustomFieldsSchema{{Name: "branch", Type: types.CustomFieldEnum}}}
+	// make request
+	resp := wsc.Request(req, s)
+	if resp.Ok {
+		t.Fatal("should fail if the schema is invalid")
+	}
+	req.Entity.CustomFieldsSchema[0].Values = []string{"Barcelona"}
+	resp = wsc.Request(req, s)
+	if !resp.Ok {
+		t.Fatalf("should update a valid schema: %s", resp.Message)
+	}
+
+	// listMembers should return the schema
+	var req2 types.APIrequest
+	req2.Method = "listMembers"
+	// make request
+	resp2 := wsc.Request(req2, s)
+	if !resp2.Ok {
+		t.Fatalf("should success: %s", resp2.Message)
+	}
+	if len(resp2.CustomFieldsSchema) != 2 {
+		t.Fatalf("expected the custom fields schema but got %+v", resp2.CustomFieldsSchema)
+	}
+
+	// imported and updated members should fulfill the schema
+	for i, tc := range []struct {
+		customFields string
+		valid        bool
+	}{
+		{`{"shares": 10, "branch": "Girona"}`, true},
+		{`{"shares": 10}`, true},
+		{``, false},
+		{`{"branch": "Girona"}`, false},
+		{`{"shares": "10"}`, false},
+		{`{"shares": 10, "branch": "Lleida"}`, false},
+		{`{"shares": 10, "brnch": "Girona"}`, false},
+		{`[10]`, false},
+	} {
+		var req3 types.APIrequest
+		req3.Method = "importMembers"
+		req3.MembersInfo = []types.MemberInfo{{CustomFields: json.RawMessage(`{"shares": 1}`)}, {CustomFields: json.RawMessage(tc.customFields)}}
+		// make request
+		resp3 := wsc.Request(req3, s)
+		if resp3.Ok != tc.valid {
+			t.Fatalf("%d: expected importMembers valid %v but got %q", i, tc.valid, resp3.Message)
+		}
+		if len(tc.customFields) == 0 {
+			// updates without custom fields leave them untouched
+			continue
+		}
+		var req4 types.APIrequest
+		req4.Method = "updateMember"
+		req4.Member = &types.Member{MemberInfo: types.MemberInfo{CustomFields: json.RawMessage(tc.customFields)}}
+		// make request
+		resp4 := wsc.Request(req4, s)
+		if resp4.Ok != tc.valid {
+			t.Fatalf("%d: expected updateMember valid %v but got %q", i, tc.valid, resp4.Message)
+		}
+	}
+
+	// CSV values should be converted to the type of the fields
+	var req5 types.APIrequest
+	req5.Method = "importMembersCSV"
+	req5.CSV = "Mail,shares,branch\n" +
+		"john@vocdoni.io,10,Girona\n" +
+		"jane@vocdoni.io,ten,Girona\n" +
+		"joan@vocdoni.io,,Barcelona\n" +
+		"marta@vocdoni.io,NaN,Girona\n" +
+		"pere@vocdoni.io,Inf,Girona\n"
+	req5.ColumnMapping = map[string]string{"Mail": "email"}
+	// make request
+	resp5 := wsc.Request(req5, s)
+	if !resp5.Ok {
+		t.Fatalf("should success: %s", resp5.Message)
+	}
+	if resp5.Count != 1 || len(resp5.RejectedRows) != 4 {
+		t.Fatalf("expected 1 imported member and 4 rejected rows but got %d and %+v", resp5.Count, resp5.RejectedRows)
+	}
+
+	// target filters should be consistent with the schema
+	for i, tc := range []struct {
+		filters string
+		valid   bool
+	}{
+		{`{"customFields": [{"path": "shares", "op": "gt", "value": 10}]}`, true},
+		{`{"customFields": [{"path": "branch", "op": "in", "value": ["Barcelona", "Girona"]}]}`, true},
+		{`{"customFields": [{"path": "branch", "op": "gt", "value": 10}]}`, false},
+		{`{"customFields": [{"path": "branch", "op": "eq", "value": "Lleida"}]}`, false},
+		{`{"customFields": [{"path": "branch.city", "op": "exists"}]}`, false},
+		{`{"customFields": [{"path": "city", "op": "exists"}]}`, false},
+	} {
+		var req6 types.APIrequest
+		req6.Method = "addTarget"
+		req6.Target = &types.Target{Name: fmt.Sprintf("target%d", i), Filters: json.RawMessage(tc.filters)}
+		// make request
+		resp6 := wsc.Request(req6, s)
+		if resp6.Ok != tc.valid {
+			t.Fatalf("%d: expected addTarget valid %v but got %q", i, tc.valid, resp6.Message)
+		}
+	}
+}
+
+func TestRetention(t *testing.T) {
+	wsc, err := testcommon.NewApiConnection(fmt.Sprintf("http://127.0.0.1:%d/api/manager", api.Port), t)
+	// check connected successfully
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := ethereum.NewSignKeys()
+	s.AddHexKey(testdb.Signers[1].Priv)
+
+	// should fail if a retention period is negative
+	var req types.APIrequest
+	req.Method = "updateEntity"
+	req.Entity = &types.EntityInfo{Retention: &types.RetentionSettings{UnverifiedMembersDays: -1}}
+	// make request
+	resp := wsc.Request(req, s)
+	if resp.Ok {
+		t.Fatal("should fail if a retention period is negative")
+	}
+
+	// should update valid retention settings
+	req.Entity.Retention = &types.RetentionSettings{UnverifiedMembersDays: 30, EphemeralKeysDays: 7}
+	resp = wsc.Request(req, s)
+	if !resp.Ok {
+		t.Fatalf("should update valid retention settings: %s", resp.Message)
+	}
+}
+
+func TestDeleteMembers(t *testing.T) {
+	wsc, err := testcommon.NewApiConnection(fmt.Sprintf("http://127.0.0.1:%d/api/manager", api.Port), t)
+	// check connected successfully
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// should fail if db delete member fails
+	s := ethereum.NewSignKeys()
+	s.AddHexKey(testdb.Signers[0].Priv)
+	var req types.APIrequest
+	req.Method = "deleteMembers"
+	req.MemberIDs = []uuid.UUID{uuid.New()}
+	// *req.MemberID = uuid.New()
+	// make request
+	resp := wsc.Request(req, s)
+	// check register went successful
+	if resp.Ok {
+		t.Fatal("should fail if update fails on db")
+	}
+
+	// otherwise should success
+	s2 := ethereum.NewSignKeys()
+	s2.AddHexKey(testdb.Signers[1].Priv)
+	var req2 types.APIrequest
+	req2.Method = "deleteMembers"
+	req2.MemberIDs = []uuid.UUID{uuid.New()}
+	// make request
+	resp2 := wsc.Request(req2, s2)
+	// check register went successful
+	if !resp2.Ok {
+		t.Fatal("should succeed")
+	}
+}
+
+func TestDeletedMembers(t *testing.T) {
+	wsc, err := testcommon.NewApiConnection(fmt.Sprintf("http://127.0.0.1:%d/api/manager", api.Port), t)
+	// check connected successfully
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := ethereum.NewSignKeys()
+	s.AddHexKey(testdb.Signers[0].Priv)
+	s2 := ethereum.NewSignKeys()
+	s2.AddHexKey(testdb.Signers[1].Priv)
+
+	// should fail if db list deleted members fails
+	var req types.APIrequest
+	req.Method = "listDeletedMembers"
+	resp := wsc.Request(req, s)
+	if resp.Ok {
+		t.Fatal("should fail if list deleted members fails on db")
+	}
+
+	// should list the deleted members
+	resp = wsc.Request(req, s2)
+	if !resp.Ok {
+		t.Fatalf("should list deleted members: %s", resp.Message)
+	}
+	if len(resp.Members) != 1 || resp.Members[0].DeletedAt == nil {
+		t.Fatalf("unexpected deleted members %+v", resp.Members)
+	}
+
+	// should fail without members to restore
+	var req2 types.APIrequest
+	req2.Method = "restoreMembers"
+	resp = wsc.Request(req2, s2)
+	if resp.Ok {
+		t.Fatal("should fail without member IDs")
+	}
+
+	// should fail if db restore fails
+	req2.MemberIDs = []uuid.UUID{uuid.New()}
+	resp = wsc.Request(req2, s)
+	if resp.Ok {
+		t.Fatal("should fail if restore fails on db")
+	}
+
+	// otherwise should succeed
+	resp = wsc.Request(req2, s2)
+	if !resp.Ok || resp.Count != 1 {
+		t.Fatalf("should restore members: %s", resp.Message)
+	}
+}
+
+func TestUpdateMembers(t *testing.T) {
+	wsc, err := testcommon.NewApiConnection(fmt.Sprintf("http://127.0.0.1:%d/api/manager", api.Port), t)
+	// check connected successfully
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := ethereum.NewSignKeys()
+	s.AddHexKey(testdb.Signers[0].Priv)
+	s2 := ethereum.NewSignKeys()
+	s2.AddHexKey(testdb.Signers[1].Priv)
+	s4 := ethereum.NewSignKeys()
+	s4.AddHexKey(testdb.Signers[3].Priv)
+
+	// should fail without a patch
+	var req types.APIrequest
+	req.Method = "updateMembers"
+	req.MemberIDs = []uuid.UUID{uuid.New(), uuid.New()}
+	resp := wsc.Request(req, s2)
+	if resp.Ok {
+		t.Fatal("should fail without a member patch")
+	}
+
+	// should fail with an empty patch
+	req.MemberPatch = &types.MemberPatch{}
+	resp = wsc.Request(req, s2)
+	if resp.Ok {
+		t.Fatal("should fail with an empty member patch")
+	}
+
+	// should fail with both members and a target
+	consented := false
+	req.MemberPatch = &types.MemberPatch{Consented: &consented}
+	targetID := uuid.New()
+	req.TargetID = &targetID
+	resp = wsc.Request(req, s2)
+	if resp.Ok {
+		t.Fatal("should fail with both a member list and a target")
+	}
+	req.TargetID = nil
+
+	// should fail if db update fails
+	resp = wsc.Request(req, s)
+	if resp.Ok {
+		t.Fatal("should fail if update fails on db")
+	}
+
+	// should update the members
+	resp = wsc.Request(req, s2)
+	if !resp.Ok || resp.Count != 2 {
+		t.Fatalf("should update members: %s", resp.Message)
+	}
+
+	// should fail if the target is not found
+	req.MemberIDs = nil
+	req.TargetID = &targetID
+	resp = wsc.Request(req, s2)
+	if resp.Ok {
+		t.Fatal("should fail if the target is not found")
+	}
+
+	// should validate the custom fields against the schema
+	req.TargetID = nil
+	req.MemberIDs = []uuid.UUID{uuid.New()}
+	req.MemberPatch = &types.MemberPatch{CustomFields: json.RawMessage(`{"shares": null}`)}
+	resp = wsc.Request(req, s4)
+	if resp.Ok {
+		t.Fatal("should fail removing a required custom field")
+	}
+	req.MemberPatch = &types.MemberPatch{CustomFields: json.RawMessage(`{"unknown": 1}`)}
+	resp = wsc.Request(req, s4)
+	if resp.Ok {
+		t.Fatal("should fail with an unknown custom field")
+	}
+	req.MemberPatch = &types.MemberPatch{CustomFields: json.RawMessage(`{"shares": 3, "branch": null}`)}
+	resp = wsc.Request(req, s4)
+	if !resp.Ok || resp.Count != 1 {
+		t.Fatalf("should update custom fields: %s", resp.Message)
+	}
+}
 
 func TestCountMembers(t *testing.T) {
 	wsc, err := testcommon.NewApiConnection(fmt.Sprintf("http://127.0.0.1:%d/api/manager", api.Port), t)
@@ -380,6 +1181,26 @@ func TestCountMembers(t *testing.T) {
 	if !resp2.Ok {
 		t.Fatal("should success")
 	}
+
+	// should accept a search
+	var req3 types.APIrequest
+	req3.Method = "countMembers"
+	req3.ListOptions = &types.ListOptions{Search: "john smith"}
+	// make request
+	resp3 := wsc.Request(req3, s2)
+	if !resp3.Ok {
+		t.Fatal("should success with a search")
+	}
+
+	// should fail if the search is too long
+	var req4 types.APIrequest
+	req4.Method = "countMembers"
+	req4.ListOptions = &types.ListOptions{Search: strings.Repeat("a", 200)}
+	// make request
+	resp4 := wsc.Request(req4, s2)
+	if resp4.Ok {
+		t.Fatal("should fail if the search is too long")
+	}
 }
 
 func TestGenerateTokens(t *testing.T) {
@@ -399,28 +1220,418 @@ func TestGenerateTokens(t *testing.T) {
 	resp := wsc.Request(req, s)
 	// check register went successful
 	if resp.Ok {
-		t.Fatal("should fail if invalid amount")
+		t.Fatal("should fail if invalid amount")
+	}
+
+	// should fail if cannot generate members with tokens
+	s2 := ethereum.NewSignKeys()
+	s2.AddHexKey(testdb.Signers[1].Priv)
+	var req2 types.APIrequest
+	req2.Method = "generateTokens"
+	req2.Amount = 2
+	// make request
+	resp2 := wsc.Request(req2, s2)
+	// check register went successful
+	if resp2.Ok {
+		t.Fatal("should fail if cannot generate members")
+	}
+
+	// otherwise should success
+	s3 := ethereum.NewSignKeys()
+	s3.AddHexKey(testdb.Signers[2].Priv)
+	var req3 types.APIrequest
+	req3.Method = "generateTokens"
+	req3.Amount = 2
+	// make request
+	resp3 := wsc.Request(req3, s3)
+	// check register went successful
+	if !resp3.Ok {
+		t.Fatal("should success")
+	}
+}
+
+func TestExportTokens(t *testing.T) {
+	wsc, err := testcommon.NewApiConnection(fmt.Sprintf("http://127.0.0.1:%d/api/manager", api.Port), t)
+	// check connected successfully
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// should fail if db MembersTokensEmails fails
+	s := ethereum.NewSignKeys()
+	s.AddHexKey(testdb.Signers[0].Priv)
+	var req types.APIrequest
+	req.Method = "exportTokens"
+	// make request
+	resp := wsc.Request(req, s)
+	// check register went successful
+	if resp.Ok {
+		t.Fatal("should fail if db members tokens emails fails")
+	}
+
+	// should fail if no rows returned from db MembersTokensEmails
+	s2 := ethereum.NewSignKeys()
+	s2.AddHexKey(testdb.Signers[1].Priv)
+	var req2 types.APIrequest
+	req2.Method = "exportTokens"
+	// make request
+	resp2 := wsc.Request(req2, s2)
+	// check register went successful
+	if resp2.Ok {
+		t.Fatal("should fail if db members tokens emails returns no rows")
+	}
+
+	// otherwise should sucesss
+	// should fail if db MembersTokensEmails fails
+	s3 := ethereum.NewSignKeys()
+	s3.AddHexKey(testdb.Signers[2].Priv)
+	var req3 types.APIrequest
+	req3.Method = "exportTokens"
+	// make request
+	resp3 := wsc.Request(req3, s3)
+	// check register went successful
+	if !resp3.Ok {
+		t.Fatal("should success")
+	}
+}
+
+func TestImportMembers(t *testing.T) {
+	wsc, err := testcommon.NewApiConnection(fmt.Sprintf("http://127.0.0.1:%d/api/manager", api.Port), t)
+	// check connected successfully
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// should fail if members info < 1
+	s := ethereum.NewSignKeys()
+	s.AddHexKey(testdb.Signers[0].Priv)
+	var req types.APIrequest
+	req.Method = "importMembers"
+	req.MembersInfo = []types.MemberInfo{}
+	// make request
+	resp := wsc.Request(req, s)
+	// check register went successful
+	if resp.Ok {
+		t.Fatal("should fail if members info < 1")
+	}
+
+	// should fail if db import members fails
+	s2 := ethereum.NewSignKeys()
+	s2.AddHexKey(testdb.Signers[1].Priv)
+	var req2 types.APIrequest
+	req2.Method = "importMembers"
+	req2.MembersInfo = make([]types.MemberInfo, 2)
+	// make request
+	resp2 := wsc.Request(req2, s2)
+	// check register went successful
+	if resp2.Ok {
+		t.Fatal("should fail if db import members fails")
+	}
+
+	// otherwise should success
+	s3 := ethereum.NewSignKeys()
+	s3.AddHexKey(testdb.Signers[2].Priv)
+	var req3 types.APIrequest
+	req3.Method = "importMembers"
+	req3.MembersInfo = make([]types.MemberInfo, 2)
+	// make request
+	resp3 := wsc.Request(req3, s3)
+	// check register went successful
+	if !resp3.Ok {
+		t.Fatal("should success")
+	}
+}
+
+func TestImportMembersCSV(t *testing.T) {
+	wsc, err := testcommon.NewApiConnection(fmt.Sprintf("http://127.0.0.1:%d/api/manager", api.Port), t)
+	// check connected successfully
+	if err != nil {
+		t.Fatal(err)
+	}
+	csv := "Name,Surname,Mail,Birth,Branch\n" +
+		"John,Smith,john@vocdoni.io,1980-01-01,Barcelona\n" +
+		"Jane,Doe,not-an-email,,Girona\n" +
+		"\"Joan\nMaria\",Garcia,joan@vocdoni.io,,\n" +
+		"\n" +
+		"Jordi,Puig,john@vocdoni.io,,\n" +
+		"Anna,Roca,existing@vocdoni.io,,\n" +
+		"Marta,Vila,marta@vocdoni.io,01/01/1990,\n" +
+		"Pere,Font\n"
+	mapping := map[string]string{"Name": "firstName", "Surname": "lastName", "Mail": "email", "Birth": "dateOfBirth"}
+
+	// should fail if the mapping is invalid
+	s := ethereum.NewSignKeys()
+	s.AddHexKey(testdb.Signers[2].Priv)
+	var req types.APIrequest
+	req.Method = "importMembersCSV"
+	req.CSV = csv
+	req.ColumnMapping = map[string]string{"Name": "nickname"}
+	// make request
+	resp := wsc.Request(req, s)
+	if resp.Ok {
+		t.Fatal("should fail if the mapping is invalid")
+	}
+
+	// should fail if db ImportMembers fails
+	s2 := ethereum.NewSignKeys()
+	s2.AddHexKey(testdb.Signers[1].Priv)
+	var req2 types.APIrequest
+	req2.Method = "importMembersCSV"
+	req2.CSV = csv
+	req2.ColumnMapping = mapping
+	// make request
+	resp2 := wsc.Request(req2, s2)
+	if resp2.Ok {
+		t.Fatal("should fail if db ImportMembers fails")
+	}
+
+	// otherwise should import the valid rows and report the rest
+	var req3 types.APIrequest
+	req3.Method = "importMembersCSV"
+	req3.CSV = csv
+	req3.ColumnMapping = mapping
+	// make request
+	resp3 := wsc.Request(req3, s)
+	if !resp3.Ok {
+		t.Fatalf("should success: %s", resp3.Message)
+	}
+	if resp3.Count != 2 {
+		t.Fatalf("expected 2 imported members but got %d", resp3.Count)
+	}
+	expectedLines := []int{3, 7, 8, 9, 10}
+	if len(resp3.RejectedRows) != len(expectedLines) {
+		t.Fatalf("expected %d rejected rows but got %+v", len(expectedLines), resp3.RejectedRows)
+	}
+	for i, row := range resp3.RejectedRows {
+		if row.Line != expectedLines[i] || len(row.Reason) == 0 {
+			t.Fatalf("expected rejected line %d but got %+v", expectedLines[i], row)
+		}
+	}
+}
+
+func TestExportMembers(t *testing.T) {
+	wsc, err := testcommon.NewApiConnection(fmt.Sprintf("http://127.0.0.1:%d/api/manager", api.Port), t)
+	// check connected successfully
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// should fail if the format is invalid
+	s := ethereum.NewSignKeys()
+	s.AddHexKey(testdb.Signers[2].Priv)
+	var req types.APIrequest
+	req.Method = "exportMembers"
+	req.Format = "xlsx"
+	// make request
+	resp := wsc.Request(req, s)
+	if resp.Ok {
+		t.Fatal("should fail if the format is invalid")
+	}
+
+	// should fail if db Target fails
+	s2 := ethereum.NewSignKeys()
+	s2.AddHexKey(testdb.Signers[0].Priv)
+	var req2 types.APIrequest
+	req2.Method = "exportMembers"
+	targetID := uuid.New()
+	req2.TargetID = &targetID
+	// make request
+	resp2 := wsc.Request(req2, s2)
+	if resp2.Ok {
+		t.Fatal("should fail if db Target fails")
+	}
+
+	// should fail if db StreamMembers fails
+	s3 := ethereum.NewSignKeys()
+	s3.AddHexKey(testdb.Signers[1].Priv)
+	var req3 types.APIrequest
+	req3.Method = "exportMembers"
+	// make request
+	resp3 := wsc.Request(req3, s3)
+	if resp3.Ok {
+		t.Fatal("should fail if db StreamMembers fails")
+	}
+
+	// should export as CSV by default, flattening the custom fields
+	var req4 types.APIrequest
+	req4.Method = "exportMembers"
+	// make request
+	resp4 := wsc.Request(req4, s)
+	if !resp4.Ok {
+		t.Fatalf("should success: %s", resp4.Message)
+	}
+	if resp4.Count != 2 {
+		t.Fatalf("expected 2 exported members but got %d", resp4.Count)
+	}
+	lines := strings.Split(strings.TrimSpace(resp4.Export), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected header and 2 rows but got %q", resp4.Export)
+	}
+	if !strings.HasSuffix(lines[0], ",registered,tags,createdAt,branch.city,shares") {
+		t.Fatalf("unexpected CSV header %q", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], ",true,,,Barcelona,10") {
+		t.Fatalf("unexpected CSV row %q", lines[1])
+	}
+
+	// should export as JSON Lines
+	var req5 types.APIrequest
+	req5.Method = "exportMembers"
+	req5.Format = "jsonl"
+	// make request
+	resp5 := wsc.Request(req5, s)
+	if !resp5.Ok {
+		t.Fatalf("should success: %s", resp5.Message)
+	}
+	lines = strings.Split(strings.TrimSpace(resp5.Export), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines but got %q", resp5.Export)
+	}
+	var member map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[1]), &member); err != nil {
+		t.Fatal(err)
+	}
+	if member["registered"] != false || member["firstName"] != "Jane" {
+		t.Fatalf("unexpected exported member %s", lines[1])
+	}
+}
+
+func TestCountTargets(t *testing.T) {
+	wsc, err := testcommon.NewApiConnection(fmt.Sprintf("http://127.0.0.1:%d/api/manager", api.Port), t)
+	// check connected successfully
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// should fail if db countTargets fails
+	s := ethereum.NewSignKeys()
+	s.AddHexKey(testdb.Signers[0].Priv)
+	var req types.APIrequest
+	req.Method = "countTargets"
+	// make request
+	resp := wsc.Request(req, s)
+	// check register went successful
+	if resp.Ok {
+		t.Fatal("should fail if db count targets fails")
+	}
+
+	// otherwise should success
+	s2 := ethereum.NewSignKeys()
+	s2.AddHexKey(testdb.Signers[1].Priv)
+	var req2 types.APIrequest
+	req2.Method = "countTargets"
+	// make request
+	resp2 := wsc.Request(req2, s2)
+	// check register went successful
+	if !resp2.Ok {
+		t.Fatal("should success")
+	}
+}
+
+func TestListTargets(t *testing.T) {
+	wsc, err := testcommon.NewApiConnection(fmt.Sprintf("http://127.0.0.1:%d/api/manager", api.Port), t)
+	// check connected successfully
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// should fail if invalid list options
+	s := ethereum.NewSignKeys()
+	s.AddHexKey(testdb.Signers[0].Priv)
+	var req types.APIrequest
+	req.Method = "listTargets"
+	req.ListOptions = &types.ListOptions{
+		Count:  10,
+		Order:  "0x",
+		Skip:   2,
+		SortBy: "lastName",
+	}
+	// make request
+	resp := wsc.Request(req, s)
+	// check register went successful
+	if resp.Ok {
+		t.Fatal("should fail if invalid list options")
+	}
+
+	// should fail if listTargets returns no rows
+	s2 := ethereum.NewSignKeys()
+	s2.AddHexKey(testdb.Signers[1].Priv)
+	var req2 types.APIrequest
+	req2.Method = "listTargets"
+	req2.ListOptions = &types.ListOptions{
+		Count:  10,
+		Order:  "ascend",
+		Skip:   2,
+		SortBy: "lastName",
+	}
+	// make request
+	resp2 := wsc.Request(req2, s2)
+	// check register went successful
+	if resp2.Ok {
+		t.Fatal("should fail if not members found")
+	}
+
+	// should fail if db listTargets fails
+	// should fail db list members fails
+	var req3 types.APIrequest
+	req3.Method = "listTargets"
+	req3.ListOptions = &types.ListOptions{
+		Count:  10,
+		Order:  "ascend",
+		Skip:   2,
+		SortBy: "lastName",
+	}
+	// make request
+	resp3 := wsc.Request(req3, s)
+	// check register went successful
+	if resp3.Ok {
+		t.Fatal("should fail if db list targets fail")
+	}
+
+}
+
+func TestGetTarget(t *testing.T) {
+	wsc, err := testcommon.NewApiConnection(fmt.Sprintf("http://127.0.0.1:%d/api/manager", api.Port), t)
+	// check connected successfully
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// should fail if target not found
+	s := ethereum.NewSignKeys()
+	s.AddHexKey(testdb.Signers[0].Priv)
+	var req types.APIrequest
+	req.Method = "getTarget"
+	req.TargetID = new(uuid.UUID)
+	*req.TargetID = uuid.New()
+	// make request
+	resp := wsc.Request(req, s)
+	// check register went successful
+	if resp.Ok {
+		t.Fatal("should fail if db Target fails")
 	}
 
-	// should fail if cannot generate members with tokens
+	// should fail if db targetMembers fail
 	s2 := ethereum.NewSignKeys()
 	s2.AddHexKey(testdb.Signers[1].Priv)
 	var req2 types.APIrequest
-	req2.Method = "generateTokens"
-	req2.Amount = 2
+	req2.Method = "getTarget"
+	req2.TargetID = new(uuid.UUID)
+	*req2.TargetID = uuid.New()
 	// make request
 	resp2 := wsc.Request(req2, s2)
 	// check register went successful
 	if resp2.Ok {
-		t.Fatal("should fail if cannot generate members")
+		t.Fatal("should fail if db Target members fails")
 	}
 
 	// otherwise should success
 	s3 := ethereum.NewSignKeys()
 	s3.AddHexKey(testdb.Signers[2].Priv)
 	var req3 types.APIrequest
-	req3.Method = "generateTokens"
-	req3.Amount = 2
+	req3.Method = "getTarget"
+	req3.TargetID = new(uuid.UUID)
+	*req3.TargetID = uuid.New()
 	// make request
 	resp3 := wsc.Request(req3, s3)
 	// check register went successful
@@ -429,43 +1640,46 @@ func TestGenerateTokens(t *testing.T) {
 	}
 }
 
-func TestExportTokens(t *testing.T) {
+func TestDumpTarget(t *testing.T) {
 	wsc, err := testcommon.NewApiConnection(fmt.Sprintf("http://127.0.0.1:%d/api/manager", api.Port), t)
 	// check connected successfully
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	// should fail if db MembersTokensEmails fails
+	// should fail if db target fails
 	s := ethereum.NewSignKeys()
 	s.AddHexKey(testdb.Signers[0].Priv)
 	var req types.APIrequest
-	req.Method = "exportTokens"
+	req.Method = "dumpTarget"
+	req.TargetID = new(uuid.UUID)
+	*req.TargetID = uuid.New()
 	// make request
 	resp := wsc.Request(req, s)
 	// check register went successful
 	if resp.Ok {
-		t.Fatal("should fail if db members tokens emails fails")
+		t.Fatal("should fail if db Target fails")
 	}
 
-	// should fail if no rows returned from db MembersTokensEmails
+	// should fail if db DumpTargetClaims fails
 	s2 := ethereum.NewSignKeys()
 	s2.AddHexKey(testdb.Signers[1].Priv)
 	var req2 types.APIrequest
-	req2.Method = "exportTokens"
+	req2.Method = "dumpTarget"
+	req2.TargetID = req.TargetID
 	// make request
 	resp2 := wsc.Request(req2, s2)
 	// check register went successful
 	if resp2.Ok {
-		t.Fatal("should fail if db members tokens emails returns no rows")
+		t.Fatal("should fail if db DumpTargetClaims fails")
 	}
 
-	// otherwise should sucesss
-	// should fail if db MembersTokensEmails fails
+	// otherwise should success
 	s3 := ethereum.NewSignKeys()
 	s3.AddHexKey(testdb.Signers[2].Priv)
 	var req3 types.APIrequest
-	req3.Method = "exportTokens"
+	req3.Method = "dumpTarget"
+	req3.TargetID = req.TargetID
 	// make request
 	resp3 := wsc.Request(req3, s3)
 	// check register went successful
@@ -474,245 +1688,239 @@ func TestExportTokens(t *testing.T) {
 	}
 }
 
-func TestImportMembers(t *testing.T) {
+func TestAddTarget(t *testing.T) {
 	wsc, err := testcommon.NewApiConnection(fmt.Sprintf("http://127.0.0.1:%d/api/manager", api.Port), t)
 	// check connected successfully
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	// should fail if members info < 1
+	// should fail if the filters are invalid
 	s := ethereum.NewSignKeys()
-	s.AddHexKey(testdb.Signers[0].Priv)
+	s.AddHexKey(testdb.Signers[2].Priv)
 	var req types.APIrequest
-	req.Method = "importMembers"
-	req.MembersInfo = []types.MemberInfo{}
+	req.Method = "addTarget"
+	req.Target = &types.Target{Name: "target", Filters: []byte(`{"origins": ["Unknown"]}`)}
 	// make request
 	resp := wsc.Request(req, s)
-	// check register went successful
 	if resp.Ok {
-		t.Fatal("should fail if members info < 1")
+		t.Fatal("should fail if the filters are invalid")
 	}
 
-	// should fail if db import members fails
+	// should fail if db AddTarget fails
 	s2 := ethereum.NewSignKeys()
 	s2.AddHexKey(testdb.Signers[1].Priv)
 	var req2 types.APIrequest
-	req2.Method = "importMembers"
-	req2.MembersInfo = make([]types.MemberInfo, 2)
+	req2.Method = "addTarget"
+	req2.Target = &types.Target{Name: "target", Filters: []byte(`{"verified": true}`)}
 	// make request
 	resp2 := wsc.Request(req2, s2)
-	// check register went successful
 	if resp2.Ok {
-		t.Fatal("should fail if db import members fails")
+		t.Fatal("should fail if db AddTarget fails")
 	}
 
 	// otherwise should success
 	var req3 types.APIrequest
-	req3.Method = "importMembers"
-	req3.MembersInfo = make([]types.MemberInfo, 2)
+	req3.Method = "addTarget"
+	req3.Target = &types.Target{Name: "target", Filters: []byte(`{"verified": true}`)}
 	// make request
 	resp3 := wsc.Request(req3, s)
-	// check register went successful
 	if !resp3.Ok {
 		t.Fatal("should success")
 	}
 }
 
-func TestCountTargets(t *testing.T) {
+func TestUpdateTarget(t *testing.T) {
 	wsc, err := testcommon.NewApiConnection(fmt.Sprintf("http://127.0.0.1:%d/api/manager", api.Port), t)
 	// check connected successfully
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	// should fail if db countTargets fails
+	// should fail if db UpdateTarget fails
 	s := ethereum.NewSignKeys()
 	s.AddHexKey(testdb.Signers[0].Priv)
 	var req types.APIrequest
-	req.Method = "countTargets"
+	req.Method = "updateTarget"
+	req.TargetID = new(uuid.UUID)
+	*req.TargetID = uuid.New()
+	req.Target = &types.Target{Name: "renamed"}
 	// make request
 	resp := wsc.Request(req, s)
-	// check register went successful
 	if resp.Ok {
-		t.Fatal("should fail if db count targets fails")
+		t.Fatal("should fail if db UpdateTarget fails")
 	}
 
-	// otherwise should success
+	// should fail if the filters are invalid
 	s2 := ethereum.NewSignKeys()
-	s2.AddHexKey(testdb.Signers[1].Priv)
+	s2.AddHexKey(testdb.Signers[2].Priv)
 	var req2 types.APIrequest
-	req2.Method = "countTargets"
+	req2.Method = "updateTarget"
+	req2.TargetID = req.TargetID
+	req2.Target = &types.Target{Filters: []byte(`{"dateOfBirth": {}}`)}
 	// make request
 	resp2 := wsc.Request(req2, s2)
-	// check register went successful
-	if !resp2.Ok {
+	if resp2.Ok {
+		t.Fatal("should fail if the filters are invalid")
+	}
+
+	// otherwise should success
+	var req3 types.APIrequest
+	req3.Method = "updateTarget"
+	req3.TargetID = req.TargetID
+	req3.Target = &types.Target{Name: "renamed", Filters: []byte(`{"hasPublicKey": true}`)}
+	// make request
+	resp3 := wsc.Request(req3, s2)
+	if !resp3.Ok {
 		t.Fatal("should success")
 	}
+	if resp3.Count != 1 {
+		t.Fatalf("expected 1 updated target but got %d", resp3.Count)
+	}
 }
 
-func TestListTargets(t *testing.T) {
+func TestDeleteTarget(t *testing.T) {
 	wsc, err := testcommon.NewApiConnection(fmt.Sprintf("http://127.0.0.1:%d/api/manager", api.Port), t)
 	// check connected successfully
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	// should fail if invalid list options
+	// should fail if the target is used by a census
 	s := ethereum.NewSignKeys()
 	s.AddHexKey(testdb.Signers[0].Priv)
 	var req types.APIrequest
-	req.Method = "listTargets"
-	req.ListOptions = &types.ListOptions{
-		Count:  10,
-		Order:  "0x",
-		Skip:   2,
-		SortBy: "lastName",
-	}
+	req.Method = "deleteTarget"
+	req.TargetID = new(uuid.UUID)
+	*req.TargetID = uuid.New()
 	// make request
 	resp := wsc.Request(req, s)
-	// check register went successful
 	if resp.Ok {
-		t.Fatal("should fail if invalid list options")
+		t.Fatal("should fail if the target is used by a census")
 	}
 
-	// should fail if listTargets returns no rows
+	// otherwise should success
 	s2 := ethereum.NewSignKeys()
-	s2.AddHexKey(testdb.Signers[1].Priv)
+	s2.AddHexKey(testdb.Signers[2].Priv)
 	var req2 types.APIrequest
-	req2.Method = "listTargets"
-	req2.ListOptions = &types.ListOptions{
-		Count:  10,
-		Order:  "ascend",
-		Skip:   2,
-		SortBy: "lastName",
-	}
+	req2.Method = "deleteTarget"
+	req2.TargetID = req.TargetID
 	// make request
 	resp2 := wsc.Request(req2, s2)
-	// check register went successful
-	if resp2.Ok {
-		t.Fatal("should fail if not members found")
-	}
-
-	// should fail if db listTargets fails
-	// should fail db list members fails
-	var req3 types.APIrequest
-	req3.Method = "listTargets"
-	req3.ListOptions = &types.ListOptions{
-		Count:  10,
-		Order:  "ascend",
-		Skip:   2,
-		SortBy: "lastName",
-	}
-	// make request
-	resp3 := wsc.Request(req3, s)
-	// check register went successful
-	if resp3.Ok {
-		t.Fatal("should fail if db list targets fail")
+	if !resp2.Ok {
+		t.Fatal("should success")
 	}
-
 }
 
-func TestGetTarget(t *testing.T) {
+func TestUpdateTag(t *testing.T) {
 	wsc, err := testcommon.NewApiConnection(fmt.Sprintf("http://127.0.0.1:%d/api/manager", api.Port), t)
 	// check connected successfully
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	// should fail if target not found
+	// should fail without a tag ID
 	s := ethereum.NewSignKeys()
 	s.AddHexKey(testdb.Signers[0].Priv)
 	var req types.APIrequest
-	req.Method = "getTarget"
-	req.TargetID = new(uuid.UUID)
-	*req.TargetID = uuid.New()
+	req.Method = "updateTag"
+	req.Tag = &types.Tag{Name: "renamed"}
 	// make request
 	resp := wsc.Request(req, s)
-	// check register went successful
 	if resp.Ok {
-		t.Fatal("should fail if db Target fails")
+		t.Fatal("should fail without a tag ID")
 	}
 
-	// should fail if db targetMembers fail
+	// should fail if db UpdateTag fails
+	req.TagID = 1
+	resp = wsc.Request(req, s)
+	if resp.Ok {
+		t.Fatal("should fail if db UpdateTag fails")
+	}
+
+	// should fail if the color is invalid
 	s2 := ethereum.NewSignKeys()
-	s2.AddHexKey(testdb.Signers[1].Priv)
+	s2.AddHexKey(testdb.Signers[2].Priv)
 	var req2 types.APIrequest
-	req2.Method = "getTarget"
-	req2.TargetID = new(uuid.UUID)
-	*req2.TargetID = uuid.New()
+	req2.Method = "updateTag"
+	req2.TagID = 1
+	req2.Tag = &types.Tag{Color: "red"}
 	// make request
 	resp2 := wsc.Request(req2, s2)
-	// check register went successful
 	if resp2.Ok {
-		t.Fatal("should fail if db Target members fails")
+		t.Fatal("should fail if the color is invalid")
 	}
 
 	// otherwise should success
-	s3 := ethereum.NewSignKeys()
-	s3.AddHexKey(testdb.Signers[2].Priv)
-	var req3 types.APIrequest
-	req3.Method = "getTarget"
-	req3.TargetID = new(uuid.UUID)
-	*req3.TargetID = uuid.New()
-	// make request
-	resp3 := wsc.Request(req3, s3)
-	// check register went successful
-	if !resp3.Ok {
+	req2.Tag = &types.Tag{Name: "renamed", Description: "Board members", Color: "#ff8800"}
+	resp2 = wsc.Request(req2, s2)
+	if !resp2.Ok {
 		t.Fatal("should success")
 	}
+	if resp2.Count != 1 {
+		t.Fatalf("expected 1 updated tag but got %d", resp2.Count)
+	}
 }
 
-func TestDumpTarget(t *testing.T) {
+func TestTagMembersByFilter(t *testing.T) {
 	wsc, err := testcommon.NewApiConnection(fmt.Sprintf("http://127.0.0.1:%d/api/manager", api.Port), t)
 	// check connected successfully
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	// should fail if db target fails
 	s := ethereum.NewSignKeys()
 	s.AddHexKey(testdb.Signers[0].Priv)
+	s2 := ethereum.NewSignKeys()
+	s2.AddHexKey(testdb.Signers[2].Priv)
+
+	// should fail when selecting members in more than one way
 	var req types.APIrequest
-	req.Method = "dumpTarget"
-	req.TargetID = new(uuid.UUID)
-	*req.TargetID = uuid.New()
-	// make request
-	resp := wsc.Request(req, s)
-	// check register went successful
+	req.Method = "addTag"
+	req.TagID = 1
+	req.MemberIDs = []uuid.UUID{uuid.New()}
+	req.Filter = &types.Target{Filters: json.RawMessage(`{"verified": false}`)}
+	resp := wsc.Request(req, s2)
 	if resp.Ok {
-		t.Fatal("should fail if db Target fails")
+		t.Fatal("should fail with both member IDs and a filter")
 	}
+	req.MemberIDs = nil
 
-	// should fail if db dumpClaims fails
-	/*
-		s2 := ethereum.NewSignKeys()
-		s2.AddHexKey(testdb.Signers[1].Priv)
-		var req2 types.APIrequest
-		req2.Method = "dumpTarget"
-		req2.TargetID = uuid.New()
-		// make request
-		resp2 := wsc.Request(req2, s2)
-		// check register went successful
-		if resp2.Ok {
-			t.Fatal("should fail if db Target fails")
-		}
-	*/
+	// should fail if db fails
+	resp = wsc.Request(req, s)
+	if resp.Ok {
+		t.Fatal("should fail if db AddTagToMembersByFilters fails")
+	}
+
+	// should fail if the filter is invalid
+	var req2 types.APIrequest
+	req2.Method = "addTag"
+	req2.TagID = 1
+	req2.Filter = &types.Target{Filters: json.RawMessage(`{"unknown": 1}`)}
+	resp = wsc.Request(req2, s2)
+	if resp.Ok {
+		t.Fatal("should fail if the filter is invalid")
+	}
 
 	// otherwise should success
-	/*
-		s3 := ethereum.NewSignKeys()
-		s3.AddHexKey(testdb.Signers[2].Priv)
-		var req3 types.APIrequest
-		req3.Method = "dumpTarget"
-		req3.TargetID = uuid.New()
-		// make request
-		resp3 := wsc.Request(req3, s3)
-		// check register went successful
-		if !resp3.Ok {
-			t.Fatal("should success")
-		}
-	*/
+	resp = wsc.Request(req, s2)
+	if !resp.Ok || resp.Count != 2 {
+		t.Fatalf("should add tag by filter: %s", resp.Message)
+	}
+	req.Method = "removeTag"
+	resp = wsc.Request(req, s2)
+	if !resp.Ok || resp.Count != 2 {
+		t.Fatalf("should remove tag by filter: %s", resp.Message)
+	}
+
+	// should fail if the target is not found
+	req.Filter = nil
+	targetID := uuid.New()
+	req.TargetID = &targetID
+	resp = wsc.Request(req, s2)
+	if resp.Ok {
+		t.Fatal("should fail if the target is not found")
+	}
 }
 
 func TestAddCensus(t *testing.T) {
@@ -793,17 +2001,57 @@ func TestAddCensus(t *testing.T) {
 	}
 
 	// otherwise should success
+	s4 := ethereum.NewSignKeys()
+	s4.AddHexKey(testdb.Signers[1].Priv)
 	var req6 types.APIrequest
 	req6.Method = "addCensus"
 	req6.TargetID = new(uuid.UUID)
 	*req6.TargetID = uuid.New()
 	req6.CensusID = "d67fb28849af7543f2b0b6bf01bde17613bf7ada"
 	// make request
-	resp6 := wsc.Request(req6, s)
+	resp6 := wsc.Request(req6, s4)
 	// check register went successful
 	if !resp6.Ok {
 		t.Fatal("should success")
 	}
+
+	// should success weighting by a custom field if the entity has no
+	// custom fields schema
+	req7 := req6
+	req7.Census = &types.CensusInfo{Weight: &types.CensusWeight{Field: "shares"}}
+	if resp := wsc.Request(req7, s4); !resp.Ok {
+		t.Fatalf("should success weighting by a custom field: %s", resp.Message)
+	}
+	// a constant weight is always valid
+	req7.Census = &types.CensusInfo{Weight: &types.CensusWeight{Constant: 3}}
+	if resp := wsc.Request(req7, s4); !resp.Ok {
+		t.Fatalf("should success with a constant weight: %s", resp.Message)
+	}
+
+	// the entity of Signers[3] has a number "shares" and an enum "branch"
+	// custom fields
+	s5 := ethereum.NewSignKeys()
+	s5.AddHexKey(testdb.Signers[3].Priv)
+	// should fail if the weight field is not a number
+	req7.Census = &types.CensusInfo{Weight: &types.CensusWeight{Field: "branch"}}
+	if resp := wsc.Request(req7, s5); resp.Ok {
+		t.Fatal("should fail if the weight field is not a number")
+	}
+	// should fail if the weight field is not defined
+	req7.Census = &types.CensusInfo{Weight: &types.CensusWeight{Field: "delegates"}}
+	if resp := wsc.Request(req7, s5); resp.Ok {
+		t.Fatal("should fail if the weight field is not defined")
+	}
+	// should fail with more than one weight source
+	req7.Census = &types.CensusInfo{Weight: &types.CensusWeight{Constant: 2, Field: "shares"}}
+	if resp := wsc.Request(req7, s5); resp.Ok {
+		t.Fatal("should fail with more than one weight source")
+	}
+	// should fail if a weight tag does not exist
+	req7.Census = &types.CensusInfo{Weight: &types.CensusWeight{Tags: map[int32]uint64{1: 3}}}
+	if resp := wsc.Request(req7, s5); resp.Ok {
+		t.Fatal("should fail if a weight tag does not exist")
+	}
 }
 
 func TestGetCensus(t *testing.T) {
@@ -970,6 +2218,187 @@ func TestListCensus(t *testing.T) {
 	if !resp4.Ok {
 		t.Fatal("should success")
 	}
+
+	// censuses can be filtered by state
+	req4.ListOptions.State = types.CensusPublished
+	if resp := wsc.Request(req4, s3); !resp.Ok {
+		t.Fatalf("should success filtering by state: %s", resp.Message)
+	}
+	// should fail with an unknown state
+	req4.ListOptions.State = "open"
+	if resp := wsc.Request(req4, s3); resp.Ok {
+		t.Fatal("should fail with an unknown state")
+	}
+}
+
+func TestInvalidClaims(t *testing.T) {
+	wsc, err := testcommon.NewApiConnection(fmt.Sprintf("http://127.0.0.1:%d/api/manager", api.Port), t)
+	// check connected successfully
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the censuses of the entity of Signers[3] contain Signers[3] and Signers[4],
+	// and Signers[4] is not flagged as a census member
+	s := ethereum.NewSignKeys()
+	s.AddHexKey(testdb.Signers[3].Priv)
+	member, _ := hex.DecodeString(testdb.Signers[3].Pub)
+	other, _ := hex.DecodeString(testdb.Signers[4].Pub)
+	var req types.APIrequest
+	req.Method = "updateCensus"
+	req.CensusID = "d67fb28849af7543f2b0b6bf01bde17613bf7ada"
+	req.Census = &types.CensusInfo{MerkleTreeURI: "ipfs://abc"}
+	req.InvalidClaims = [][]byte{member, other, {1, 2, 3}, member}
+	resp := wsc.Request(req, s)
+	if !resp.Ok {
+		t.Fatalf("should success: %s", resp.Message)
+	}
+	reasons := make(map[string]string)
+	for _, invalidClaim := range resp.InvalidClaims {
+		if invalidClaim.MemberID == nil && invalidClaim.Reason != types.InvalidClaimNotInCensus {
+			t.Fatalf("claim %x without member should not be in census: %s", invalidClaim.Claim, invalidClaim.Reason)
+		}
+		reasons[hex.EncodeToString(invalidClaim.Claim)] = invalidClaim.Reason
+	}
+	expected := map[string]string{
+		testdb.Signers[3].Pub: types.InvalidClaimRejected,
+		testdb.Signers[4].Pub: types.InvalidClaimNotInCensus,
+		"010203":              types.InvalidClaimPublicKey,
+	}
+	if fmt.Sprint(reasons) != fmt.Sprint(expected) {
+		t.Fatalf("expected invalid claims %v but got %v", expected, reasons)
+	}
+
+	// should fail if db FlagInvalidClaims fails
+	s2 := ethereum.NewSignKeys()
+	s2.AddHexKey(testdb.Signers[0].Priv)
+	if resp := wsc.Request(req, s2); resp.Ok {
+		t.Fatal("should fail if db FlagInvalidClaims fails")
+	}
+}
+
+func TestCensusState(t *testing.T) {
+	wsc, err := testcommon.NewApiConnection(fmt.Sprintf("http://127.0.0.1:%d/api/manager", api.Port), t)
+	// check connected successfully
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := ethereum.NewSignKeys()
+	s.AddHexKey(testdb.Signers[2].Priv)
+
+	// the census 0b5e55ed is published and the rest are drafts
+	var req types.APIrequest
+	req.Method = "updateCensusState"
+	req.CensusID = "0b5e55ed"
+	// should fail without a state
+	if resp := wsc.Request(req, s); resp.Ok {
+		t.Fatal("should fail without a state")
+	}
+	// should fail with an unknown state
+	req.State = "open"
+	if resp := wsc.Request(req, s); resp.Ok {
+		t.Fatal("should fail with an unknown state")
+	}
+	// should fail skipping states
+	req.State = types.CensusArchived
+	if resp := wsc.Request(req, s); resp.Ok {
+		t.Fatal("should fail skipping states")
+	}
+	// a published census can be closed
+	req.State = types.CensusClosed
+	if resp := wsc.Request(req, s); !resp.Ok {
+		t.Fatalf("should close a published census: %s", resp.Message)
+	}
+	// should fail going back to a previous state
+	req.CensusID = "d67fb28849af7543f2b0b6bf01bde17613bf7ada"
+	if resp := wsc.Request(req, s); resp.Ok {
+		t.Fatal("should fail closing a draft census")
+	}
+	// should fail publishing a census that is not expanded
+	req.State = types.CensusPublished
+	if resp := wsc.Request(req, s); resp.Ok {
+		t.Fatal("should fail publishing a draft census")
+	}
+	// censuses are only expanded by dumpCensus
+	req.State = types.CensusExpanded
+	if resp := wsc.Request(req, s); resp.Ok {
+		t.Fatal("should fail expanding a census")
+	}
+	// should fail if db SetCensusState fails
+	s2 := ethereum.NewSignKeys()
+	s2.AddHexKey(testdb.Signers[0].Priv)
+	req.CensusID = "0b5e55ed"
+	req.State = types.CensusClosed
+	if resp := wsc.Request(req, s2); resp.Ok {
+		t.Fatal("should fail if db SetCensusState fails")
+	}
+
+	// published censuses cannot be updated nor expanded
+	req = types.APIrequest{Method: "updateCensus", CensusID: "0b5e55ed"}
+	req.Census = &types.CensusInfo{MerkleTreeURI: "ipfs://abc"}
+	if resp := wsc.Request(req, s); resp.Ok {
+		t.Fatal("should fail updating a published census")
+	}
+	req = types.APIrequest{Method: "dumpCensus", CensusID: "0b5e55ed"}
+	if resp := wsc.Request(req, s); resp.Ok {
+		t.Fatal("should fail expanding a published census")
+	}
+
+	// nor deleted, unlike drafts
+	s3 := ethereum.NewSignKeys()
+	s3.AddHexKey(testdb.Signers[3].Priv)
+	req = types.APIrequest{Method: "deleteCensus", CensusID: "0b5e55ed"}
+	if resp := wsc.Request(req, s3); resp.Ok {
+		t.Fatal("should fail deleting a published census")
+	}
+	req.CensusID = "d67fb28849af7543f2b0b6bf01bde17613bf7ada"
+	if resp := wsc.Request(req, s3); !resp.Ok {
+		t.Fatalf("should delete a draft census: %s", resp.Message)
+	}
+}
+
+func TestCensusRoot(t *testing.T) {
+	wsc, err := testcommon.NewApiConnection(fmt.Sprintf("http://127.0.0.1:%d/api/manager", api.Port), t)
+	// check connected successfully
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := ethereum.NewSignKeys()
+	s.AddHexKey(testdb.Signers[2].Priv)
+
+	// dumpCensus returns the root of the census tree it built
+	var req types.APIrequest
+	req.Method = "dumpCensus"
+	req.CensusID = "d67fb28849af7543f2b0b6bf01bde17613bf7ada"
+	resp := wsc.Request(req, s)
+	if !resp.Ok {
+		t.Fatalf("dumpCensus failed: %s", resp.Message)
+	}
+	if len(resp.Root) != 32 {
+		t.Fatalf("expected a 32 bytes root, got %x", resp.Root)
+	}
+	root := resp.Root
+
+	// should fail if the root does not match the census tree
+	req.Method = "updateCensus"
+	req.Census = &types.CensusInfo{MerkleRoot: bytes.Repeat([]byte{1}, 32)}
+	resp = wsc.Request(req, s)
+	if resp.Ok {
+		t.Fatal("should fail if the merkle root does not match")
+	}
+
+	// otherwise should success
+	req.Census = &types.CensusInfo{MerkleRoot: root, MerkleTreeURI: "ipfs://abc"}
+	resp = wsc.Request(req, s)
+	if !resp.Ok {
+		t.Fatalf("should success: %s", resp.Message)
+	}
+
+	// updates without a root are not checked
+	req.Census = &types.CensusInfo{MerkleTreeURI: "ipfs://abc"}
+	resp = wsc.Request(req, s)
+	if !resp.Ok {
+		t.Fatalf("should success: %s", resp.Message)
+	}
 }
 
 func TestRequestGas(t *testing.T) {
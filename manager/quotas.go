@@ -0,0 +1,152 @@
+package manager
+
+import (
+	"fmt"
+	"time"
+
+	"go.vocdoni.io/dvote/log"
+	"go.vocdoni.io/manager/types"
+)
+
+// sandboxMembersLimit is the maximum number of members of an entity that is
+// pending approval
+const sandboxMembersLimit = 100
+
+// defaultQuotas are the quotas of the entities that do not override them. A
+// zero quota is unlimited, so the members of authorized entities are only
+// limited if overridden.
+var defaultQuotas = types.Quotas{
+	Censuses:               100,
+	EmailsPerDay:           5000,
+	FaucetRequestsPerMonth: 10,
+}
+
+// Resources limited by the quotas
+const (
+	quotaMembers        = "members"
+	quotaCensuses       = "censuses"
+	quotaEmails         = "emails per day"
+	quotaFaucetRequests = "faucet requests per month"
+)
+
+// entityQuotas returns the quotas that apply to the entity
+func entityQuotas(entity *types.Entity) *types.Quotas {
+	quotas := defaultQuotas
+	if q := entity.Quotas; q != nil {
+		if q.Members > 0 {
+			quotas.Members = q.Members
+		}
+		if q.Censuses > 0 {
+			quotas.Censuses = q.Censuses
+		}
+		if q.EmailsPerDay > 0 {
+			quotas.EmailsPerDay = q.EmailsPerDay
+		}
+		if q.FaucetRequestsPerMonth > 0 {
+			quotas.FaucetRequestsPerMonth = q.FaucetRequestsPerMonth
+		}
+	}
+	if !entity.IsAuthorized && (quotas.Members == 0 || quotas.Members > sandboxMembersLimit) {
+		quotas.Members = sandboxMembersLimit
+	}
+	return &quotas
+}
+
+// entityUsage returns the quotas of the entity along with its current usage,
+// metering the emails by UTC day and the faucet requests by UTC month
+func (m *Manager) entityUsage(entityID []byte) (*types.Entity, *types.Quotas, *types.Usage, error) {
+	entity, err := m.db.Entity(entityID)
+	if err != nil {
+		log.Errorf("cannot retrieve entity %x: (%v)", entityID, err)
+		return nil, nil, nil, fmt.Errorf("cannot retrieve entity")
+	}
+	now := time.Now().UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	usage, err := m.db.EntityUsage(entityID, day, month)
+	if err != nil {
+		log.Errorf("cannot retrieve usage of entity %x: (%v)", entityID, err)
+		return nil, nil, nil, fmt.Errorf("cannot retrieve usage")
+	}
+	return entity, entityQuotas(entity), usage, nil
+}
+
+// checkQuota returns an error if using the given amount of the resource would
+// exceed the quota of the entity
+func (m *Manager) checkQuota(entityID []byte, resource string, adding int) error {
+	entity, quotas, usage, err := m.entityUsage(entityID)
+	if err != nil {
+		return err
+	}
+	var used, limit int
+	switch resource {
+	case quotaMembers:
+		used, limit = usage.Members, quotas.Members
+	case quotaCensuses:
+		used, limit = usage.Censuses, quotas.Censuses
+	case quotaFaucetRequests:
+		used, limit = usage.FaucetRequestsThisMonth, quotas.FaucetRequestsPerMonth
+	default:
+		return fmt.Errorf("unknown quota %s", resource)
+	}
+	if limit == 0 || used+adding <= limit {
+		return nil
+	}
+	log.Debugf("entity %x cannot use %d %s, %d used of %d", entityID, adding, resource, used, limit)
+	if resource == quotaMembers && !entity.IsAuthorized {
+		return fmt.Errorf("entity is pending approval: it cannot have more than %d members until it is authorized", limit)
+	}
+	return fmt.Errorf("%s quota exceeded: %d used of %d", resource, used, limit)
+}
+
+// reserveEmails atomically reserves count emails of the daily quota of the
+// entity before sending them, returning the UTC day they are reserved on
+func (m *Manager) reserveEmails(entityID []byte, count int) (time.Time, error) {
+	entity, err := m.db.Entity(entityID)
+	if err != nil {
+		log.Errorf("cannot retrieve entity %x: (%v)", entityID, err)
+		return time.Time{}, fmt.Errorf("cannot retrieve entity")
+	}
+	now := time.Now().UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	limit := entityQuotas(entity).EmailsPerDay
+	reserved, err := m.db.ReserveEmails(entityID, day, count, limit)
+	if err != nil {
+		log.Errorf("cannot reserve %d emails for entity %x: (%v)", count, entityID, err)
+		return time.Time{}, fmt.Errorf("cannot reserve emails")
+	}
+	if !reserved {
+		log.Debugf("entity %x cannot send %d emails of %d per day", entityID, count, limit)
+		return time.Time{}, fmt.Errorf("%s quota exceeded: cannot send %d more emails of %d", quotaEmails, count, limit)
+	}
+	return day, nil
+}
+
+// releaseEmails gives back the emails reserved on day that were not sent
+func (m *Manager) releaseEmails(entityID []byte, day time.Time, count int) {
+	if count == 0 {
+		return
+	}
+	if err := m.db.ReleaseEmails(entityID, day, count); err != nil {
+		log.Errorf("cannot release %d emails of entity %x: (%v)", count, entityID, err)
+	}
+}
+
+func (m *Manager) getUsage(request *types.APIrequest) (*types.APIresponse, error) {
+	var entityID []byte
+	var err error
+	var response types.APIresponse
+
+	// retrieve entity ID
+	if entityID, err = actingEntityID(request); err != nil {
+		log.Errorf("cannot recover %x entityID: (%v)", request.SignaturePublicKey, err)
+		return nil, fmt.Errorf("cannot recover entityID")
+	}
+
+	if _, response.Quotas, response.Usage, err = m.entityUsage(entityID); err != nil {
+		return nil, err
+	}
+
+	log.Debugf("Entity: %x getUsage: %+v of %+v", entityID, *response.Usage, *response.Quotas)
+	return &response, nil
+}
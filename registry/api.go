@@ -7,6 +7,7 @@ import (
 	"go.vocdoni.io/dvote/httprouter"
 	"go.vocdoni.io/dvote/log"
 	"go.vocdoni.io/dvote/metrics"
+	"go.vocdoni.io/manager/census"
 	"go.vocdoni.io/manager/database"
 	"go.vocdoni.io/manager/rpcapi"
 )
@@ -15,15 +16,27 @@ type Registry struct {
 	api *rpcapi.RPCAPI
 	db  database.Database
 	ma  *metrics.Agent
+	// censusTrees stores the Merkle trees computed for the censuses
+	censusTrees *census.Trees
 }
 
 // NewRegistry creates a new registry handler for the Router
-func NewRegistry(signer *ethereum.SignKeys, r *httprouter.HTTProuter, route string, d database.Database, ma *metrics.Agent) (*Registry, error) {
-	if r == nil || d == nil {
+func NewRegistry(signer *ethereum.SignKeys, r *httprouter.HTTProuter, route string, d database.Database, ma *metrics.Agent, censusTrees *census.Trees) (*Registry, error) {
+	return newRegistry(signer, r, "registry Mobile", route+"/registry", d, ma, censusTrees)
+}
+
+// NewCensusProofs creates a handler for the Router that only serves the census
+// proofs (see EnableCensusProofAPI), under its own namespace
+func NewCensusProofs(signer *ethereum.SignKeys, r *httprouter.HTTProuter, route string, d database.Database, ma *metrics.Agent, censusTrees *census.Trees) (*Registry, error) {
+	return newRegistry(signer, r, "census proofs", route+"/census", d, ma, censusTrees)
+}
+
+func newRegistry(signer *ethereum.SignKeys, r *httprouter.HTTProuter, name, path string, d database.Database, ma *metrics.Agent, censusTrees *census.Trees) (*Registry, error) {
+	if r == nil || d == nil || censusTrees == nil {
 		return nil, fmt.Errorf("invalid arguments for manager API")
 	}
 
-	api, err := rpcapi.NewAPI(signer, r, "registry Mobile", route+"/registry", nil, false)
+	api, err := rpcapi.NewAPI(signer, r, name, path, nil, false)
 	if err != nil {
 		return nil, fmt.Errorf("could not create the manager API: %v", err)
 	}
@@ -32,7 +45,7 @@ func NewRegistry(signer *ethereum.SignKeys, r *httprouter.HTTProuter, route stri
 	// rpcapi.APIs = append(rpcapi.APIs, "manager")
 	// api.AddAuthorizedAddress(signer.Address())
 	// rpcapi.ManagerAPI = api
-	return &Registry{api: api, db: d, ma: ma}, nil
+	return &Registry{api: api, db: d, ma: ma, censusTrees: censusTrees}, nil
 }
 
 // RegisterMethods registers all registry methods behind the given path
@@ -48,3 +61,13 @@ func (r *Registry) EnableAPI() error {
 	r.registerMetrics()
 	return nil
 }
+
+// EnableCensusProofAPI registers the censusProof method alone. The proofs are
+// read from the census trees stored in the data dir of the manager, so it must
+// be served by the process running the manager API.
+func (r *Registry) EnableCensusProofAPI() error {
+	log.Infof("enabling census proof API")
+
+	r.api.RegisterPublic("censusProof", true, r.censusProof)
+	return nil
+}
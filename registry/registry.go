@@ -3,19 +3,21 @@ package registry
 import (
 	"bytes"
 	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 	"strings"
 	"time"
 
-	"github.com/badoux/checkmail"
 	"github.com/google/uuid"
 	"github.com/prometheus/client_golang/prometheus"
 	"go.vocdoni.io/dvote/crypto/ethereum"
 	"go.vocdoni.io/dvote/log"
 
+	"go.vocdoni.io/manager/census"
 	"go.vocdoni.io/manager/types"
+	"go.vocdoni.io/manager/util"
 )
 
 func (r *Registry) register(request *types.APIrequest) (*types.APIresponse, error) {
@@ -389,6 +391,67 @@ func (r *Registry) listSubscriptions(request *types.APIrequest) (*types.APIrespo
 	}, nil
 }
 
+// censusProof returns the Merkle inclusion proof in a census of the public key
+// of the member of the token, of the given public key or of the signer
+func (r *Registry) censusProof(request *types.APIrequest) (*types.APIresponse, error) {
+	var response types.APIresponse
+
+	// increase stats counter
+	RegistryRequests.With(prometheus.Labels{"method": "censusProof"}).Inc()
+
+	if len(request.EntityID) == 0 || len(request.CensusID) == 0 {
+		return nil, fmt.Errorf("invalid arguments")
+	}
+	censusID, err := util.DecodeCensusID(request.CensusID, request.EntityID)
+	if err != nil {
+		log.Warnf("cannot decode census id %s for %x: (%v)", request.CensusID, request.EntityID, err)
+		return nil, fmt.Errorf("cannot decode census id")
+	}
+
+	pubKey := request.SignaturePublicKey
+	if len(request.Token) > 0 {
+		uid, err := uuid.Parse(request.Token)
+		if err != nil {
+			log.Warnf("invalid token id format %s for entity %x: (%v)", request.Token, request.EntityID, err)
+			return nil, fmt.Errorf("invalid token format")
+		}
+		censusMember, err := r.db.CensusMember(request.EntityID, censusID, &uid)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, fmt.Errorf("member not in census")
+			}
+			log.Errorf("cannot retrieve member %s of census %x for entity %x: (%v)", uid, censusID, request.EntityID, err)
+			return nil, fmt.Errorf("cannot retrieve census member")
+		}
+		pubKey = censusMember.DigestedPubKey
+	} else if len(request.PubKey) > 0 {
+		pubKey = request.PubKey
+	}
+	if len(pubKey) == 0 {
+		return nil, fmt.Errorf("member has no public key in census")
+	}
+
+	// proofs are served from the stored tree, which is only built by the
+	// manager, as long as it matches the census root
+	if response.Value, response.Proof, response.Root, err = r.censusTrees.CensusProof(r.db, request.EntityID, censusID, pubKey); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, fmt.Errorf("census not found")
+		case err == census.ErrTreeNotBuilt:
+			log.Warnf("census %x of entity %x: %v", censusID, request.EntityID, err)
+			return nil, fmt.Errorf("census tree not available")
+		case err == census.ErrNotInCensus:
+			RegistryRequests.With(prometheus.Labels{"method": "censusProof_error_not_in_census"}).Inc()
+			return nil, fmt.Errorf("public key not in census")
+		}
+		log.Errorf("cannot generate proof of %x in census %x: (%v)", pubKey, censusID, err)
+		return nil, fmt.Errorf("cannot generate census proof")
+	}
+
+	log.Debugf("censusProof: %x in census %x of entity %x", pubKey, censusID, request.EntityID)
+	return &response, nil
+}
+
 // ===== helpers =======
 
 func checkMemberInfo(m *types.MemberInfo) bool {
@@ -397,8 +460,5 @@ func checkMemberInfo(m *types.MemberInfo) bool {
 		return false
 	}
 
-	if err := checkmail.ValidateFormat(m.Email); err != nil {
-		return false
-	}
-	return true
+	return util.ValidEmail(m.Email)
 }
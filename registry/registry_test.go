@@ -9,12 +9,15 @@ import (
 	"time"
 
 	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+	"github.com/vocdoni/arbo"
 	"go.vocdoni.io/dvote/crypto/ethereum"
 
 	"go.vocdoni.io/dvote/util"
 	"go.vocdoni.io/manager/database/testdb"
 	"go.vocdoni.io/manager/test/testcommon"
 	"go.vocdoni.io/manager/types"
+	managerutil "go.vocdoni.io/manager/util"
 )
 
 var api testcommon.TestAPI
@@ -239,6 +242,116 @@ func TestStatus(t *testing.T) {
 	// }
 }
 
+func TestCensusProof(t *testing.T) {
+	wsc, err := testcommon.NewApiConnection(fmt.Sprintf("http://127.0.0.1:%d/api/census", api.Port), t)
+	// check connected successfully
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the censuses of the entity of Signers[3] contain Signers[3] and Signers[4]
+	member := ethereum.NewSignKeys()
+	member.AddHexKey(testdb.Signers[3].Priv)
+	other := ethereum.NewSignKeys()
+	other.Generate()
+	entityID, err := hex.DecodeString("6d3e07d7d1dd84469cc3adf49fa83daf2678b4c9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkProof := func(resp *types.APIresponse, pub string) {
+		t.Helper()
+		pubKey, err := hex.DecodeString(pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, err := arbo.HashFunctionBlake2b.Hash(pubKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := arbo.CheckProof(arbo.HashFunctionBlake2b, key, resp.Value, resp.Root, resp.Proof); err != nil || !ok {
+			t.Fatalf("invalid proof for %s: (%v)", pub, err)
+		}
+	}
+
+	// should fail without a census id
+	var req types.APIrequest
+	req.Method = "censusProof"
+	req.EntityID = entityID
+	resp := wsc.Request(req, member)
+	if resp.Ok {
+		t.Fatal("should fail without a census id")
+	}
+
+	// should fail until the manager builds the census tree
+	req.CensusID = "d67fb28849af7543f2b0b6bf01bde17613bf7ada"
+	censusID, err := managerutil.DecodeCensusID(req.CensusID, entityID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := api.Trees.Build(censusID, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	resp = wsc.Request(req, member)
+	if resp.Ok {
+		t.Fatal("should fail if the census tree is not built")
+	}
+	if _, err := api.Trees.BuildCensus(api.DB, entityID, censusID); err != nil {
+		t.Fatal(err)
+	}
+
+	// the key of the signer is used by default
+	resp = wsc.Request(req, member)
+	if !resp.Ok {
+		t.Fatalf("should success: %s", resp.Message)
+	}
+	checkProof(resp, testdb.Signers[3].Pub)
+
+	// should fail if the key is not in the census
+	resp = wsc.Request(req, other)
+	if resp.Ok {
+		t.Fatal("should fail if the key is not in the census")
+	}
+
+	// otherwise a public key can be given
+	req.PubKey, _ = hex.DecodeString(testdb.Signers[4].Pub)
+	resp = wsc.Request(req, other)
+	if !resp.Ok {
+		t.Fatalf("should success: %s", resp.Message)
+	}
+	checkProof(resp, testdb.Signers[4].Pub)
+
+	// or the token of a member of the census
+	req.PubKey = nil
+	req.Token = uuid.New().String()
+	resp = wsc.Request(req, other)
+	if !resp.Ok {
+		t.Fatalf("should success: %s", resp.Message)
+	}
+	checkProof(resp, testdb.Signers[4].Pub)
+
+	// should fail with an invalid token
+	req.Token = "1234"
+	resp = wsc.Request(req, other)
+	if resp.Ok {
+		t.Fatal("should fail with an invalid token")
+	}
+
+	// should fail if the token is not of a census member
+	req.EntityID, _ = hex.DecodeString("c873e0d5ba9d5b0e2ff2e2fa76078263cc2d8c3e")
+	req.Token = uuid.New().String()
+	resp = wsc.Request(req, other)
+	if resp.Ok {
+		t.Fatal("should fail if the member is not in the census")
+	}
+
+	// should fail if the census cannot be retrieved
+	req.EntityID, _ = hex.DecodeString("5fa506aa68191bcc657795e57f080472e712c27d")
+	req.Token = ""
+	resp = wsc.Request(req, member)
+	if resp.Ok {
+		t.Fatal("should fail if the census cannot be retrieved")
+	}
+}
+
 func TestSubscribe(t *testing.T) {
 	var req types.APIrequest
 	s := ethereum.NewSignKeys()
@@ -1,9 +1,14 @@
 package testcommon
 
 import (
+	"os"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
 	"go.vocdoni.io/dvote/crypto/ethereum"
 	"go.vocdoni.io/dvote/httprouter"
 	"go.vocdoni.io/dvote/log"
+	"go.vocdoni.io/manager/census"
 	"go.vocdoni.io/manager/config"
 	"go.vocdoni.io/manager/database"
 	"go.vocdoni.io/manager/database/pgsql"
@@ -19,6 +24,10 @@ type TestAPI struct {
 	Router *httprouter.HTTProuter
 	Port   int
 	Signer *ethereum.SignKeys
+	// Admin is the platform superadmin of the manager API
+	Admin *ethereum.SignKeys
+	// Trees stores the census trees computed by the manager API
+	Trees *census.Trees
 }
 
 // Start creates a new database connection and API endpoint for testing.
@@ -34,6 +43,8 @@ func (t *TestAPI) Start(dbc *config.DB, route string) error {
 		signer = ethereum.NewSignKeys()
 		signer.Generate()
 		t.Signer = signer
+		t.Admin = ethereum.NewSignKeys()
+		t.Admin.Generate()
 
 		cfg = &config.Manager{
 			API: &config.API{
@@ -81,7 +92,15 @@ func (t *TestAPI) Start(dbc *config.DB, route string) error {
 		}
 		// defer s.ClosePool()
 
-		mg, err := manager.NewManager(signer, &httpRouter, "/api", t.DB, s, nil)
+		treesDir, err := os.MkdirTemp("", "dvotemanager")
+		if err != nil {
+			log.Fatal(err)
+		}
+		if t.Trees, err = census.NewTrees(treesDir); err != nil {
+			log.Fatal(err)
+		}
+
+		mg, err := manager.NewManager(signer, &httpRouter, "/api", t.DB, s, nil, []ethcommon.Address{t.Admin.Address()}, time.Hour, t.Trees)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -90,7 +109,7 @@ func (t *TestAPI) Start(dbc *config.DB, route string) error {
 			log.Fatal(err)
 		}
 
-		r, err := registry.NewRegistry(t.Signer, &httpRouter, "/api", t.DB, nil)
+		r, err := registry.NewRegistry(t.Signer, &httpRouter, "/api", t.DB, nil, t.Trees)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -99,6 +118,15 @@ func (t *TestAPI) Start(dbc *config.DB, route string) error {
 			log.Fatal(err)
 		}
 
+		proofs, err := registry.NewCensusProofs(t.Signer, &httpRouter, "/api", t.DB, nil, t.Trees)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if err := proofs.EnableCensusProofAPI(); err != nil {
+			log.Fatal(err)
+		}
+
 		ta, err := tokenapi.NewTokenAPI(&httpRouter, "/api", t.DB, nil)
 		if err != nil {
 			log.Fatal(err)
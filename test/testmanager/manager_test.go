@@ -18,6 +18,7 @@ import (
 	"github.com/google/uuid"
 	"go.vocdoni.io/dvote/crypto/ethereum"
 	"go.vocdoni.io/dvote/util"
+	"go.vocdoni.io/manager/census"
 	"go.vocdoni.io/manager/config"
 	"go.vocdoni.io/manager/test/testcommon"
 	"go.vocdoni.io/manager/types"
@@ -465,7 +466,7 @@ func TestDeleteMembers(t *testing.T) {
 		t.Fatal("invalidID was not returned correctly")
 	}
 
-	rows, err := api.DB.CountMembers(entities[0].ID)
+	rows, err := api.DB.CountMembers(entities[0].ID, "")
 	if err != nil {
 		t.Fatalf("could retrieve deleted member from database: %s", err)
 	}
@@ -481,7 +482,7 @@ func TestDeleteMembers(t *testing.T) {
 		t.Fatalf("request failed: %v", req)
 	}
 
-	rows, err = api.DB.CountMembers(entities[0].ID)
+	rows, err = api.DB.CountMembers(entities[0].ID, "")
 	if err != nil {
 		t.Fatalf("could retrieve deleted member from database: %s", err)
 	}
@@ -497,7 +498,7 @@ func TestDeleteMembers(t *testing.T) {
 		t.Fatalf("request failed: %v", req)
 	}
 
-	rows, err = api.DB.CountMembers(entities[0].ID)
+	rows, err = api.DB.CountMembers(entities[0].ID, "")
 	if err != nil {
 		t.Fatalf("could retrieve deleted member from database: %s", err)
 	}
@@ -746,6 +747,7 @@ func TestDumpCensus(t *testing.T) {
 	resp := wsc.Request(req, entitySigners[0])
 	c.Assert(resp.Ok, qt.IsTrue, qt.Commentf("request failed: %+v", req))
 	c.Assert(resp.Claims, qt.HasLen, n)
+	c.Assert(resp.Root, qt.HasLen, 32)
 
 	if len(resp.Claims) != n {
 		t.Fatalf("expected %d claims but got %d", n, len(resp.Claims))
@@ -758,6 +760,7 @@ func TestDumpCensus(t *testing.T) {
 	if !census.Ephemeral {
 		t.Fatal("census was marked as non ephemeral while it should")
 	}
+	c.Assert(census.MerkleRoot, qt.DeepEquals, resp.Root)
 
 	ephemeralMembers, err := api.DB.ListEphemeralMemberInfo(entities[0].ID, idBytes)
 	c.Assert(err, qt.IsNil, qt.Commentf("testDumpCensus: cannot retrieve ephemeral member info: (%v)", err))
@@ -898,7 +901,7 @@ func TestSendVotingLinks(t *testing.T) {
 		t.Fatalf("cannot add members into database: %s", err)
 	}
 
-	dbMembers, err := api.DB.ListMembers(entities[0].ID, nil)
+	dbMembers, _, err := api.DB.ListMembers(entities[0].ID, nil)
 	if err != nil {
 		t.Fatalf("coulld not retrieve DB members: %v", err)
 	}
@@ -1168,8 +1171,17 @@ func TestUpdateCensus(t *testing.T) {
 	if err != nil {
 		t.Fatalf("cannot decode randpom id: %s", err)
 	}
-	root := util.RandomBytes(32)
 	name := fmt.Sprintf("census%s", strconv.Itoa(rand.Int()))
+	// the census has no members, so its root is the one of an empty tree
+	trees, err := census.NewTrees(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer trees.Close()
+	root, _, err := trees.Build(idBytes, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	err = api.DB.AddCensus(entities[0].ID, idBytes, &targetID, &types.CensusInfo{Name: name})
 	if err != nil {
@@ -1185,6 +1197,16 @@ func TestUpdateCensus(t *testing.T) {
 		t.Fatalf("census update without data succeeded: %+v", req)
 	}
 
+	// update with a root that does not match the census should fail
+	req.Census = &types.CensusInfo{
+		MerkleRoot:    util.RandomBytes(32),
+		MerkleTreeURI: fmt.Sprintf("ipfs://%s", util.TrimHex(id)),
+	}
+	resp = wsc.Request(req, signers[0])
+	if resp.Ok {
+		t.Fatalf("census update with a wrong root succeeded: %+v", req)
+	}
+
 	// update with correct data should succeed
 	req.Census = &types.CensusInfo{
 		MerkleRoot:    root,
@@ -1528,7 +1550,7 @@ func TestSendValidationLinks(t *testing.T) {
 		t.Fatalf("cannot add members into database: %s", err)
 	}
 
-	dbMembers, err := api.DB.ListMembers(entities[0].ID, nil)
+	dbMembers, _, err := api.DB.ListMembers(entities[0].ID, nil)
 	if err != nil {
 		t.Fatalf("coulld not retrieve DB members: %v", err)
 	}
@@ -1709,7 +1731,7 @@ func TestDeleteTag(t *testing.T) {
 	if err := api.DB.AddMemberBulk(entities[0].ID, members); err != nil {
 		t.Fatalf("cannot add members into database: %s", err)
 	}
-	listedMembers, err := api.DB.ListMembers(entities[0].ID, nil)
+	listedMembers, _, err := api.DB.ListMembers(entities[0].ID, nil)
 	if err != nil {
 		t.Fatalf("cannot add members into database: %s", err)
 	}
@@ -1795,7 +1817,7 @@ func TestAddTag(t *testing.T) {
 	if err := api.DB.AddMemberBulk(entities[0].ID, members); err != nil {
 		t.Fatalf("cannot add members into database: %s", err)
 	}
-	listedMembers, err := api.DB.ListMembers(entities[0].ID, nil)
+	listedMembers, _, err := api.DB.ListMembers(entities[0].ID, nil)
 	if err != nil {
 		t.Fatalf("cannot add members into database: %s", err)
 	}
@@ -1826,7 +1848,7 @@ func TestAddTag(t *testing.T) {
 		t.Fatalf("expected to receive an updated count of %d but received %d", len(memberIDs), resp.Count)
 	}
 
-	listedMembers, err = api.DB.ListMembers(entities[0].ID, nil)
+	listedMembers, _, err = api.DB.ListMembers(entities[0].ID, nil)
 	if err != nil {
 		t.Fatalf("cannot add members into database: %s", err)
 	}
@@ -1903,7 +1925,7 @@ func TestRemoveTag(t *testing.T) {
 	if err := api.DB.AddMemberBulk(entities[0].ID, members); err != nil {
 		t.Fatalf("cannot add members into database: %s", err)
 	}
-	listedMembers, err := api.DB.ListMembers(entities[0].ID, nil)
+	listedMembers, _, err := api.DB.ListMembers(entities[0].ID, nil)
 	if err != nil {
 		t.Fatalf("cannot add members into database: %s", err)
 	}
@@ -1981,7 +2003,7 @@ func TestRemoveTag(t *testing.T) {
 		t.Fatal("unexpected response for removing tag from memberIDs with duplicate id")
 	}
 
-	listedMembers, err = api.DB.ListMembers(entities[0].ID, nil)
+	listedMembers, _, err = api.DB.ListMembers(entities[0].ID, nil)
 	if err != nil {
 		t.Fatalf("cannot list members from database: %s", err)
 	}
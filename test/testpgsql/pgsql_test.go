@@ -165,6 +165,350 @@ func TestEntity(t *testing.T) {
 	}
 }
 
+func TestEntityCustomFieldsSchema(t *testing.T) {
+	c := qt.New(t)
+	// create entity
+	_, entities := testcommon.CreateEntities(1)
+	err := api.DB.AddEntity(entities[0].ID, &entities[0].EntityInfo)
+	c.Assert(err, qt.IsNil)
+
+	// entities start without schema
+	schema, err := api.DB.EntityCustomFieldsSchema(entities[0].ID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(schema, qt.HasLen, 0)
+
+	info := entities[0].EntityInfo
+	info.CustomFieldsSchema = types.CustomFieldsSchema{
+		{Name: "shares", Type: types.CustomFieldNumber, Required: true},
+		{Name: "branch", Type: types.CustomFieldEnum, Values: []string{"Barcelona", "Girona"}},
+	}
+	count, err := api.DB.UpdateEntity(entities[0].ID, &info)
+	c.Assert(err, qt.IsNil)
+	c.Assert(count, qt.Equals, 1)
+	schema, err = api.DB.EntityCustomFieldsSchema(entities[0].ID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(schema, qt.DeepEquals, info.CustomFieldsSchema)
+	entity, err := api.DB.Entity(entities[0].ID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(entity.CustomFieldsSchema, qt.DeepEquals, info.CustomFieldsSchema)
+
+	// updates without schema keep the current one
+	info.Name = "renamed entity"
+	info.CustomFieldsSchema = nil
+	_, err = api.DB.UpdateEntity(entities[0].ID, &info)
+	c.Assert(err, qt.IsNil)
+	schema, err = api.DB.EntityCustomFieldsSchema(entities[0].ID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(schema, qt.HasLen, 2)
+
+	// while an empty schema removes it
+	info.CustomFieldsSchema = types.CustomFieldsSchema{}
+	count, err = api.DB.UpdateEntity(entities[0].ID, &info)
+	c.Assert(err, qt.IsNil)
+	c.Assert(count, qt.Equals, 1)
+	schema, err = api.DB.EntityCustomFieldsSchema(entities[0].ID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(schema, qt.HasLen, 0)
+
+	// member custom fields are replaced by updates and kept if not provided
+	err = api.DB.ImportMembers(entities[0].ID, []types.MemberInfo{{Email: "john@vocdoni.io", CustomFields: json.RawMessage(`{"shares": 10}`)}})
+	c.Assert(err, qt.IsNil)
+	members, _, err := api.DB.ListMembers(entities[0].ID, nil)
+	c.Assert(err, qt.IsNil)
+	c.Assert(members, qt.HasLen, 1)
+	count, err = api.DB.UpdateMember(entities[0].ID, &members[0].ID, &types.MemberInfo{CustomFields: json.RawMessage(`{"shares": 20, "branch": "Girona"}`)})
+	c.Assert(err, qt.IsNil)
+	c.Assert(count, qt.Equals, 1)
+	_, err = api.DB.UpdateMember(entities[0].ID, &members[0].ID, &types.MemberInfo{FirstName: "John"})
+	c.Assert(err, qt.IsNil)
+	member, err := api.DB.Member(entities[0].ID, &members[0].ID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(member.CustomFields), qt.Equals, `{"branch": "Girona", "shares": 20}`)
+
+	// cleaning up
+	err = api.DB.DeleteEntity(entities[0].ID)
+	c.Assert(err, qt.IsNil)
+}
+
+func TestListEntities(t *testing.T) {
+	c := qt.New(t)
+	// create entities with a common name
+	_, entities := testcommon.CreateEntities(3)
+	prefix := fmt.Sprintf("listentities%d", rand.Int())
+	for i, entity := range entities {
+		entity.Name = fmt.Sprintf("%s %d", prefix, i)
+		err := api.DB.AddEntity(entity.ID, &entity.EntityInfo)
+		c.Assert(err, qt.IsNil)
+	}
+	_, err := api.DB.CreateNMembers(entities[0].ID, 2)
+	c.Assert(err, qt.IsNil)
+
+	count, err := api.DB.CountEntities(prefix)
+	c.Assert(err, qt.IsNil)
+	c.Assert(count, qt.Equals, 3)
+	// entities can also be found by their ID
+	count, err = api.DB.CountEntities(fmt.Sprintf("0x%x", entities[1].ID))
+	c.Assert(err, qt.IsNil)
+	c.Assert(count, qt.Equals, 1)
+
+	// pages are sorted and include the counts
+	list, next, err := api.DB.ListEntities(&types.ListOptions{Search: prefix, SortBy: "name", Count: 2})
+	c.Assert(err, qt.IsNil)
+	c.Assert(list, qt.HasLen, 2)
+	c.Assert(next, qt.Not(qt.Equals), "")
+	c.Assert(list[0].Name, qt.Equals, entities[0].Name)
+	c.Assert(*list[0].MembersCount, qt.Equals, 2)
+	c.Assert(*list[0].CensusesCount, qt.Equals, 0)
+	list, next, err = api.DB.ListEntities(&types.ListOptions{Search: prefix, SortBy: "name", Count: 2, Cursor: next})
+	c.Assert(err, qt.IsNil)
+	c.Assert(list, qt.HasLen, 1)
+	c.Assert(next, qt.Equals, "")
+	c.Assert(list[0].Name, qt.Equals, entities[2].Name)
+
+	// cleaning up
+	for _, entity := range entities {
+		err = api.DB.DeleteEntity(entity.ID)
+		c.Assert(err, qt.IsNil)
+	}
+}
+
+func TestEntityOperators(t *testing.T) {
+	c := qt.New(t)
+	// create entity
+	_, entities := testcommon.CreateEntities(1)
+	err := api.DB.AddEntity(entities[0].ID, &entities[0].EntityInfo)
+	c.Assert(err, qt.IsNil)
+	entityID := entities[0].ID
+
+	// the initial census managers are admins
+	operators, err := api.DB.EntityOperators(entityID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(operators, qt.HasLen, len(entities[0].CensusManagersAddresses))
+	c.Assert(operators[0].Role, qt.Equals, types.OperatorAdmin)
+
+	// operators are added and their roles updated
+	editor, viewer := util.RandomBytes(20), util.RandomBytes(20)
+	err = api.DB.SetEntityOperator(entityID, editor, types.OperatorAdmin)
+	c.Assert(err, qt.IsNil)
+	err = api.DB.SetEntityOperator(entityID, viewer, types.OperatorViewer)
+	c.Assert(err, qt.IsNil)
+	err = api.DB.SetEntityOperator(entityID, editor, types.OperatorEditor)
+	c.Assert(err, qt.IsNil)
+	role, err := api.DB.EntityOperatorRole(entityID, editor)
+	c.Assert(err, qt.IsNil)
+	c.Assert(role, qt.Equals, types.OperatorEditor)
+	role, err = api.DB.EntityOperatorRole(entityID, viewer)
+	c.Assert(err, qt.IsNil)
+	c.Assert(role, qt.Equals, types.OperatorViewer)
+	_, err = api.DB.EntityOperatorRole(entityID, util.RandomBytes(20))
+	c.Assert(err, qt.Equals, sql.ErrNoRows)
+	operators, err = api.DB.EntityOperators(entityID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(operators, qt.HasLen, len(entities[0].CensusManagersAddresses)+2)
+	entity, err := api.DB.Entity(entityID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(entity.CensusManagersAddresses, qt.HasLen, len(operators))
+
+	// removing an operator keeps the roles of the others
+	count, err := api.DB.RemoveEntityOperator(entityID, editor)
+	c.Assert(err, qt.IsNil)
+	c.Assert(count, qt.Equals, 1)
+	count, err = api.DB.RemoveEntityOperator(entityID, editor)
+	c.Assert(err, qt.IsNil)
+	c.Assert(count, qt.Equals, 0)
+	_, err = api.DB.EntityOperatorRole(entityID, editor)
+	c.Assert(err, qt.Equals, sql.ErrNoRows)
+	role, err = api.DB.EntityOperatorRole(entityID, viewer)
+	c.Assert(err, qt.IsNil)
+	c.Assert(role, qt.Equals, types.OperatorViewer)
+
+	// cleaning up
+	err = api.DB.DeleteEntity(entityID)
+	c.Assert(err, qt.IsNil)
+}
+
+func TestRetention(t *testing.T) {
+	c := qt.New(t)
+	// create entity
+	_, entities := testcommon.CreateEntities(1)
+	err := api.DB.AddEntity(entities[0].ID, &entities[0].EntityInfo)
+	c.Assert(err, qt.IsNil)
+	entityID := entities[0].ID
+
+	// entities start without retention
+	entity, err := api.DB.Entity(entityID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(*entity.Retention, qt.Equals, types.RetentionSettings{})
+	info := entities[0].EntityInfo
+	info.Retention = &types.RetentionSettings{UnverifiedMembersDays: 30, EphemeralKeysDays: 7}
+	count, err := api.DB.UpdateEntity(entityID, &info)
+	c.Assert(err, qt.IsNil)
+	c.Assert(count, qt.Equals, 1)
+	entity, err = api.DB.Entity(entityID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(*entity.Retention, qt.Equals, *info.Retention)
+
+	// an ephemeral census with two unverified members
+	memberIDs, err := api.DB.CreateNMembers(entityID, 2)
+	c.Assert(err, qt.IsNil)
+	targetID, err := api.DB.AddTarget(entityID, &types.Target{Name: "all", Filters: json.RawMessage(`{}`)})
+	c.Assert(err, qt.IsNil)
+	censusID := util.RandomBytes(32)
+	err = api.DB.AddCensus(entityID, censusID, &targetID, &types.CensusInfo{Name: "census", Ephemeral: true})
+	c.Assert(err, qt.IsNil)
+	_, err = api.DB.ExpandCensusMembers(entityID, censusID)
+	c.Assert(err, qt.IsNil)
+
+	// recent members are kept
+	purged, err := api.DB.PurgeUnverifiedMembers()
+	c.Assert(err, qt.IsNil)
+	c.Assert(purged[hex.EncodeToString(entityID)], qt.Equals, 0)
+	members, _, err := api.DB.Members(entityID, memberIDs)
+	c.Assert(err, qt.IsNil)
+	c.Assert(members, qt.HasLen, 2)
+
+	// keys are kept while the process has not ended for the retention days
+	endDate := time.Now().Add(-24 * time.Hour)
+	_, err = api.DB.UpdateCensus(entityID, censusID, &types.CensusInfo{ProcessID: util.RandomBytes(32), ProcessEndDate: &endDate})
+	c.Assert(err, qt.IsNil)
+	census, err := api.DB.Census(entityID, censusID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(census.ProcessID, qt.HasLen, 32)
+	c.Assert(census.ProcessEndDate, qt.Not(qt.IsNil))
+	dropped, err := api.DB.DropEphemeralKeys()
+	c.Assert(err, qt.IsNil)
+	c.Assert(dropped[hex.EncodeToString(entityID)], qt.Equals, 0)
+
+	// and dropped afterwards
+	endDate = time.Now().Add(-8 * 24 * time.Hour)
+	_, err = api.DB.UpdateCensus(entityID, censusID, &types.CensusInfo{ProcessEndDate: &endDate})
+	c.Assert(err, qt.IsNil)
+	dropped, err = api.DB.DropEphemeralKeys()
+	c.Assert(err, qt.IsNil)
+	c.Assert(dropped[hex.EncodeToString(entityID)], qt.Equals, 2)
+	ephemeralMembers, err := api.DB.ListEphemeralMemberInfo(entityID, censusID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(ephemeralMembers, qt.HasLen, 2)
+	for _, member := range ephemeralMembers {
+		c.Assert(member.PrivKey, qt.HasLen, 0)
+	}
+
+	// cleaning up
+	err = api.DB.DeleteEntity(entityID)
+	c.Assert(err, qt.IsNil)
+}
+
+func TestCountActiveProcessCensuses(t *testing.T) {
+	c := qt.New(t)
+	// create entity
+	_, entities := testcommon.CreateEntities(1)
+	err := api.DB.AddEntity(entities[0].ID, &entities[0].EntityInfo)
+	c.Assert(err, qt.IsNil)
+	entityID := entities[0].ID
+	targetID, err := api.DB.AddTarget(entityID, &types.Target{Name: "all", Filters: json.RawMessage(`{}`)})
+	c.Assert(err, qt.IsNil)
+	censusID := util.RandomBytes(32)
+	err = api.DB.AddCensus(entityID, censusID, &targetID, &types.CensusInfo{Name: "census"})
+	c.Assert(err, qt.IsNil)
+
+	// censuses without process are not counted
+	since := time.Now().Add(-7 * 24 * time.Hour)
+	count, err := api.DB.CountActiveProcessCensuses(entityID, since)
+	c.Assert(err, qt.IsNil)
+	c.Assert(count, qt.Equals, 0)
+
+	// a census of a process with unknown end date is counted
+	_, err = api.DB.UpdateCensus(entityID, censusID, &types.CensusInfo{ProcessID: util.RandomBytes(32)})
+	c.Assert(err, qt.IsNil)
+	count, err = api.DB.CountActiveProcessCensuses(entityID, since)
+	c.Assert(err, qt.IsNil)
+	c.Assert(count, qt.Equals, 1)
+
+	// as well as one of a process that ended after since
+	endDate := time.Now().Add(-24 * time.Hour)
+	_, err = api.DB.UpdateCensus(entityID, censusID, &types.CensusInfo{ProcessEndDate: &endDate})
+	c.Assert(err, qt.IsNil)
+	count, err = api.DB.CountActiveProcessCensuses(entityID, since)
+	c.Assert(err, qt.IsNil)
+	c.Assert(count, qt.Equals, 1)
+
+	// but not once the process ended before
+	endDate = time.Now().Add(-8 * 24 * time.Hour)
+	_, err = api.DB.UpdateCensus(entityID, censusID, &types.CensusInfo{ProcessEndDate: &endDate})
+	c.Assert(err, qt.IsNil)
+	count, err = api.DB.CountActiveProcessCensuses(entityID, since)
+	c.Assert(err, qt.IsNil)
+	c.Assert(count, qt.Equals, 0)
+
+	// cleaning up
+	err = api.DB.DeleteEntity(entityID)
+	c.Assert(err, qt.IsNil)
+}
+
+func TestEntityQuotas(t *testing.T) {
+	c := qt.New(t)
+	// create entity
+	_, entities := testcommon.CreateEntities(1)
+	err := api.DB.AddEntity(entities[0].ID, &entities[0].EntityInfo)
+	c.Assert(err, qt.IsNil)
+	entityID := entities[0].ID
+
+	// entities start without quotas
+	entity, err := api.DB.Entity(entityID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(*entity.Quotas, qt.Equals, types.Quotas{})
+	quotas := types.Quotas{Members: 10, EmailsPerDay: 100}
+	count, err := api.DB.SetEntityQuotas(entityID, &quotas)
+	c.Assert(err, qt.IsNil)
+	c.Assert(count, qt.Equals, 1)
+	entity, err = api.DB.Entity(entityID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(*entity.Quotas, qt.Equals, quotas)
+
+	// usage of members, censuses, emails and faucet requests
+	memberIDs, err := api.DB.CreateNMembers(entityID, 3)
+	c.Assert(err, qt.IsNil)
+	targetID, err := api.DB.AddTarget(entityID, &types.Target{Name: "all", Filters: json.RawMessage(`{}`)})
+	c.Assert(err, qt.IsNil)
+	err = api.DB.AddCensus(entityID, util.RandomBytes(32), &targetID, &types.CensusInfo{Name: "census"})
+	c.Assert(err, qt.IsNil)
+	err = api.DB.AddMemberEmails(entityID, memberIDs[:2], types.MemberEmailValidation, nil, nil)
+	c.Assert(err, qt.IsNil)
+	err = api.DB.AddFaucetRequest(entityID)
+	c.Assert(err, qt.IsNil)
+	now := time.Now().UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	reserved, err := api.DB.ReserveEmails(entityID, day, 3, 4)
+	c.Assert(err, qt.IsNil)
+	c.Assert(reserved, qt.IsTrue)
+	// should not reserve over the limit
+	reserved, err = api.DB.ReserveEmails(entityID, day, 2, 4)
+	c.Assert(err, qt.IsNil)
+	c.Assert(reserved, qt.IsFalse)
+	reserved, err = api.DB.ReserveEmails(entityID, day.AddDate(0, 0, 1), 5, 4)
+	c.Assert(err, qt.IsNil)
+	c.Assert(reserved, qt.IsFalse)
+	err = api.DB.ReleaseEmails(entityID, day, 1)
+	c.Assert(err, qt.IsNil)
+	// deleting members does not give back their emails
+	_, _, err = api.DB.DeleteMembers(entityID, memberIDs[1:])
+	c.Assert(err, qt.IsNil)
+	_, err = api.DB.PurgeDeletedMembers(time.Now().Add(time.Hour))
+	c.Assert(err, qt.IsNil)
+	usage, err := api.DB.EntityUsage(entityID, day, day)
+	c.Assert(err, qt.IsNil)
+	c.Assert(*usage, qt.Equals, types.Usage{Members: 1, Censuses: 1, EmailsToday: 2, FaucetRequestsThisMonth: 1})
+
+	// only the emails and faucet requests of the period are counted
+	usage, err = api.DB.EntityUsage(entityID, day.AddDate(0, 0, 1), time.Now().Add(time.Hour))
+	c.Assert(err, qt.IsNil)
+	c.Assert(*usage, qt.Equals, types.Usage{Members: 1, Censuses: 1})
+
+	// cleaning up
+	err = api.DB.DeleteEntity(entityID)
+	c.Assert(err, qt.IsNil)
+}
+
 func TestUser(t *testing.T) {
 	var err error
 	userSigner := ethereum.NewSignKeys()
@@ -207,7 +551,7 @@ func TestMember(t *testing.T) {
 	if err != nil {
 		t.Fatalf("cannot add user to the Postgres DB (pgsql.go:addUser) %s", err)
 	}
-	if initialCount, err = api.DB.CountMembers(entities[0].ID); err != nil {
+	if initialCount, err = api.DB.CountMembers(entities[0].ID, ""); err != nil {
 		t.Fatalf("cannot count members correctly: %+v", err)
 	}
 
@@ -216,7 +560,7 @@ func TestMember(t *testing.T) {
 		t.Fatalf("cannot add member to the Postgres DB (pgsql.go:addMember): %s", err)
 	}
 
-	if count, err = api.DB.CountMembers(entities[0].ID); err != nil || count != initialCount+1 {
+	if count, err = api.DB.CountMembers(entities[0].ID, ""); err != nil || count != initialCount+1 {
 		t.Fatalf("expected %d counted: %d\ncannot count members correctly: %+v", initialCount+1, count, err)
 	}
 
@@ -305,12 +649,12 @@ func TestMember(t *testing.T) {
 		t.Fatalf("cannot add members to Postgres DB (pgsql.go:AddMemberBulk): %s", err)
 	}
 
-	if count, err = api.DB.CountMembers(entities[0].ID); err != nil || count != initialCount+11 {
+	if count, err = api.DB.CountMembers(entities[0].ID, ""); err != nil || count != initialCount+11 {
 		t.Fatalf("expected %d counted: %d\ncannot count members correctly: %+v", initialCount+11, count, err)
 	}
 
 	// Test Selecting all members
-	allMembers, err := api.DB.ListMembers(entities[0].ID, &types.ListOptions{})
+	allMembers, _, err := api.DB.ListMembers(entities[0].ID, &types.ListOptions{})
 	if err != nil {
 		t.Fatalf("cannot select all members from Postgres DB (pgsql.go:ListMembers): %s", err)
 	}
@@ -346,7 +690,7 @@ func TestMember(t *testing.T) {
 		SortBy: "lastName",
 		Order:  "descend",
 	}
-	members, err = api.DB.ListMembers(entities[0].ID, filter)
+	members, _, err = api.DB.ListMembers(entities[0].ID, filter)
 	if err != nil {
 		t.Fatalf("cannot select all members from Postgres DB (pgsql.go:ListMembers): %s", err)
 	}
@@ -429,7 +773,7 @@ func TestMember(t *testing.T) {
 		t.Fatalf("able to register member using existing token but to non-correspondig entity:  (%+v)", err)
 	}
 
-	members, err = api.DB.ListMembers(entities[0].ID, nil)
+	members, _, err = api.DB.ListMembers(entities[0].ID, nil)
 	if err != nil {
 		t.Fatalf("cannot select all members from Postgres DB (pgsql.go:ListMembers): %s", err)
 	}
@@ -455,7 +799,7 @@ func TestMember(t *testing.T) {
 	if updatedCount != len(memberIDs) {
 		t.Fatalf("expected to delete %d but deleted %d members", len(memberIDs), updatedCount)
 	}
-	if n, err = api.DB.CountMembers(entities[0].ID); err != nil {
+	if n, err = api.DB.CountMembers(entities[0].ID, ""); err != nil {
 		t.Fatalf("cannot count  members from Postgres DB (pgsql.go:DeleteMembers): %s", err)
 	}
 	if n != 0 {
@@ -526,13 +870,524 @@ func TestMember(t *testing.T) {
 	// cleaning up
 	for _, entity := range entities {
 		if err := api.DB.DeleteEntity(entity.ID); err != nil {
-			t.Errorf("error deleting test entity: %w", err)
+			t.Errorf("error deleting test entity: %v", err)
 		}
 
 	}
 
 }
 
+func TestListMembersCursor(t *testing.T) {
+	c := qt.New(t)
+	// create entity
+	_, entities := testcommon.CreateEntities(1)
+	err := api.DB.AddEntity(entities[0].ID, &entities[0].EntityInfo)
+	c.Assert(err, qt.IsNil)
+
+	// repeated last names and members without email
+	var membersInfo []types.MemberInfo
+	for i := 0; i < 7; i++ {
+		membersInfo = append(membersInfo, types.MemberInfo{
+			FirstName: fmt.Sprintf("Name%d", i),
+			LastName:  fmt.Sprintf("LastName%d", i%3),
+			Email:     fmt.Sprintf("member%d@vocdoni.io", i),
+		})
+	}
+	err = api.DB.ImportMembers(entities[0].ID, membersInfo)
+	c.Assert(err, qt.IsNil)
+	_, err = api.DB.CreateNMembers(entities[0].ID, 3)
+	c.Assert(err, qt.IsNil)
+
+	for _, options := range []types.ListOptions{
+		{},
+		{SortBy: "lastName", Order: "descend"},
+		{SortBy: "email"},
+		{SortBy: "dateOfBirth", Order: "descend"},
+	} {
+		all, next, err := api.DB.ListMembers(entities[0].ID, &options)
+		c.Assert(err, qt.IsNil)
+		c.Assert(all, qt.HasLen, 10)
+		c.Assert(next, qt.Equals, "")
+
+		// walking the pages returns all the members in the same order
+		var paged []uuid.UUID
+		options.Count = 3
+		for pages := 0; ; pages++ {
+			c.Assert(pages < 4, qt.IsTrue, qt.Commentf("too many pages"))
+			members, next, err := api.DB.ListMembers(entities[0].ID, &options)
+			c.Assert(err, qt.IsNil)
+			for _, member := range members {
+				paged = append(paged, member.ID)
+			}
+			if next == "" {
+				break
+			}
+			options.Cursor = next
+		}
+		c.Assert(paged, qt.HasLen, len(all))
+		for i := range all {
+			c.Assert(paged[i], qt.Equals, all[i].ID, qt.Commentf("options: %+v", options))
+		}
+	}
+
+	// offset pagination still works
+	members, next, err := api.DB.ListMembers(entities[0].ID, &types.ListOptions{Count: 5, Skip: 8})
+	c.Assert(err, qt.IsNil)
+	c.Assert(members, qt.HasLen, 2)
+	c.Assert(next, qt.Equals, "")
+
+	// cursors cannot be reused with other sort options
+	_, next, err = api.DB.ListMembers(entities[0].ID, &types.ListOptions{Count: 5})
+	c.Assert(err, qt.IsNil)
+	_, _, err = api.DB.ListMembers(entities[0].ID, &types.ListOptions{Count: 5, SortBy: "email", Cursor: next})
+	c.Assert(err, qt.Not(qt.IsNil))
+	_, _, err = api.DB.ListMembers(entities[0].ID, &types.ListOptions{Cursor: "invalid"})
+	c.Assert(err, qt.Not(qt.IsNil))
+
+	// cleaning up
+	err = api.DB.DeleteEntity(entities[0].ID)
+	c.Assert(err, qt.IsNil)
+}
+
+func TestExistingMemberEmails(t *testing.T) {
+	c := qt.New(t)
+	// create entities
+	_, entities := testcommon.CreateEntities(2)
+	for _, entity := range entities {
+		err := api.DB.AddEntity(entity.ID, &entity.EntityInfo)
+		c.Assert(err, qt.IsNil)
+	}
+	err := api.DB.ImportMembers(entities[0].ID, []types.MemberInfo{{Email: "john@vocdoni.io"}, {Email: "jane@vocdoni.io"}})
+	c.Assert(err, qt.IsNil)
+
+	existing, err := api.DB.ExistingMemberEmails(entities[0].ID, []string{"jane@vocdoni.io", "joan@vocdoni.io"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(existing, qt.DeepEquals, []string{"jane@vocdoni.io"})
+	// emails are only checked within the entity
+	existing, err = api.DB.ExistingMemberEmails(entities[1].ID, []string{"jane@vocdoni.io"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(existing, qt.HasLen, 0)
+
+	// cleaning up
+	for _, entity := range entities {
+		err = api.DB.DeleteEntity(entity.ID)
+		c.Assert(err, qt.IsNil)
+	}
+}
+
+func TestSearchMembers(t *testing.T) {
+	c := qt.New(t)
+	// create entity
+	_, entities := testcommon.CreateEntities(1)
+	err := api.DB.AddEntity(entities[0].ID, &entities[0].EntityInfo)
+	c.Assert(err, qt.IsNil)
+
+	err = api.DB.ImportMembers(entities[0].ID, []types.MemberInfo{
+		{FirstName: "John", LastName: "Smith", Email: "john@vocdoni.io", Phone: "+34600000001"},
+		{FirstName: "Jane", LastName: "Smithson", Email: "jane@example.com", Phone: "+34600000002",
+			CustomFields: json.RawMessage(`{"branch": {"city": "Barcelona"}, "shares": 10}`)},
+		{FirstName: "Joan", LastName: "Garcia", Email: "joan@vocdoni.io", Phone: "+34611111111",
+			CustomFields: json.RawMessage(`{"notes": ["50%_off", "Girona"]}`)},
+	})
+	c.Assert(err, qt.IsNil)
+
+	for _, tc := range []struct {
+		search string
+		count  int
+	}{
+		{"", 3},
+		{"smith", 2},
+		{"SMITHSON", 1},
+		{"jo", 2},
+		{"oh", 0},
+		{"ohn", 1},
+		{"vocdoni.io", 2},
+		{"+346000", 2},
+		{"barcelona", 1},
+		{"giro", 1},
+		{"50%_", 1},
+		{"50%x", 0},
+		{"10", 0},
+		{"jane barcelona", 1},
+		{"john barcelona", 0},
+	} {
+		count, err := api.DB.CountMembers(entities[0].ID, tc.search)
+		c.Assert(err, qt.IsNil)
+		c.Assert(count, qt.Equals, tc.count, qt.Commentf("search: %q", tc.search))
+		members, _, err := api.DB.ListMembers(entities[0].ID, &types.ListOptions{Search: tc.search})
+		c.Assert(err, qt.IsNil)
+		c.Assert(members, qt.HasLen, tc.count, qt.Commentf("search: %q", tc.search))
+	}
+
+	// search can be combined with cursors
+	members, next, err := api.DB.ListMembers(entities[0].ID, &types.ListOptions{Search: "smith", Count: 1})
+	c.Assert(err, qt.IsNil)
+	c.Assert(members, qt.HasLen, 1)
+	c.Assert(members[0].LastName, qt.Equals, "Smith")
+	members, next, err = api.DB.ListMembers(entities[0].ID, &types.ListOptions{Search: "smith", Count: 1, Cursor: next})
+	c.Assert(err, qt.IsNil)
+	c.Assert(members, qt.HasLen, 1)
+	c.Assert(members[0].LastName, qt.Equals, "Smithson")
+	c.Assert(next, qt.Equals, "")
+
+	// cleaning up
+	err = api.DB.DeleteEntity(entities[0].ID)
+	c.Assert(err, qt.IsNil)
+}
+
+func TestMemberEvents(t *testing.T) {
+	c := qt.New(t)
+	// create entity
+	_, entities := testcommon.CreateEntities(1)
+	err := api.DB.AddEntity(entities[0].ID, &entities[0].EntityInfo)
+	c.Assert(err, qt.IsNil)
+	entityID := entities[0].ID
+
+	err = api.DB.ImportMembers(entityID, []types.MemberInfo{{FirstName: "John", Email: "john@vocdoni.io"}})
+	c.Assert(err, qt.IsNil)
+	members, _, err := api.DB.ListMembers(entityID, nil)
+	c.Assert(err, qt.IsNil)
+	c.Assert(members, qt.HasLen, 1)
+	memberID := members[0].ID
+
+	// updates record only the changed fields
+	_, err = api.DB.UpdateMember(entityID, &memberID, &types.MemberInfo{Email: "john.smith@vocdoni.io"})
+	c.Assert(err, qt.IsNil)
+	tagID, err := api.DB.AddTag(entityID, "TestTag")
+	c.Assert(err, qt.IsNil)
+	_, _, err = api.DB.AddTagToMembers(entityID, []uuid.UUID{memberID}, tagID)
+	c.Assert(err, qt.IsNil)
+	// updates without changes are not recorded
+	_, err = api.DB.UpdateMember(entityID, &memberID, &types.MemberInfo{FirstName: "John"})
+	c.Assert(err, qt.IsNil)
+	_, _, err = api.DB.DeleteMembers(entityID, []uuid.UUID{memberID})
+	c.Assert(err, qt.IsNil)
+
+	// the history is kept after deleting the member
+	events, err := api.DB.MemberEvents(entityID, &memberID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(events, qt.HasLen, 4)
+	operations := make([]string, len(events))
+	for i, event := range events {
+		c.Assert(event.MemberID, qt.Equals, memberID)
+		c.Assert(event.ActorType, qt.Equals, types.MemberEventActorEntity)
+		c.Assert([]byte(event.Actor), qt.DeepEquals, entityID)
+		operations[i] = event.Operation
+	}
+	c.Assert(operations, qt.DeepEquals, []string{types.MemberEventCreate, types.MemberEventUpdate, types.MemberEventAddTag, types.MemberEventDelete})
+
+	var changes map[string]struct {
+		Before json.RawMessage `json:"before"`
+		After  json.RawMessage `json:"after"`
+	}
+	err = json.Unmarshal(events[1].Changes, &changes)
+	c.Assert(err, qt.IsNil)
+	c.Assert(changes, qt.HasLen, 1)
+	c.Assert(string(changes["email"].Before), qt.Equals, `"john@vocdoni.io"`)
+	c.Assert(string(changes["email"].After), qt.Equals, `"john.smith@vocdoni.io"`)
+	changes = nil
+	err = json.Unmarshal(events[2].Changes, &changes)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(changes["tags"].After), qt.Equals, fmt.Sprintf("[%d]", tagID))
+
+	// registry changes are recorded with the user key
+	tokens, err := api.DB.CreateNMembers(entityID, 1)
+	c.Assert(err, qt.IsNil)
+	user := ethereum.NewSignKeys()
+	c.Assert(user.Generate(), qt.IsNil)
+	pubKey := user.PublicKey()
+	err = api.DB.RegisterMember(entityID, pubKey, &tokens[0])
+	c.Assert(err, qt.IsNil)
+	events, err = api.DB.MemberEvents(entityID, &tokens[0])
+	c.Assert(err, qt.IsNil)
+	c.Assert(events, qt.HasLen, 2)
+	c.Assert(events[1].Operation, qt.Equals, types.MemberEventRegister)
+	c.Assert(events[1].ActorType, qt.Equals, types.MemberEventActorUser)
+	c.Assert([]byte(events[1].Actor), qt.DeepEquals, pubKey)
+
+	// and changes done on behalf of the entity with the operator address
+	operator := util.RandomBytes(20)
+	_, err = api.DB.AsOperator(operator).UpdateMember(entityID, &tokens[0], &types.MemberInfo{Email: "jane@vocdoni.io"})
+	c.Assert(err, qt.IsNil)
+	events, err = api.DB.MemberEvents(entityID, &tokens[0])
+	c.Assert(err, qt.IsNil)
+	c.Assert(events, qt.HasLen, 3)
+	c.Assert(events[2].ActorType, qt.Equals, types.MemberEventActorOperator)
+	c.Assert([]byte(events[2].Actor), qt.DeepEquals, operator)
+
+	// cleaning up
+	err = api.DB.DeleteEntity(entityID)
+	c.Assert(err, qt.IsNil)
+	events, err = api.DB.MemberEvents(entityID, &memberID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(events, qt.HasLen, 0)
+}
+
+func TestSoftDeleteMembers(t *testing.T) {
+	c := qt.New(t)
+	// create entity
+	_, entities := testcommon.CreateEntities(1)
+	err := api.DB.AddEntity(entities[0].ID, &entities[0].EntityInfo)
+	c.Assert(err, qt.IsNil)
+	entityID := entities[0].ID
+
+	// registered members in a census
+	_, members, err := testcommon.CreateMembers(entityID, 3)
+	c.Assert(err, qt.IsNil)
+	for i := range members {
+		members[i].Email = fmt.Sprintf("member%d@vocdoni.io", i)
+	}
+	err = api.DB.AddMemberBulk(entityID, members)
+	c.Assert(err, qt.IsNil)
+	members, _, err = api.DB.ListMembers(entityID, &types.ListOptions{SortBy: "email"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(members, qt.HasLen, 3)
+	targetID, err := api.DB.AddTarget(entityID, &types.Target{Name: "all", Filters: json.RawMessage(`{}`)})
+	c.Assert(err, qt.IsNil)
+	censusID := util.RandomBytes(32)
+	censusInfo := &types.CensusInfo{Name: "census", MerkleRoot: util.RandomBytes(32), MerkleTreeURI: "ipfs://census"}
+	_, err = api.DB.AddCensusWithMembers(entityID, censusID, &targetID, censusInfo)
+	c.Assert(err, qt.IsNil)
+
+	// deleted members are hidden
+	deleted, invalidIDs, err := api.DB.DeleteMembers(entityID, []uuid.UUID{members[0].ID, members[1].ID})
+	c.Assert(err, qt.IsNil)
+	c.Assert(deleted, qt.Equals, 2)
+	c.Assert(invalidIDs, qt.HasLen, 0)
+	_, err = api.DB.Member(entityID, &members[0].ID)
+	c.Assert(err, qt.Equals, sql.ErrNoRows)
+	count, err := api.DB.CountMembers(entityID, "")
+	c.Assert(err, qt.IsNil)
+	c.Assert(count, qt.Equals, 1)
+	targetMembers, err := api.DB.TargetMembers(entityID, &targetID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(targetMembers, qt.HasLen, 1)
+	claims, err := api.DB.DumpClaims(entityID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(claims, qt.HasLen, 1)
+	// but kept in the existing censuses
+	claims, err = api.DB.DumpCensusClaims(entityID, censusID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(claims, qt.HasLen, 3)
+	// deleting twice is not possible
+	_, invalidIDs, err = api.DB.DeleteMembers(entityID, []uuid.UUID{members[0].ID})
+	c.Assert(err, qt.IsNil)
+	c.Assert(invalidIDs, qt.DeepEquals, []uuid.UUID{members[0].ID})
+
+	deletedMembers, _, err := api.DB.ListDeletedMembers(entityID, &types.ListOptions{SortBy: "email"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(deletedMembers, qt.HasLen, 2)
+	c.Assert(deletedMembers[0].ID, qt.Equals, members[0].ID)
+	c.Assert(deletedMembers[0].DeletedAt, qt.Not(qt.IsNil))
+
+	// the email of a deleted member can be reused, which prevents restoring it
+	err = api.DB.ImportMembers(entityID, []types.MemberInfo{{Email: members[1].Email}})
+	c.Assert(err, qt.IsNil)
+	restored, invalidIDs, err := api.DB.RestoreMembers(entityID, []uuid.UUID{members[0].ID, members[1].ID, members[2].ID})
+	c.Assert(err, qt.IsNil)
+	c.Assert(restored, qt.Equals, 1)
+	c.Assert(invalidIDs, qt.HasLen, 2)
+	member, err := api.DB.Member(entityID, &members[0].ID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(member.Email, qt.Equals, members[0].Email)
+	events, err := api.DB.MemberEvents(entityID, &members[0].ID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(events[len(events)-1].Operation, qt.Equals, types.MemberEventRestore)
+
+	// purging permanently deletes the members and their census rows
+	_, err = api.DB.PurgeDeletedMembers(time.Now().Add(-time.Hour))
+	c.Assert(err, qt.IsNil)
+	deletedMembers, _, err = api.DB.ListDeletedMembers(entityID, nil)
+	c.Assert(err, qt.IsNil)
+	c.Assert(deletedMembers, qt.HasLen, 1)
+	purged, err := api.DB.PurgeDeletedMembers(time.Now())
+	c.Assert(err, qt.IsNil)
+	c.Assert(purged >= 1, qt.IsTrue)
+	deletedMembers, _, err = api.DB.ListDeletedMembers(entityID, nil)
+	c.Assert(err, qt.IsNil)
+	c.Assert(deletedMembers, qt.HasLen, 0)
+	claims, err = api.DB.DumpCensusClaims(entityID, censusID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(claims, qt.HasLen, 2)
+	// the purge is recorded without the member data
+	events, err = api.DB.MemberEvents(entityID, &members[1].ID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(events[len(events)-1].Operation, qt.Equals, types.MemberEventPurge)
+	c.Assert(string(events[len(events)-1].Changes), qt.Equals, "{}")
+
+	// cleaning up
+	err = api.DB.DeleteEntity(entityID)
+	c.Assert(err, qt.IsNil)
+}
+
+func TestUpdateMembers(t *testing.T) {
+	c := qt.New(t)
+	// create entity
+	_, entities := testcommon.CreateEntities(1)
+	err := api.DB.AddEntity(entities[0].ID, &entities[0].EntityInfo)
+	c.Assert(err, qt.IsNil)
+	entityID := entities[0].ID
+
+	membersInfo := []types.MemberInfo{
+		{Email: "update0@vocdoni.io", FirstName: "Zero", Consented: true, CustomFields: json.RawMessage(`{"shares": 1, "branch": "Girona"}`)},
+		{Email: "update1@vocdoni.io", FirstName: "One", Consented: true, CustomFields: json.RawMessage(`{"shares": 2}`)},
+		{Email: "update2@vocdoni.io", FirstName: "Two", Consented: true},
+	}
+	err = api.DB.ImportMembers(entityID, membersInfo)
+	c.Assert(err, qt.IsNil)
+	members, _, err := api.DB.ListMembers(entityID, &types.ListOptions{SortBy: "email"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(members, qt.HasLen, 3)
+
+	// update a list of members, skipping the deleted and the unknown ones
+	_, _, err = api.DB.DeleteMembers(entityID, []uuid.UUID{members[2].ID})
+	c.Assert(err, qt.IsNil)
+	consented := false
+	lastName := "Updated"
+	unknownID := uuid.New()
+	updated, invalidIDs, err := api.DB.UpdateMembers(entityID,
+		[]uuid.UUID{members[0].ID, members[1].ID, members[2].ID, unknownID},
+		&types.MemberPatch{
+			LastName:     &lastName,
+			Consented:    &consented,
+			CustomFields: json.RawMessage(`{"shares": 5, "branch": null}`),
+		})
+	c.Assert(err, qt.IsNil)
+	c.Assert(updated, qt.Equals, 2)
+	c.Assert(invalidIDs, qt.HasLen, 2)
+	for _, id := range []uuid.UUID{members[0].ID, members[1].ID} {
+		member, err := api.DB.Member(entityID, &id)
+		c.Assert(err, qt.IsNil)
+		// fields not in the patch are kept
+		c.Assert(member.FirstName, qt.Not(qt.Equals), "")
+		c.Assert(member.LastName, qt.Equals, lastName)
+		c.Assert(member.Consented, qt.IsFalse)
+		var customFields map[string]interface{}
+		err = json.Unmarshal(member.CustomFields, &customFields)
+		c.Assert(err, qt.IsNil)
+		c.Assert(customFields, qt.DeepEquals, map[string]interface{}{"shares": float64(5)})
+	}
+	events, err := api.DB.MemberEvents(entityID, &members[0].ID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(events[len(events)-1].Operation, qt.Equals, types.MemberEventUpdate)
+
+	// an empty patch is rejected
+	_, _, err = api.DB.UpdateMembers(entityID, []uuid.UUID{members[0].ID}, &types.MemberPatch{})
+	c.Assert(err, qt.Not(qt.IsNil))
+
+	// update the members matching some filters
+	filters, err := types.ParseTargetFilters(json.RawMessage(`{"customFields": [{"path": "shares", "op": "eq", "value": 5}]}`))
+	c.Assert(err, qt.IsNil)
+	consented = true
+	updated, err = api.DB.UpdateMembersByFilters(entityID, filters, &types.MemberPatch{Consented: &consented})
+	c.Assert(err, qt.IsNil)
+	c.Assert(updated, qt.Equals, 2)
+	member, err := api.DB.Member(entityID, &members[1].ID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(member.Consented, qt.IsTrue)
+	filters, err = types.ParseTargetFilters(json.RawMessage(`{}`))
+	c.Assert(err, qt.IsNil)
+	updated, err = api.DB.UpdateMembersByFilters(entityID, filters, &types.MemberPatch{FirstName: &lastName})
+	c.Assert(err, qt.IsNil)
+	c.Assert(updated, qt.Equals, 2)
+}
+
+func TestMemberData(t *testing.T) {
+	c := qt.New(t)
+	// create entity
+	_, entities := testcommon.CreateEntities(1)
+	err := api.DB.AddEntity(entities[0].ID, &entities[0].EntityInfo)
+	c.Assert(err, qt.IsNil)
+	entityID := entities[0].ID
+
+	// a registered and tagged member in a census with an email sent
+	tokens, err := api.DB.CreateNMembers(entityID, 2)
+	c.Assert(err, qt.IsNil)
+	memberID := tokens[0]
+	_, err = api.DB.UpdateMember(entityID, &memberID, &types.MemberInfo{FirstName: "John", Email: "john@vocdoni.io"})
+	c.Assert(err, qt.IsNil)
+	user := ethereum.NewSignKeys()
+	c.Assert(user.Generate(), qt.IsNil)
+	pubKey := user.PublicKey()
+	err = api.DB.RegisterMember(entityID, pubKey, &memberID)
+	c.Assert(err, qt.IsNil)
+	tagID, err := api.DB.AddTag(entityID, "Board")
+	c.Assert(err, qt.IsNil)
+	_, _, err = api.DB.AddTagToMembers(entityID, []uuid.UUID{memberID}, tagID)
+	c.Assert(err, qt.IsNil)
+	targetID, err := api.DB.AddTarget(entityID, &types.Target{Name: "all", Filters: json.RawMessage(`{}`)})
+	c.Assert(err, qt.IsNil)
+	censusID := util.RandomBytes(32)
+	censusInfo := &types.CensusInfo{Name: "census", MerkleRoot: util.RandomBytes(32), MerkleTreeURI: "ipfs://census"}
+	_, err = api.DB.AddCensusWithMembers(entityID, censusID, &targetID, censusInfo)
+	c.Assert(err, qt.IsNil)
+	processID := util.RandomBytes(32)
+	err = api.DB.AddMemberEmails(entityID, []uuid.UUID{memberID, uuid.New()}, types.MemberEmailVotingLink, censusID, processID)
+	c.Assert(err, qt.IsNil)
+
+	// export everything about the member
+	data, err := api.DB.ExportMemberData(entityID, &memberID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(data.Member.ID, qt.Equals, memberID)
+	c.Assert(data.Member.Email, qt.Equals, "john@vocdoni.io")
+	c.Assert(data.Tags, qt.DeepEquals, []string{"Board"})
+	c.Assert(data.Censuses, qt.HasLen, 1)
+	c.Assert([]byte(data.Censuses[0].ID), qt.DeepEquals, censusID)
+	c.Assert(data.Emails, qt.HasLen, 1)
+	c.Assert(data.Emails[0].Type, qt.Equals, types.MemberEmailVotingLink)
+	c.Assert([]byte(data.Emails[0].ProcessID), qt.DeepEquals, processID)
+	c.Assert(data.User, qt.Not(qt.IsNil))
+	c.Assert(data.User.PubKey, qt.DeepEquals, pubKey)
+	c.Assert(len(data.Events) > 0, qt.IsTrue)
+	// the export is recorded
+	events, err := api.DB.MemberEvents(entityID, &memberID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(events[len(events)-1].Operation, qt.Equals, types.MemberEventExport)
+	_, err = api.DB.ExportMemberData(entityID, &tokens[1])
+	c.Assert(err, qt.IsNil)
+	unknownID := uuid.New()
+	_, err = api.DB.ExportMemberData(entityID, &unknownID)
+	c.Assert(err, qt.Equals, sql.ErrNoRows)
+
+	// erase the member keeping its census rows
+	err = api.DB.EraseMember(entityID, &memberID)
+	c.Assert(err, qt.IsNil)
+	_, err = api.DB.Member(entityID, &memberID)
+	c.Assert(err, qt.Equals, sql.ErrNoRows)
+	_, err = api.DB.User(pubKey)
+	c.Assert(err, qt.Equals, sql.ErrNoRows)
+	claims, err := api.DB.DumpCensusClaims(entityID, censusID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(claims, qt.HasLen, 1)
+	// its history keeps the operations but not the data
+	events, err = api.DB.MemberEvents(entityID, &memberID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(events[len(events)-1].Operation, qt.Equals, types.MemberEventErase)
+	for _, event := range events {
+		c.Assert(string(event.Changes), qt.Equals, "{}")
+	}
+	// erased members cannot be exported, erased twice, restored nor purged
+	_, err = api.DB.ExportMemberData(entityID, &memberID)
+	c.Assert(err, qt.Equals, sql.ErrNoRows)
+	err = api.DB.EraseMember(entityID, &memberID)
+	c.Assert(err, qt.Equals, sql.ErrNoRows)
+	deletedMembers, _, err := api.DB.ListDeletedMembers(entityID, nil)
+	c.Assert(err, qt.IsNil)
+	c.Assert(deletedMembers, qt.HasLen, 0)
+	restored, _, err := api.DB.RestoreMembers(entityID, []uuid.UUID{memberID})
+	c.Assert(err, qt.IsNil)
+	c.Assert(restored, qt.Equals, 0)
+	_, err = api.DB.PurgeDeletedMembers(time.Now().Add(time.Hour))
+	c.Assert(err, qt.IsNil)
+	claims, err = api.DB.DumpCensusClaims(entityID, censusID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(claims, qt.HasLen, 1)
+
+	// cleaning up
+	err = api.DB.DeleteEntity(entityID)
+	c.Assert(err, qt.IsNil)
+}
+
 func TestTarget(t *testing.T) {
 	var inTarget, outTarget *types.Target
 	var targets []types.Target
@@ -586,6 +1441,187 @@ func TestTarget(t *testing.T) {
 	}
 }
 
+func TestUpdateDeleteTarget(t *testing.T) {
+	c := qt.New(t)
+	// create entity
+	_, entities := testcommon.CreateEntities(1)
+	err := api.DB.AddEntity(entities[0].ID, &entities[0].EntityInfo)
+	c.Assert(err, qt.IsNil)
+
+	targetID, err := api.DB.AddTarget(entities[0].ID, &types.Target{EntityID: entities[0].ID, Name: "all", Filters: json.RawMessage("{}")})
+	c.Assert(err, qt.IsNil)
+	otherID, err := api.DB.AddTarget(entities[0].ID, &types.Target{EntityID: entities[0].ID, Name: "verified", Filters: json.RawMessage(`{"verified": true}`)})
+	c.Assert(err, qt.IsNil)
+
+	// empty fields are not updated
+	count, err := api.DB.UpdateTarget(entities[0].ID, &otherID, &types.Target{Name: "validated"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(count, qt.Equals, 1)
+	target, err := api.DB.Target(entities[0].ID, &otherID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(target.Name, qt.Equals, "validated")
+	filters, err := types.ParseTargetFilters(target.Filters)
+	c.Assert(err, qt.IsNil)
+	c.Assert(filters.Verified, qt.Not(qt.IsNil))
+
+	count, err = api.DB.UpdateTarget(entities[0].ID, &otherID, &types.Target{Filters: json.RawMessage(`{"hasPublicKey": true}`)})
+	c.Assert(err, qt.IsNil)
+	c.Assert(count, qt.Equals, 1)
+	target, err = api.DB.Target(entities[0].ID, &otherID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(target.Name, qt.Equals, "validated")
+	filters, err = types.ParseTargetFilters(target.Filters)
+	c.Assert(err, qt.IsNil)
+	c.Assert(filters.Verified, qt.IsNil)
+	c.Assert(filters.HasPublicKey, qt.Not(qt.IsNil))
+
+	// names are unique per entity
+	_, err = api.DB.UpdateTarget(entities[0].ID, &otherID, &types.Target{Name: "all"})
+	c.Assert(err, qt.ErrorMatches, ".*duplicate name")
+
+	// targets of other entities cannot be modified
+	_, other := testcommon.CreateEntities(1)
+	count, err = api.DB.UpdateTarget(other[0].ID, &otherID, &types.Target{Name: "stolen"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(count, qt.Equals, 0)
+	err = api.DB.DeleteTarget(other[0].ID, &otherID)
+	c.Assert(err, qt.Equals, sql.ErrNoRows)
+
+	// targets referenced by a census cannot be deleted
+	censusID := util.RandomBytes(len(entities[0].ID))
+	err = api.DB.AddCensus(entities[0].ID, censusID, &targetID, &types.CensusInfo{Name: "census", MerkleRoot: util.RandomBytes(32)})
+	c.Assert(err, qt.IsNil)
+	err = api.DB.DeleteTarget(entities[0].ID, &targetID)
+	c.Assert(err, qt.ErrorMatches, "target is referenced by existing censuses")
+
+	err = api.DB.DeleteTarget(entities[0].ID, &otherID)
+	c.Assert(err, qt.IsNil)
+	_, err = api.DB.Target(entities[0].ID, &otherID)
+	c.Assert(err, qt.Not(qt.IsNil))
+
+	// cleaning up
+	err = api.DB.DeleteEntity(entities[0].ID)
+	c.Assert(err, qt.IsNil)
+}
+
+func TestTargetMembers(t *testing.T) {
+	c := qt.New(t)
+	// create entity
+	_, entities := testcommon.CreateEntities(1)
+	err := api.DB.AddEntity(entities[0].ID, &entities[0].EntityInfo)
+	c.Assert(err, qt.IsNil)
+
+	// 4 members with keys, 2 of them with custom fields, and 2 members without keys
+	_, members, err := testcommon.CreateMembers(entities[0].ID, 4)
+	c.Assert(err, qt.IsNil)
+	members[0].CustomFields = json.RawMessage(`{"shares": 20, "branch": {"city": "Barcelona"}}`)
+	members[1].CustomFields = json.RawMessage(`{"shares": 5, "branch": {"city": "Girona"}}`)
+	members[0].DateOfBirth = time.Time{}
+	members[1].DateOfBirth = time.Time{}
+	members[2].DateOfBirth = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+	members[3].DateOfBirth = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	err = api.DB.AddMemberBulk(entities[0].ID, members)
+	c.Assert(err, qt.IsNil)
+	tokens, err := api.DB.CreateNMembers(entities[0].ID, 2)
+	c.Assert(err, qt.IsNil)
+
+	// tag the members without keys
+	tagID, err := api.DB.AddTag(entities[0].ID, "pending")
+	c.Assert(err, qt.IsNil)
+	_, _, err = api.DB.AddTagToMembers(entities[0].ID, tokens, tagID)
+	c.Assert(err, qt.IsNil)
+
+	for i, tc := range []struct {
+		filters string
+		size    int
+		skipped int
+	}{
+		{`{}`, 6, 2},
+		{`{"hasPublicKey": true}`, 4, 0},
+		{`{"hasPublicKey": false}`, 2, 2},
+		{fmt.Sprintf(`{"tags": {"any": [%d]}}`, tagID), 2, 2},
+		{fmt.Sprintf(`{"tags": {"none": [%d]}}`, tagID), 4, 0},
+		{`{"origins": ["Form"]}`, 0, 0},
+		{`{"dateOfBirth": {"to": "1990-01-01T00:00:00Z"}}`, 1, 0},
+		{`{"dateOfBirth": {"from": "1970-01-01T00:00:00Z"}}`, 2, 0},
+		{`{"customFields": [{"path": "shares", "op": "gt", "value": 10}]}`, 1, 0},
+		{`{"customFields": [{"path": "branch.city", "op": "in", "value": ["Barcelona", "Girona"]}]}`, 2, 0},
+		{`{"customFields": [{"path": "branch", "op": "exists"}], "hasPublicKey": true}`, 2, 0},
+	} {
+		target := &types.Target{EntityID: entities[0].ID, Name: fmt.Sprintf("target%d", i), Filters: json.RawMessage(tc.filters)}
+		targetID, err := api.DB.AddTarget(entities[0].ID, target)
+		c.Assert(err, qt.IsNil)
+		targetMembers, err := api.DB.TargetMembers(entities[0].ID, &targetID)
+		c.Assert(err, qt.IsNil)
+		c.Assert(targetMembers, qt.HasLen, tc.size, qt.Commentf("filters: %s", tc.filters))
+		claims, skipped, err := api.DB.DumpTargetClaims(entities[0].ID, &targetID)
+		c.Assert(err, qt.IsNil)
+		c.Assert(claims, qt.HasLen, tc.size-tc.skipped, qt.Commentf("filters: %s", tc.filters))
+		c.Assert(skipped, qt.Equals, tc.skipped, qt.Commentf("filters: %s", tc.filters))
+	}
+
+	// cleaning up
+	err = api.DB.DeleteEntity(entities[0].ID)
+	c.Assert(err, qt.IsNil)
+}
+
+func TestStreamMembers(t *testing.T) {
+	c := qt.New(t)
+	// create entity
+	_, entities := testcommon.CreateEntities(1)
+	err := api.DB.AddEntity(entities[0].ID, &entities[0].EntityInfo)
+	c.Assert(err, qt.IsNil)
+
+	err = api.DB.ImportMembers(entities[0].ID, []types.MemberInfo{
+		{FirstName: "John", LastName: "Smith", Email: "john@vocdoni.io",
+			CustomFields: json.RawMessage(`{"branch": {"city": "Barcelona"}, "shares": 10}`)},
+		{FirstName: "Jane", LastName: "Doe", Email: "jane@vocdoni.io"},
+	})
+	c.Assert(err, qt.IsNil)
+	tagID, err := api.DB.AddTag(entities[0].ID, "board")
+	c.Assert(err, qt.IsNil)
+	members, _, err := api.DB.ListMembers(entities[0].ID, &types.ListOptions{SortBy: "lastName"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(members, qt.HasLen, 2)
+	_, _, err = api.DB.AddTagToMembers(entities[0].ID, []uuid.UUID{members[1].ID}, tagID)
+	c.Assert(err, qt.IsNil)
+
+	// members are streamed by last name with their custom fields and origin
+	var streamed []types.Member
+	err = api.DB.StreamMembers(entities[0].ID, &types.TargetFilters{}, func(member *types.Member) error {
+		streamed = append(streamed, *member)
+		return nil
+	})
+	c.Assert(err, qt.IsNil)
+	c.Assert(streamed, qt.HasLen, 2)
+	c.Assert(streamed[0].LastName, qt.Equals, "Doe")
+	c.Assert(streamed[1].LastName, qt.Equals, "Smith")
+	c.Assert(streamed[1].Origin, qt.Equals, types.Token)
+	c.Assert(streamed[1].Tags, qt.DeepEquals, []int32{tagID})
+	c.Assert(string(streamed[1].CustomFields), qt.Equals, `{"branch": {"city": "Barcelona"}, "shares": 10}`)
+
+	// filters restrict the streamed members
+	streamed = nil
+	err = api.DB.StreamMembers(entities[0].ID, &types.TargetFilters{Tags: &types.TagsFilter{All: []int32{tagID}}},
+		func(member *types.Member) error {
+			streamed = append(streamed, *member)
+			return nil
+		})
+	c.Assert(err, qt.IsNil)
+	c.Assert(streamed, qt.HasLen, 1)
+	c.Assert(streamed[0].Email, qt.Equals, "john@vocdoni.io")
+
+	// errors returned by the callback stop the stream
+	err = api.DB.StreamMembers(entities[0].ID, &types.TargetFilters{}, func(member *types.Member) error {
+		return fmt.Errorf("stop")
+	})
+	c.Assert(err, qt.ErrorMatches, "stop")
+
+	// cleaning up
+	err = api.DB.DeleteEntity(entities[0].ID)
+	c.Assert(err, qt.IsNil)
+}
+
 func TestCensus(t *testing.T) {
 	var root, idBytes []byte
 	var err error
@@ -681,11 +1717,21 @@ func TestCensus(t *testing.T) {
 	}
 
 	var censuses []types.Census
-	censuses, err = api.DB.ListCensus(entities[0].ID, &types.ListOptions{})
+	censuses, _, err = api.DB.ListCensus(entities[0].ID, &types.ListOptions{})
 	if err != nil || len(censuses) != 2 {
 		t.Fatal("unable to list censuses correctly (pgsql.go:Censuses)")
 	}
 
+	// Verify that censuses can be paginated using cursors
+	page, cursor, err := api.DB.ListCensus(entities[0].ID, &types.ListOptions{Count: 1, SortBy: "createdAt"})
+	if err != nil || len(page) != 1 || len(cursor) == 0 {
+		t.Fatalf("unable to list first page of censuses correctly (pgsql.go:ListCensus): %v", err)
+	}
+	page2, cursor, err := api.DB.ListCensus(entities[0].ID, &types.ListOptions{Count: 1, SortBy: "createdAt", Cursor: cursor})
+	if err != nil || len(page2) != 1 || len(cursor) != 0 || bytes.Equal(page[0].ID, page2[0].ID) {
+		t.Fatalf("unable to list second page of censuses correctly (pgsql.go:ListCensus): %v", err)
+	}
+
 	// Verify that an existing census can be deleted
 	if err = api.DB.DeleteCensus(entities[0].ID, censuses[0].ID); err != nil {
 		t.Fatalf("cannot delete census correctly (pgsql.go:DeleteCensus): %v", err)
@@ -796,6 +1842,32 @@ func TestCensus(t *testing.T) {
 	if ephemeralMember.ID != memberIDs[2] {
 		t.Fatalf("retrieved wrong ephemeral member info by email: (%v)", err)
 	}
+	censusMember, err := api.DB.CensusMember(entities[0].ID, idBytes, &memberIDs[2])
+	c.Assert(err, qt.IsNil, qt.Commentf("cannot retrieve census member"))
+	c.Assert(censusMember.MemberID, qt.Equals, memberIDs[2])
+	c.Assert(censusMember.Ephemeral, qt.IsTrue)
+	c.Assert(censusMember.DigestedPubKey, qt.Not(qt.HasLen), 0)
+	c.Assert(censusMember.PrivKey, qt.HasLen, 0)
+	_, err = api.DB.CensusMember(entities[0].ID, idBytes, &uuid.UUID{})
+	c.Assert(err, qt.Equals, sql.ErrNoRows)
+
+	// flag the member with an invalid claim
+	unknownClaim := util.RandomBytes(32)
+	invalidClaims, err := api.DB.FlagInvalidClaims(entities[0].ID, idBytes,
+		[][]byte{censusMember.DigestedPubKey, unknownClaim},
+		[]string{types.InvalidClaimDuplicated, types.InvalidClaimRejected})
+	c.Assert(err, qt.IsNil, qt.Commentf("cannot flag invalid claims"))
+	c.Assert(invalidClaims, qt.HasLen, 1)
+	c.Assert(*invalidClaims[0].MemberID, qt.Equals, memberIDs[2])
+	c.Assert(invalidClaims[0].Reason, qt.Equals, types.InvalidClaimDuplicated)
+	c.Assert(invalidClaims[0].Email, qt.Equals, email)
+	invalidClaims, err = api.DB.CensusInvalidClaims(entities[0].ID, idBytes)
+	c.Assert(err, qt.IsNil, qt.Commentf("cannot retrieve invalid claims"))
+	c.Assert(invalidClaims, qt.HasLen, 1)
+	c.Assert([]byte(invalidClaims[0].Claim), qt.DeepEquals, []byte(censusMember.DigestedPubKey))
+	censusMember, err = api.DB.CensusMember(entities[0].ID, idBytes, &memberIDs[2])
+	c.Assert(err, qt.IsNil)
+	c.Assert(censusMember.InvalidReason, qt.Equals, types.InvalidClaimDuplicated)
 
 	merkleRoot := util.RandomBytes(32)
 	merkleTreeUri := "ipfs://..."
@@ -834,6 +1906,59 @@ func TestCensus(t *testing.T) {
 		t.Fatalf("erroneously updated censusInfo: (%v)", err)
 	}
 
+	// Test census states
+	c.Assert(census.State, qt.Equals, types.CensusExpanded)
+	_, err = api.DB.ExpandCensusMembers(entities[0].ID, idBytes)
+	c.Assert(err, qt.Not(qt.IsNil), qt.Commentf("expanded census members twice"))
+	err = api.DB.SetCensusState(entities[0].ID, idBytes, types.CensusDraft, types.CensusExpanded)
+	c.Assert(err, qt.Not(qt.IsNil), qt.Commentf("set the state of a census that was not in the from state"))
+	err = api.DB.SetCensusState(entities[0].ID, idBytes, types.CensusExpanded, types.CensusPublished)
+	c.Assert(err, qt.IsNil, qt.Commentf("cannot publish census"))
+	// published censuses are not updated
+	count, err = api.DB.UpdateCensus(entities[0].ID, idBytes, &types.CensusInfo{MerkleTreeURI: "ipfs://new"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(count, qt.Equals, 0)
+	censuses, _, err = api.DB.ListCensus(entities[0].ID, &types.ListOptions{State: types.CensusPublished})
+	c.Assert(err, qt.IsNil, qt.Commentf("cannot list published censuses"))
+	c.Assert(censuses, qt.HasLen, 1)
+	c.Assert([]byte(censuses[0].ID), qt.DeepEquals, idBytes)
+	// closing a census drops its ephemeral keys
+	err = api.DB.SetCensusState(entities[0].ID, idBytes, types.CensusPublished, types.CensusClosed)
+	c.Assert(err, qt.IsNil, qt.Commentf("cannot close census"))
+	ephemeralMembers, err = api.DB.ListEphemeralMemberInfo(entities[0].ID, idBytes)
+	c.Assert(err, qt.IsNil)
+	for _, member := range ephemeralMembers {
+		c.Assert(member.PrivKey, qt.HasLen, 0)
+	}
+	err = api.DB.SetCensusState(entities[0].ID, idBytes, types.CensusClosed, types.CensusArchived)
+	c.Assert(err, qt.IsNil, qt.Commentf("cannot archive census"))
+	censuses, _, err = api.DB.ListCensus(entities[0].ID, &types.ListOptions{State: types.CensusPublished})
+	c.Assert(err, qt.IsNil)
+	c.Assert(censuses, qt.HasLen, 0)
+
+	// Test weighted censuses
+	// only the members with the weight field are in the census
+	_, err = api.DB.UpdateMember(entities[0].ID, &memberIDs[0], &types.MemberInfo{CustomFields: json.RawMessage(`{"shares": 5}`)})
+	c.Assert(err, qt.IsNil, qt.Commentf("cannot update member"))
+	id = util.RandomHex(len(entities[0].ID))
+	idBytes, err = hex.DecodeString(util.TrimHex(id))
+	c.Assert(err, qt.IsNil)
+	weight := &types.CensusWeight{Field: "shares"}
+	err = api.DB.AddCensus(entities[0].ID, idBytes, &targetID, &types.CensusInfo{Name: id, Weight: weight})
+	c.Assert(err, qt.IsNil, qt.Commentf("cannot add census"))
+	census, err = api.DB.Census(entities[0].ID, idBytes)
+	c.Assert(err, qt.IsNil, qt.Commentf("cannot retrieve census"))
+	c.Assert(census.Weight, qt.DeepEquals, weight)
+	censusMembers, err = api.DB.ExpandCensusMembers(entities[0].ID, idBytes)
+	c.Assert(err, qt.IsNil, qt.Commentf("cannot expand census members"))
+	c.Assert(censusMembers, qt.HasLen, 1)
+	c.Assert(censusMembers[0].MemberID, qt.Equals, memberIDs[0])
+	c.Assert(censusMembers[0].Weight, qt.Equals, uint64(5))
+	claims, weights, err := api.DB.DumpCensusWeightedClaims(entities[0].ID, idBytes)
+	c.Assert(err, qt.IsNil, qt.Commentf("cannot dump census weighted claims"))
+	c.Assert(claims, qt.DeepEquals, [][]byte{censusMembers[0].DigestedPubKey})
+	c.Assert(weights, qt.DeepEquals, []uint64{5})
+
 	err = api.DB.DeleteEntity(entities[0].ID)
 	if err != nil {
 		t.Errorf("error cleaning up %v", err)
@@ -904,7 +2029,7 @@ func TestTags(t *testing.T) {
 		t.Fatalf("unable to add member tags:  (%v)", err)
 	}
 	// verify tags were registered correctly
-	taggedMembers, err := api.DB.ListMembers(entities[0].ID, nil)
+	taggedMembers, _, err := api.DB.ListMembers(entities[0].ID, nil)
 	if err != nil {
 		t.Fatalf("error retrieving entity members:  (%v)", err)
 	}
@@ -921,9 +2046,49 @@ func TestTags(t *testing.T) {
 		t.Fatal("able to create tag with duplicate name")
 	}
 
+	// list tags with the number of members that have them
+	otherTagID, err := api.DB.AddTag(entities[0].ID, "OtherTag")
+	if err != nil {
+		t.Fatalf("error creating tag:  (%v)", err)
+	}
+	tags, err = api.DB.ListTags(entities[0].ID)
+	if err != nil {
+		t.Fatalf("error listing tags:  (%v)", err)
+	}
+	if len(tags) != 2 || tags[0].MembersCount == nil || tags[1].MembersCount == nil {
+		t.Fatalf("unexpected tags %+v", tags)
+	}
+	if *tags[0].MembersCount != len(memberIDs) || *tags[1].MembersCount != 0 {
+		t.Fatalf("unexpected tag members count %d and %d", *tags[0].MembersCount, *tags[1].MembersCount)
+	}
+
+	// update tag, empty fields are kept
+	updated, err := api.DB.UpdateTag(entities[0].ID, otherTagID, &types.Tag{Name: "Renamed", Description: "Board", Color: "#ff8800"})
+	if err != nil || updated != 1 {
+		t.Fatalf("error updating tag:  (%v)", err)
+	}
+	updated, err = api.DB.UpdateTag(entities[0].ID, otherTagID, &types.Tag{Color: "#0088ff"})
+	if err != nil || updated != 1 {
+		t.Fatalf("error updating tag:  (%v)", err)
+	}
+	otherTag, err := api.DB.Tag(entities[0].ID, otherTagID)
+	if err != nil {
+		t.Fatalf("error retrieving updated tag:  (%v)", err)
+	}
+	if otherTag.Name != "Renamed" || otherTag.Description != "Board" || otherTag.Color != "#0088ff" {
+		t.Fatalf("tag not updated correctly %+v", otherTag)
+	}
+	// duplicate names are not allowed
+	if _, err = api.DB.UpdateTag(entities[0].ID, otherTagID, &types.Tag{Name: "TestTag"}); err == nil {
+		t.Fatal("able to rename tag with a duplicate name")
+	}
+	if err = api.DB.DeleteTag(entities[0].ID, otherTagID); err != nil {
+		t.Fatalf("unable to delete tag:  (%v)", err)
+	}
+
 	// verify that the same tag cannot be added twice
 	// Add tag to members
-	updated, invalidIDs, err := api.DB.AddTagToMembers(entities[0].ID, memberIDs, tag.ID)
+	updated, invalidIDs, err = api.DB.AddTagToMembers(entities[0].ID, memberIDs, tag.ID)
 	if err != nil {
 		t.Fatalf("error adding tag to members:  (%v)", err)
 	}
@@ -960,7 +2125,7 @@ func TestTags(t *testing.T) {
 		t.Fatal("unexpected result removing tag from members")
 	}
 	// verify tags were removed correctly
-	taggedMembers, err = api.DB.ListMembers(entities[0].ID, nil)
+	taggedMembers, _, err = api.DB.ListMembers(entities[0].ID, nil)
 	if err != nil {
 		t.Fatalf("error retrieving entity members:  (%v)", err)
 	}
@@ -982,7 +2147,7 @@ func TestTags(t *testing.T) {
 	if err = api.DB.DeleteTag(entities[0].ID, tag.ID); err != nil {
 		t.Fatalf("unable to delete tag that exists for members:  (%v)", err)
 	}
-	taggedMembers, err = api.DB.ListMembers(entities[0].ID, nil)
+	taggedMembers, _, err = api.DB.ListMembers(entities[0].ID, nil)
 	if err != nil {
 		t.Fatalf("error retrieving entity members:  (%v)", err)
 	}
@@ -1001,6 +2166,32 @@ func TestTags(t *testing.T) {
 	if err != nil {
 		t.Fatalf("error creating tag:  (%v)", err)
 	}
+
+	// add and remove the tag to the members matching some filters
+	filters, err := types.ParseTargetFilters(json.RawMessage(`{"hasPublicKey": false}`))
+	if err != nil {
+		t.Fatalf("cannot parse filters: (%v)", err)
+	}
+	added, err = api.DB.AddTagToMembersByFilters(entities[0].ID, filters, tagID)
+	if err != nil || added != len(memberIDs)-1 {
+		t.Fatalf("unexpected result adding tag by filters %d: (%v)", added, err)
+	}
+	// members that already have the tag are not counted
+	added, err = api.DB.AddTagToMembersByFilters(entities[0].ID, filters, tagID)
+	if err != nil || added != 0 {
+		t.Fatalf("unexpected result adding tag by filters twice %d: (%v)", added, err)
+	}
+	filters, err = types.ParseTargetFilters(json.RawMessage(fmt.Sprintf(`{"tags": {"all": [%d]}}`, tagID)))
+	if err != nil {
+		t.Fatalf("cannot parse filters: (%v)", err)
+	}
+	deleted, err = api.DB.RemoveTagFromMembersByFilters(entities[0].ID, filters, tagID)
+	if err != nil || deleted != len(memberIDs)-1 {
+		t.Fatalf("unexpected result removing tag by filters %d: (%v)", deleted, err)
+	}
+	if _, err = api.DB.AddTagToMembersByFilters(entities[0].ID, filters, tagID+1000); err == nil {
+		t.Fatal("able to add a non existing tag by filters")
+	}
 	if err = api.DB.DeleteTag(entities[0].ID, tagID); err != nil {
 		t.Fatalf("unable to delete tag that exists for members:  (%v)", err)
 	}
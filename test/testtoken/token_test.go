@@ -397,6 +397,20 @@ func TestImportKeysBulk(t *testing.T) {
 		t.Errorf("succeeded to import duplicate keys: %+v", resp)
 	}
 
+	// 2. keys cannot be imported if the entity requires custom fields
+	info := entities[0].EntityInfo
+	info.CustomFieldsSchema = types.CustomFieldsSchema{{Name: "shares", Type: types.CustomFieldNumber, Required: true}}
+	_, err = api.DB.UpdateEntity(entities[0].ID, &info)
+	c.Assert(err, qt.IsNil)
+	if err := bulkSigner.Generate(); err != nil {
+		t.Fatalf("error generating ethereum keys: (%v)", err)
+	}
+	req.Keys = []string{fmt.Sprintf("%x", bulkSigner.PublicKey())}
+	req.Timestamp = int32(time.Now().Unix())
+	req.AuthHash = calculateAuth(req.Keys, req.EntityID, req.Method, fmt.Sprintf("%d", req.Timestamp), entities[0].CallbackSecret)
+	resp = wsc.Request(req, nil)
+	c.Assert(resp.Ok, qt.IsFalse, qt.Commentf("imported keys without the required custom fields"))
+
 	err = api.DB.DeleteEntity(entities[0].ID)
 	c.Check(err, qt.IsNil)
 }
@@ -224,6 +224,17 @@ func (t *TokenAPI) importKeysBulk(request *types.APIrequest) (*types.APIresponse
 		return nil, fmt.Errorf("invalid authentication")
 	}
 
+	// imported keys carry no custom fields, which must fulfill the entity schema
+	schema, err := t.db.EntityCustomFieldsSchema(request.EntityID)
+	if err != nil {
+		log.Errorf("importKeysBulk: error retrieving custom fields schema of %x: (%v)", request.EntityID, err)
+		return nil, fmt.Errorf("error retrieving entity")
+	}
+	if err = schema.ValidateFields(nil); err != nil {
+		log.Debugf("importKeysBulk: members of %x cannot be imported without custom fields: (%v)", request.EntityID, err)
+		return nil, fmt.Errorf("invalid custom fields: %v", err)
+	}
+
 	members := make([]types.Member, len(request.Keys))
 	for i, claim := range request.Keys {
 		if members[i].PubKey, err = hex.DecodeString(dvoteutil.TrimHex(claim)); err != nil {
@@ -272,7 +283,7 @@ func (t *TokenAPI) listKeys(request *types.APIrequest) (*types.APIresponse, erro
 	}
 
 	// Query for members
-	members, err := t.db.ListMembers(request.EntityID, request.ListOptions)
+	members, _, err := t.db.ListMembers(request.EntityID, request.ListOptions)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("no members found")
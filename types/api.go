@@ -19,32 +19,47 @@ type APIrequest struct {
 	AuthHash string      `json:"authHash,omitempty"`
 	Census   *CensusInfo `json:"census,omitempty"`
 	CensusID string      `json:"censusId,omitempty"`
+	// ColumnMapping maps CSV column names to MemberInfo json field names
+	ColumnMapping map[string]string `json:"columnMapping,omitempty"`
+	// ConfirmationToken confirms irreversible operations such as deleteEntity
+	ConfirmationToken string `json:"confirmationToken,omitempty"`
+	CSV               string `json:"csv,omitempty"`
 	//TODO Keys HexBytes when API supports protobuf or similar
 	Keys               []string     `json:"keys,omitempty"` // claim Keys
 	Email              string       `json:"email,omitempty"`
 	EntityID           HexBytes     `json:"entityId,omitempty"`
 	Entity             *EntityInfo  `json:"entity,omitempty"`
 	Filter             *Target      `json:"filter,omitempty"`
+	Format             string       `json:"format,omitempty"`
 	ListOptions        *ListOptions `json:"listOptions,omitempty"`
 	MemberID           *uuid.UUID   `json:"memberId,omitempty"`
 	MemberIDs          []uuid.UUID  `json:"memberIds,omitempty"`
 	Member             *Member      `json:"member,omitempty"`
 	MemberInfo         *MemberInfo  `json:"memberInfo,omitempty"`
+	MemberPatch        *MemberPatch `json:"memberPatch,omitempty"`
 	MembersInfo        []MemberInfo `json:"membersInfo,omitempty"`
 	Method             string       `json:"method"`
+	Operator           *Operator    `json:"operator,omitempty"`
 	InvalidClaims      [][]byte     `json:"invalidClaims"`
 	PubKey             HexBytes     `json:"publicKey,omitempty"`
 	ProcessID          HexBytes     `json:"processId,omitempty"`
+	Quotas             *Quotas      `json:"quotas,omitempty"`
 	Signature          string       `json:"signature,omitempty"`
 	Scope              string       `json:"scope,omitempty"`
+	State              CensusState  `json:"state,omitempty"`
 	Status             *Status      `json:"status,omitempty"`
+	Tag                *Tag         `json:"tag,omitempty"`
 	TagID              int32        `json:"tagId,omitempty"`
 	TagName            string       `json:"tagName,omitempty"`
+	Target             *Target      `json:"target,omitempty"`
 	TargetID           *uuid.UUID   `json:"targetId,omitempty"`
 	Timestamp          int32        `json:"timestamp"`
 	Token              string       `json:"token,omitempty"`
 	Topic              string       `json:"topic,omitempty"`
 	SignaturePublicKey []byte       `json:"signaturPublicKey,omitempty"`
+	// OperatorAddress is the address of the operator that signed the request
+	// on behalf of the entity, set by the manager and never read from the wire
+	OperatorAddress HexBytes `json:"-"`
 }
 
 func (mr *APIrequest) SetID(id string) {
@@ -73,26 +88,45 @@ type ResponseMessage struct {
 // Fields must be in alphabetical order
 // Those fields with valid zero-values (such as bool) must be pointers
 type APIresponse struct {
-	APIList    []string    `json:"apiList,omitempty"`
-	Census     *Census     `json:"census,omitempty"`
-	Censuses   []Census    `json:"censuses,omitempty"`
-	Claims     [][]byte    `json:"claims,omitempty"`
-	Count      int         `json:"count,omitempty"`
-	Entity     *Entity     `json:"entity,omitempty"`
-	Entities   []Entity    `json:"entities,omitempty"`
-	Health     int32       `json:"health,omitempty"`
-	InvalidIDs []uuid.UUID `json:"invalidIds,omitempty"`
+	APIList []string `json:"apiList,omitempty"`
+	// Archive is a zip archive with the data of an entity
+	Archive           []byte   `json:"archive,omitempty"`
+	Census            *Census  `json:"census,omitempty"`
+	Censuses          []Census `json:"censuses,omitempty"`
+	Claims            [][]byte `json:"claims,omitempty"`
+	ConfirmationToken string   `json:"confirmationToken,omitempty"`
+	Count             int      `json:"count,omitempty"`
+	// CustomFieldsSchema is returned along with the members of an entity
+	CustomFieldsSchema CustomFieldsSchema `json:"customFieldsSchema,omitempty"`
+	Entity             *Entity            `json:"entity,omitempty"`
+	Entities           []Entity           `json:"entities,omitempty"`
+	Events             []MemberEvent      `json:"events,omitempty"`
+	Export             string             `json:"export,omitempty"`
+	Health             int32              `json:"health,omitempty"`
+	// InvalidClaims are the invalid claims of a census and their members
+	InvalidClaims []InvalidClaim `json:"invalidClaims,omitempty"`
+	InvalidIDs    []uuid.UUID    `json:"invalidIds,omitempty"`
 	//TODO InvalidKeys HexBytes when API supports protobuf or similar
 	InvalidKeys   []string     `json:"invalidKeys,omitempty"`
 	Member        *Member      `json:"member,omitempty"`
+	MemberData    *MemberData  `json:"memberData,omitempty"`
 	Members       []Member     `json:"members,omitempty"`
 	MembersTokens []TokenEmail `json:"membersTokens,omitempty"`
 	Message       string       `json:"message,omitempty"`
+	NextCursor    string       `json:"nextCursor,omitempty"`
 	Ok            bool         `json:"ok"`
-	PublicKey     string       `json:"publicKey,omitempty"`
+	Operators     []Operator   `json:"operators,omitempty"`
+	// Proof is the Merkle inclusion proof of a key in a census tree
+	Proof     []byte  `json:"proof,omitempty"`
+	PublicKey string  `json:"publicKey,omitempty"`
+	Quotas    *Quotas `json:"quotas,omitempty"`
 	//TODO Keys HexBytes when API supports protobuf or similar
-	Keys        []string    `json:"keys,omitempty"`
-	Request     string      `json:"request"`
+	Keys         []string      `json:"keys,omitempty"`
+	RejectedRows []RejectedRow `json:"rejectedRows,omitempty"`
+	Request      string        `json:"request"`
+	// Root is the root of the census tree computed by the manager
+	Root        []byte      `json:"root,omitempty"`
+	Skipped     int         `json:"skipped,omitempty"`
 	Status      *Status     `json:"status,omitempty"`
 	Tag         *Tag        `json:"tag,omitempty"`
 	Tags        []Tag       `json:"tags,omitempty"`
@@ -102,6 +136,11 @@ type APIresponse struct {
 	Token       string      `json:"token,omitempty"`
 	Tokens      []uuid.UUID `json:"tokens,omitempty"`
 	TokenStatus string      `json:"tokenStatus,omitempty"`
+	Usage       *Usage      `json:"usage,omitempty"`
+	// Value is the leaf value of a key in a census tree
+	Value []byte `json:"value,omitempty"`
+	// Weights are the weights of the census claims, in the same order
+	Weights []uint64 `json:"weights,omitempty"`
 }
 
 // SetError sets the APIresponse's Ok field to false, and Message to a string
@@ -138,11 +177,26 @@ type Status struct {
 	NeedsUpdate bool `json:"needsUpdate"`
 }
 
+// ListOptions defines the sorting and the pagination of list methods.
+// Pages can be requested either by Skip (offset) or by Cursor, the opaque
+// nextCursor returned along with the previous page. Cursors must be used
+// with the same SortBy and Order they were obtained with.
+// Search restricts the listed members to those matching the given words.
 type ListOptions struct {
 	Count  int    `json:"count,omitempty"`
+	Cursor string `json:"cursor,omitempty"`
 	Order  string `json:"order,omitempty"`
+	Search string `json:"search,omitempty"`
 	Skip   int    `json:"skip,omitempty"`
 	SortBy string `json:"sortBy,omitempty"`
+	// State filters the censuses by their state
+	State CensusState `json:"state,omitempty"`
+}
+
+// RejectedRow describes a row of an imported file that could not be imported
+type RejectedRow struct {
+	Line   int    `json:"line"`
+	Reason string `json:"reason"`
 }
 
 func NewApiRequest() jsonrpcapi.MessageAPI {
@@ -0,0 +1,164 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/google/uuid"
+)
+
+// CensusState is the state of a census in its lifecycle
+type CensusState string
+
+// Census states, in the order a census goes through them
+const (
+	// CensusDraft censuses have no members yet
+	CensusDraft CensusState = "draft"
+	// CensusExpanded censuses have the members of their target, expanded by
+	// dumpCensus
+	CensusExpanded CensusState = "expanded"
+	// CensusPublished censuses are used by a voting process and cannot change
+	CensusPublished CensusState = "published"
+	// CensusClosed censuses belong to an ended process and have no ephemeral
+	// private keys anymore
+	CensusClosed CensusState = "closed"
+	// CensusArchived censuses are kept for the record
+	CensusArchived CensusState = "archived"
+)
+
+// censusTransitions maps each census state to the only state it can go to
+var censusTransitions = map[CensusState]CensusState{
+	CensusDraft:     CensusExpanded,
+	CensusExpanded:  CensusPublished,
+	CensusPublished: CensusClosed,
+	CensusClosed:    CensusArchived,
+}
+
+// Valid reports whether s is a known census state
+func (s CensusState) Valid() bool {
+	_, ok := censusTransitions[s]
+	return ok || s == CensusArchived
+}
+
+// CanTransition reports whether a census in state s can go to state to
+func (s CensusState) CanTransition(to CensusState) bool {
+	next, ok := censusTransitions[s]
+	return ok && next == to
+}
+
+// Mutable reports whether the info of a census in state s can be updated
+func (s CensusState) Mutable() bool {
+	return s == CensusDraft || s == CensusExpanded
+}
+
+// CensusWeight defines the source of the weight of each member in a census.
+// Only one source can be set, and members weigh 1 if none is set. Members
+// whose weight is 0 are left out of the census.
+type CensusWeight struct {
+	// Constant is the weight of every member
+	Constant uint64 `json:"constant,omitempty"`
+	// Field is the name of a numeric custom field holding the weight of
+	// each member. Members without the field weigh 0.
+	Field string `json:"field,omitempty"`
+	// Tags maps tag IDs to weights. A member weighs the highest weight of
+	// its tags, or 0 if it has none of them.
+	Tags map[int32]uint64 `json:"tags,omitempty"`
+}
+
+// Validate checks that a single weight source is set and, if the entity has
+// a custom fields schema, that the weight field is a number field
+func (w *CensusWeight) Validate(schema CustomFieldsSchema) error {
+	sources := 0
+	if w.Constant > 0 {
+		if w.Constant > math.MaxInt64 {
+			return fmt.Errorf("weight constant is too big")
+		}
+		sources++
+	}
+	if len(w.Field) > 0 {
+		sources++
+		if len(schema) > 0 {
+			field, ok := schema.Field(w.Field)
+			if !ok {
+				return fmt.Errorf("weight field %q is not defined", w.Field)
+			}
+			if field.Type != CustomFieldNumber {
+				return fmt.Errorf("weight field %q is not a number", w.Field)
+			}
+		}
+	}
+	if w.Tags != nil {
+		if len(w.Tags) == 0 {
+			return fmt.Errorf("empty weight tags table")
+		}
+		for tag, weight := range w.Tags {
+			if weight > math.MaxInt64 {
+				return fmt.Errorf("weight of tag %d is too big", tag)
+			}
+		}
+		sources++
+	}
+	if sources != 1 {
+		return fmt.Errorf("exactly one weight source must be set")
+	}
+	return nil
+}
+
+// MemberWeight returns the weight of the member in a census using w, which
+// can be nil
+func (w *CensusWeight) MemberWeight(member *Member) (uint64, error) {
+	switch {
+	case w == nil:
+		return 1, nil
+	case w.Constant > 0:
+		return w.Constant, nil
+	case len(w.Field) > 0:
+		fields := make(map[string]json.RawMessage)
+		if len(member.CustomFields) > 0 {
+			if err := json.Unmarshal(member.CustomFields, &fields); err != nil {
+				return 0, fmt.Errorf("invalid custom fields: %w", err)
+			}
+		}
+		raw, ok := fields[w.Field]
+		if !ok || string(raw) == "null" {
+			return 0, nil
+		}
+		var value float64
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return 0, fmt.Errorf("custom field %q: weight must be a number", w.Field)
+		}
+		if value < 0 || value != math.Trunc(value) || value >= math.MaxInt64 {
+			return 0, fmt.Errorf("custom field %q: weight must be a non-negative integer", w.Field)
+		}
+		return uint64(value), nil
+	case len(w.Tags) > 0:
+		var weight uint64
+		for _, tag := range member.Tags {
+			if w.Tags[tag] > weight {
+				weight = w.Tags[tag]
+			}
+		}
+		return weight, nil
+	}
+	return 1, nil
+}
+
+// Reasons for a census claim to be invalid
+const (
+	InvalidClaimPublicKey   = "invalid public key"
+	InvalidClaimDuplicated  = "duplicated public key"
+	InvalidClaimRejected    = "rejected by the census service"
+	InvalidClaimNotInCensus = "not in census"
+)
+
+// InvalidClaim is a claim reported as invalid when updating a census, along
+// with the census member it belongs to, if any
+type InvalidClaim struct {
+	MemberID  *uuid.UUID `json:"memberId,omitempty" db:"member_id"`
+	Claim     HexBytes   `json:"claim" db:"digested_public_key"`
+	Reason    string     `json:"reason" db:"invalid_reason"`
+	FirstName string     `json:"firstName,omitempty" db:"first_name"`
+	LastName  string     `json:"lastName,omitempty" db:"last_name"`
+	Email     string     `json:"email,omitempty" db:"email"`
+}
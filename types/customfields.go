@@ -0,0 +1,254 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// CustomFieldType is the type of the values of a member custom field
+type CustomFieldType string
+
+// Custom field types
+const (
+	CustomFieldString CustomFieldType = "string"
+	CustomFieldNumber CustomFieldType = "number"
+	// CustomFieldDate values are strings formatted as 2006-01-02 or RFC3339
+	CustomFieldDate CustomFieldType = "date"
+	// CustomFieldEnum values are strings restricted to the allowed values
+	CustomFieldEnum CustomFieldType = "enum"
+	CustomFieldBool CustomFieldType = "bool"
+)
+
+// CustomFieldDateLayouts are the accepted formats of date custom fields
+var CustomFieldDateLayouts = []string{"2006-01-02", time.RFC3339}
+
+// CustomField defines a custom field of the members of an entity
+type CustomField struct {
+	Name     string          `json:"name"`
+	Type     CustomFieldType `json:"type"`
+	Required bool            `json:"required,omitempty"`
+	// Values are the allowed values of enum fields
+	Values []string `json:"values,omitempty"`
+}
+
+// CustomFieldsSchema defines the custom fields that the members of an entity
+// can have. An empty schema accepts any custom fields.
+type CustomFieldsSchema []CustomField
+
+// Field returns the definition of the custom field with the given name
+func (s CustomFieldsSchema) Field(name string) (*CustomField, bool) {
+	for i := range s {
+		if s[i].Name == name {
+			return &s[i], true
+		}
+	}
+	return nil, false
+}
+
+// Validate checks that the schema is well formed
+func (s CustomFieldsSchema) Validate() error {
+	names := make(map[string]bool, len(s))
+	for _, field := range s {
+		if len(field.Name) == 0 {
+			return fmt.Errorf("empty custom field name")
+		}
+		if names[field.Name] {
+			return fmt.Errorf("duplicate custom field %q", field.Name)
+		}
+		names[field.Name] = true
+		switch field.Type {
+		case CustomFieldString, CustomFieldNumber, CustomFieldDate, CustomFieldBool:
+			if len(field.Values) > 0 {
+				return fmt.Errorf("custom field %q: values are only allowed for enum fields", field.Name)
+			}
+		case CustomFieldEnum:
+			if len(field.Values) == 0 {
+				return fmt.Errorf("custom field %q: enum fields require values", field.Name)
+			}
+			values := make(map[string]bool, len(field.Values))
+			for _, value := range field.Values {
+				if values[value] {
+					return fmt.Errorf("custom field %q: duplicate value %q", field.Name, value)
+				}
+				values[value] = true
+			}
+		default:
+			return fmt.Errorf("custom field %q: unknown type %q", field.Name, field.Type)
+		}
+	}
+	return nil
+}
+
+// ValidateFields checks that the custom fields of a member, a JSON object,
+// fulfill the schema: every field must be defined in the schema, have a
+// value of its type and required fields must be present.
+func (s CustomFieldsSchema) ValidateFields(customFields json.RawMessage) error {
+	if len(s) == 0 {
+		return nil
+	}
+	fields := make(map[string]json.RawMessage)
+	if raw := bytes.TrimSpace(customFields); len(raw) > 0 && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return fmt.Errorf("custom fields must be an object")
+		}
+	}
+	for name, value := range fields {
+		field, ok := s.Field(name)
+		if !ok {
+			return fmt.Errorf("unknown custom field %q", name)
+		}
+		if string(value) == "null" {
+			continue
+		}
+		if err := field.validateValue(value); err != nil {
+			return fmt.Errorf("custom field %q: %w", name, err)
+		}
+	}
+	for _, field := range s {
+		if value, ok := fields[field.Name]; field.Required && (!ok || string(value) == "null") {
+			return fmt.Errorf("missing required custom field %q", field.Name)
+		}
+	}
+	return nil
+}
+
+// ValidatePatch checks that a patch of the custom fields of members, a JSON
+// object, only sets fields of the schema with values of their type. Null
+// values remove fields, which is not allowed for the required ones.
+func (s CustomFieldsSchema) ValidatePatch(customFields json.RawMessage) error {
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(customFields, &fields); err != nil || fields == nil {
+		return fmt.Errorf("custom fields must be an object")
+	}
+	if len(s) == 0 {
+		return nil
+	}
+	for name, value := range fields {
+		field, ok := s.Field(name)
+		if !ok {
+			return fmt.Errorf("unknown custom field %q", name)
+		}
+		if string(value) == "null" {
+			if field.Required {
+				return fmt.Errorf("cannot remove required custom field %q", name)
+			}
+			continue
+		}
+		if err := field.validateValue(value); err != nil {
+			return fmt.Errorf("custom field %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (f *CustomField) validateValue(raw json.RawMessage) error {
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return fmt.Errorf("invalid value: %w", err)
+	}
+	switch f.Type {
+	case CustomFieldString:
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("value must be a string")
+		}
+	case CustomFieldNumber:
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("value must be a number")
+		}
+	case CustomFieldBool:
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("value must be a boolean")
+		}
+	case CustomFieldDate:
+		date, ok := value.(string)
+		if !ok || !validDate(date) {
+			return fmt.Errorf("value must be a date")
+		}
+	case CustomFieldEnum:
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("value must be a string")
+		}
+		for _, allowed := range f.Values {
+			if s == allowed {
+				return nil
+			}
+		}
+		return fmt.Errorf("value %q not allowed", s)
+	}
+	return nil
+}
+
+// CheckFilters checks that the custom field filters of a target refer to
+// fields of the schema and are consistent with their types
+func (s CustomFieldsSchema) CheckFilters(filters []CustomFieldFilter) error {
+	if len(s) == 0 {
+		return nil
+	}
+	for _, filter := range filters {
+		path := filter.PathElements()
+		field, ok := s.Field(path[0])
+		if !ok {
+			return fmt.Errorf("unknown custom field %q", path[0])
+		}
+		if len(path) > 1 {
+			return fmt.Errorf("custom field %q has no nested fields", field.Name)
+		}
+		switch filter.Op {
+		case FilterOpGt, FilterOpGte, FilterOpLt, FilterOpLte:
+			if field.Type != CustomFieldNumber {
+				return fmt.Errorf("custom field %q is not a number", field.Name)
+			}
+		case FilterOpEq, FilterOpNeq:
+			if err := field.validateValue(filter.Value); err != nil {
+				return fmt.Errorf("custom field %q filter: %w", field.Name, err)
+			}
+		case FilterOpIn:
+			var values []json.RawMessage
+			if err := json.Unmarshal(filter.Value, &values); err != nil {
+				return fmt.Errorf("custom field %q filter: %w", field.Name, err)
+			}
+			for _, value := range values {
+				if err := field.validateValue(value); err != nil {
+					return fmt.Errorf("custom field %q filter: %w", field.Name, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ParseValue converts the text representation of a value, as found in a CSV
+// file, to the JSON value of the custom field
+func (f *CustomField) ParseValue(text string) (interface{}, error) {
+	switch f.Type {
+	case CustomFieldNumber:
+		number, err := strconv.ParseFloat(text, 64)
+		// NaN and infinities cannot be encoded in JSON
+		if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+			return nil, fmt.Errorf("custom field %q: value must be a number", f.Name)
+		}
+		return number, nil
+	case CustomFieldBool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return nil, fmt.Errorf("custom field %q: value must be a boolean", f.Name)
+		}
+		return b, nil
+	}
+	// strings, dates and enums are validated along with the rest of fields
+	return text, nil
+}
+
+func validDate(date string) bool {
+	for _, layout := range CustomFieldDateLayouts {
+		if _, err := time.Parse(layout, date); err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Custom field filter operators
+const (
+	FilterOpExists = "exists"
+	FilterOpEq     = "eq"
+	FilterOpNeq    = "neq"
+	FilterOpIn     = "in"
+	FilterOpGt     = "gt"
+	FilterOpGte    = "gte"
+	FilterOpLt     = "lt"
+	FilterOpLte    = "lte"
+)
+
+// TargetFilters holds the conditions that a member must fulfill in order to
+// be part of a Target. All the defined conditions must be satisfied and an
+// empty set of filters ({}) matches all the members of the entity.
+type TargetFilters struct {
+	Tags         *TagsFilter         `json:"tags,omitempty"`
+	Origins      []string            `json:"origins,omitempty"`
+	Verified     *bool               `json:"verified,omitempty"`
+	DateOfBirth  *DateRangeFilter    `json:"dateOfBirth,omitempty"`
+	HasPublicKey *bool               `json:"hasPublicKey,omitempty"`
+	CustomFields []CustomFieldFilter `json:"customFields,omitempty"`
+}
+
+// TagsFilter matches members by the tags they carry.
+// Any: at least one of the tags, All: every tag, None: none of the tags.
+type TagsFilter struct {
+	Any  []int32 `json:"any,omitempty"`
+	All  []int32 `json:"all,omitempty"`
+	None []int32 `json:"none,omitempty"`
+}
+
+// DateRangeFilter matches dates in [From, To). Members without a date are
+// never matched by a range.
+type DateRangeFilter struct {
+	From *time.Time `json:"from,omitempty"`
+	To   *time.Time `json:"to,omitempty"`
+}
+
+// CustomFieldFilter matches the value found at Path, a dot separated
+// path inside the member custom fields, against Value using Op.
+type CustomFieldFilter struct {
+	Path  string          `json:"path"`
+	Op    string          `json:"op"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// PathElements returns the custom field path split in its elements
+func (c *CustomFieldFilter) PathElements() []string {
+	return strings.Split(c.Path, ".")
+}
+
+// ParseTargetFilters decodes and validates the filters of a target.
+// Unknown fields are rejected in order to avoid silently ignoring typos.
+func ParseTargetFilters(raw json.RawMessage) (*TargetFilters, error) {
+	filters := &TargetFilters{}
+	if len(bytes.TrimSpace(raw)) == 0 || string(bytes.TrimSpace(raw)) == "null" {
+		return filters, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(filters); err != nil {
+		return nil, fmt.Errorf("cannot decode filters: %w", err)
+	}
+	if err := filters.Validate(); err != nil {
+		return nil, err
+	}
+	return filters, nil
+}
+
+// Validate checks that the filters are well formed
+func (f *TargetFilters) Validate() error {
+	if f.Tags != nil {
+		for _, tags := range [][]int32{f.Tags.Any, f.Tags.All, f.Tags.None} {
+			for _, tag := range tags {
+				if tag <= 0 {
+					return fmt.Errorf("invalid tag id %d", tag)
+				}
+			}
+		}
+	}
+	for _, origin := range f.Origins {
+		if !ValidOrigin(origin) {
+			return fmt.Errorf("invalid origin %q", origin)
+		}
+	}
+	if f.DateOfBirth != nil {
+		if f.DateOfBirth.From == nil && f.DateOfBirth.To == nil {
+			return fmt.Errorf("empty date of birth range")
+		}
+		if f.DateOfBirth.From != nil && f.DateOfBirth.To != nil && !f.DateOfBirth.From.Before(*f.DateOfBirth.To) {
+			return fmt.Errorf("invalid date of birth range")
+		}
+	}
+	for _, cf := range f.CustomFields {
+		if err := cf.validate(); err != nil {
+			return fmt.Errorf("invalid custom field filter %q: %w", cf.Path, err)
+		}
+	}
+	return nil
+}
+
+func (c *CustomFieldFilter) validate() error {
+	for _, elem := range c.PathElements() {
+		if len(elem) == 0 {
+			return fmt.Errorf("invalid path")
+		}
+	}
+	if c.Op == FilterOpExists {
+		return nil
+	}
+	if len(c.Value) == 0 {
+		return fmt.Errorf("missing value")
+	}
+	var value interface{}
+	if err := json.Unmarshal(c.Value, &value); err != nil {
+		return fmt.Errorf("invalid value: %w", err)
+	}
+	switch c.Op {
+	case FilterOpEq, FilterOpNeq:
+	case FilterOpIn:
+		if _, ok := value.([]interface{}); !ok {
+			return fmt.Errorf("value must be an array")
+		}
+	case FilterOpGt, FilterOpGte, FilterOpLt, FilterOpLte:
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("value must be a number")
+		}
+	default:
+		return fmt.Errorf("unknown operator %q", c.Op)
+	}
+	return nil
+}
+
+// ValidOrigin returns true if origin is the name of a known Origin
+func ValidOrigin(origin string) bool {
+	for o := Token; o <= API; o++ {
+		if o.String() == origin {
+			return true
+		}
+	}
+	return false
+}
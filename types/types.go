@@ -2,6 +2,7 @@ package types
 
 import (
 	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,6 +19,9 @@ type Entity struct {
 	ID           []byte `json:"id" db:"id"`
 	IsAuthorized bool   `json:"isAuthorized" db:"is_authorized"`
 	EntityInfo
+	// MembersCount and CensusesCount are only returned by the admin API
+	MembersCount  *int `json:"membersCount,omitempty" db:"members_count"`
+	CensusesCount *int `json:"censusesCount,omitempty" db:"censuses_count"`
 }
 
 type EntityInfo struct {
@@ -30,6 +34,107 @@ type EntityInfo struct {
 	CensusManagersAddresses [][]byte `json:"censusManagersAddresses,omitempty" db:"census_managers_addresses"`
 	Origins                 []Origin `json:"origins" db:"origins"`
 	Consented               bool     `json:"consented" db:"consented"`
+	// CustomFieldsSchema defines the custom fields of the members
+	CustomFieldsSchema CustomFieldsSchema `json:"customFieldsSchema,omitempty" db:"custom_fields_schema"`
+	// Retention defines how long the data that is no longer needed is kept
+	Retention *RetentionSettings `json:"retention,omitempty" db:"retention"`
+	// Quotas overrides the default quotas of the entity, only set by the
+	// platform admins
+	Quotas *Quotas `json:"quotas,omitempty" db:"quotas"`
+}
+
+// RetentionSettings defines how long an entity keeps the data that is no
+// longer needed, it is enforced periodically. Zero periods keep it forever.
+type RetentionSettings struct {
+	// UnverifiedMembersDays is the number of days after which the members
+	// that did not register a public key are deleted, unless they are part
+	// of a census whose process has not ended
+	UnverifiedMembersDays int `json:"unverifiedMembersDays"`
+	// EphemeralKeysDays is the number of days after the end of the process
+	// of an ephemeral census after which the private keys of its members are
+	// dropped
+	EphemeralKeysDays int `json:"ephemeralKeysDays"`
+}
+
+// Validate checks that the retention periods are not negative
+func (r *RetentionSettings) Validate() error {
+	if r.UnverifiedMembersDays < 0 || r.EphemeralKeysDays < 0 {
+		return fmt.Errorf("retention periods cannot be negative")
+	}
+	return nil
+}
+
+// Quotas limits the resources an entity can use. Zero quotas fall back to the
+// default ones.
+type Quotas struct {
+	// Members is the maximum number of members
+	Members int `json:"members"`
+	// Censuses is the maximum number of censuses
+	Censuses int `json:"censuses"`
+	// EmailsPerDay is the maximum number of emails sent per day (UTC)
+	EmailsPerDay int `json:"emailsPerDay"`
+	// FaucetRequestsPerMonth is the maximum number of faucet requests per
+	// month (UTC)
+	FaucetRequestsPerMonth int `json:"faucetRequestsPerMonth"`
+}
+
+// Validate checks that the quotas are not negative
+func (q *Quotas) Validate() error {
+	if q.Members < 0 || q.Censuses < 0 || q.EmailsPerDay < 0 || q.FaucetRequestsPerMonth < 0 {
+		return fmt.Errorf("quotas cannot be negative")
+	}
+	return nil
+}
+
+// Usage is the current consumption of the resources limited by the quotas
+type Usage struct {
+	Members                 int `json:"members" db:"members"`
+	Censuses                int `json:"censuses" db:"censuses"`
+	EmailsToday             int `json:"emailsToday" db:"emails_today"`
+	FaucetRequestsThisMonth int `json:"faucetRequestsThisMonth" db:"faucet_requests_this_month"`
+}
+
+// OperatorRole is the role of an operator acting on behalf of an entity
+type OperatorRole string
+
+const (
+	// OperatorAdmin can also manage the entity settings and its operators
+	OperatorAdmin OperatorRole = "admin"
+	// OperatorEditor can manage the members, targets, censuses and tags
+	OperatorEditor OperatorRole = "editor"
+	// OperatorViewer can only read the entity data
+	OperatorViewer OperatorRole = "viewer"
+)
+
+// level ranks the roles by their permissions, zero being an unknown role
+func (r OperatorRole) level() int {
+	switch r {
+	case OperatorAdmin:
+		return 3
+	case OperatorEditor:
+		return 2
+	case OperatorViewer:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Valid checks that the role is a known one
+func (r OperatorRole) Valid() bool {
+	return r.level() > 0
+}
+
+// Allows checks that the role has at least the permissions of the required one
+func (r OperatorRole) Allows(required OperatorRole) bool {
+	return r.Valid() && r.level() >= required.level()
+}
+
+// Operator is an address that can act on behalf of an entity with a role.
+// The addresses of the operators are the census managers addresses of the entity.
+type Operator struct {
+	Address HexBytes     `json:"address" db:"address"`
+	Role    OperatorRole `json:"role" db:"role"`
 }
 
 //go:generate stringer -type=Origin
@@ -62,6 +167,8 @@ type Member struct {
 	ID       uuid.UUID `json:"id" db:"id"`
 	EntityID []byte    `json:"entityId" db:"entity_id"`
 	PubKey   []byte    `json:"publicKey,omitempty" db:"public_key"`
+	// DeletedAt is set for deleted members until they are purged
+	DeletedAt *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
 	MemberInfo
 }
 
@@ -79,6 +186,26 @@ type MemberInfo struct {
 	Tags          []int32         `json:"tags,omitempty" db:"tags"`
 }
 
+// MemberPatch holds the fields to update on several members at once, only
+// the fields that are set are modified. The email is not included since it
+// is unique for every member. CustomFields are merged into the current ones
+// and the fields set to null are removed.
+type MemberPatch struct {
+	DateOfBirth   *time.Time      `json:"dateOfBirth,omitempty"`
+	FirstName     *string         `json:"firstName,omitempty"`
+	LastName      *string         `json:"lastName,omitempty"`
+	Phone         *string         `json:"phone,omitempty"`
+	StreetAddress *string         `json:"streetAddress,omitempty"`
+	Consented     *bool           `json:"consented,omitempty"`
+	CustomFields  json.RawMessage `json:"customFields,omitempty"`
+}
+
+// IsEmpty returns true if the patch does not modify any field
+func (p *MemberPatch) IsEmpty() bool {
+	return p.DateOfBirth == nil && p.FirstName == nil && p.LastName == nil && p.Phone == nil &&
+		p.StreetAddress == nil && p.Consented == nil && len(p.CustomFields) == 0
+}
+
 // In case COPY FROM is adopted
 // func (m *MemberInfo) GetDBFields() []string {
 // 	return []string{
@@ -133,6 +260,78 @@ type MemberInfo struct {
 // 	}
 // }
 
+// Member event operations
+const (
+	MemberEventCreate    = "create"
+	MemberEventUpdate    = "update"
+	MemberEventDelete    = "delete"
+	MemberEventAddTag    = "addTag"
+	MemberEventRemoveTag = "removeTag"
+	MemberEventRegister  = "register"
+	MemberEventRestore   = "restore"
+	MemberEventPurge     = "purge"
+	MemberEventExport    = "export"
+	MemberEventErase     = "erase"
+)
+
+// Member event actor types: entities act through the manager and token APIs,
+// operators through the manager API on behalf of an entity and users through
+// the registry with their public key
+const (
+	MemberEventActorEntity   = "entity"
+	MemberEventActorOperator = "operator"
+	MemberEventActorUser     = "user"
+)
+
+// MemberEvent records a change of a member. Changes holds, for every modified
+// field, its value before and after the change.
+type MemberEvent struct {
+	ID        int64           `json:"id" db:"id"`
+	CreatedAt time.Time       `json:"createdAt" db:"created_at"`
+	MemberID  uuid.UUID       `json:"memberId" db:"member_id"`
+	Operation string          `json:"operation" db:"operation"`
+	ActorType string          `json:"actorType" db:"actor_type"`
+	Actor     HexBytes        `json:"actor,omitempty" db:"actor"`
+	Changes   json.RawMessage `json:"changes" db:"changes"`
+}
+
+// Types of the emails sent to the members
+const (
+	MemberEmailValidation = "validation"
+	MemberEmailVotingLink = "votingLink"
+)
+
+// MemberEmail records an email sent to a member, along with the census and
+// the process it was sent for
+type MemberEmail struct {
+	ID        int64     `json:"id" db:"id"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	MemberID  uuid.UUID `json:"memberId" db:"member_id"`
+	Type      string    `json:"type" db:"type"`
+	CensusID  HexBytes  `json:"censusId,omitempty" db:"census_id"`
+	ProcessID HexBytes  `json:"processId,omitempty" db:"process_id"`
+}
+
+// MemberCensus is a census a member was included in
+type MemberCensus struct {
+	ID        HexBytes  `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	Ephemeral bool      `json:"ephemeral" db:"ephemeral"`
+}
+
+// MemberData holds everything stored about a member, as exported to answer
+// a data subject access request
+type MemberData struct {
+	ExportedAt time.Time      `json:"exportedAt"`
+	Member     *Member        `json:"member"`
+	Tags       []string       `json:"tags"`
+	Censuses   []MemberCensus `json:"censuses"`
+	Emails     []MemberEmail  `json:"emails"`
+	User       *User          `json:"user,omitempty"`
+	Events     []MemberEvent  `json:"events"`
+}
+
 type User struct {
 	CreatedUpdated
 	PubKey         []byte `json:"publicKey" db:"public_key"`
@@ -155,6 +354,12 @@ type CensusInfo struct {
 	Size          int    `json:"size" db:"size"`
 	Ephemeral     bool   `json:"ephemeral" db:"ephemeral"`
 	ProcessID     []byte `json:"processId,omitempty" db:"process_id"`
+	// ProcessEndDate is when the process using the census ends
+	ProcessEndDate *time.Time `json:"processEndDate,omitempty" db:"process_end_date"`
+	// Weight is the source of the weights of the census members
+	Weight *CensusWeight `json:"weight,omitempty" db:"-"`
+	// State is the state of the census in its lifecycle, set by the manager
+	State CensusState `json:"state,omitempty" db:"state"`
 }
 
 type CensusMember struct {
@@ -164,6 +369,9 @@ type CensusMember struct {
 	PrivKey        []byte    `json:"privateKey,omitempty" db:"private_key"`
 	PubKey         []byte    `json:"publicKey,omitempty" db:"public_key"`
 	DigestedPubKey []byte    `json:"digestedPublicKey,omitempty" db:"digested_public_key"`
+	Weight         uint64    `json:"weight" db:"weight"`
+	// InvalidReason is why the claim of the member was reported as invalid
+	InvalidReason string `json:"invalidReason,omitempty" db:"invalid_reason"`
 }
 
 type EphemeralMemberInfo struct {
@@ -232,10 +440,14 @@ type NewsFeedItemAuthor struct {
 	URL  string `json:"url,omitempty"`
 }
 
-// A tag of a given entity for categorizing users
+// Tag labels a group of members. MembersCount, the number of members with
+// the tag, is only provided when listing the tags of an entity.
 type Tag struct {
 	CreatedUpdated
-	ID       int32  `json:"id,omitempty" db:"id"`
-	EntityID []byte `json:"entityId,omitempty" db:"entity_id"`
-	Name     string `json:"name,omitempty" db:"name"`
+	ID           int32  `json:"id,omitempty" db:"id"`
+	EntityID     []byte `json:"entityId,omitempty" db:"entity_id"`
+	Name         string `json:"name,omitempty" db:"name"`
+	Description  string `json:"description,omitempty" db:"description"`
+	Color        string `json:"color,omitempty" db:"color"`
+	MembersCount *int   `json:"membersCount,omitempty" db:"members_count"`
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/badoux/checkmail"
 	"github.com/google/uuid"
 
 	"go.vocdoni.io/dvote/crypto/ethereum"
@@ -24,7 +25,14 @@ func ValidPubKey(pubKey []byte) bool {
 	return len(pubKey) == ethereum.PubKeyLengthBytes
 }
 
-func DecodeCensusID(id string, pubKey []byte) ([]byte, error) {
+// ValidEmail checks the format of an email address
+func ValidEmail(email string) bool {
+	return checkmail.ValidateFormat(email) == nil
+}
+
+// DecodeCensusID decodes a census ID, optionally prefixed by the address of
+// its entity ("0x.../0x...") which must then be entityID
+func DecodeCensusID(id string, entityID []byte) ([]byte, error) {
 	var censusID string
 	split := strings.Split(id, "/")
 	// Check for correct format 0xffdf.../0xfdf5f...
@@ -37,10 +45,7 @@ func DecodeCensusID(id string, pubKey []byte) ([]byte, error) {
 		if err != nil {
 			return nil, fmt.Errorf("error decoding address: %v", err)
 		}
-		// recover address from signature
-		if recoveredAddress, err := ethereum.AddrFromPublicKey(pubKey); err != nil {
-			return nil, fmt.Errorf("cannot extract entity address %v", err)
-		} else if string(recoveredAddress.Bytes()) != string(inputAddressBytes) {
+		if string(entityID) != string(inputAddressBytes) {
 			return nil, fmt.Errorf("invalid address in census id")
 		}
 		censusID = split[1]